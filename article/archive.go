@@ -0,0 +1,456 @@
+package article
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	archiveVersion = 1
+	manifestName   = "manifest.json"
+	articlesDir    = "articles/"
+	// maxArchiveFileSize bounds every file unpacked from an imported archive
+	maxArchiveFileSize = 16 << 20
+	// maxArchiveSize bounds all the files unpacked from an imported archive together
+	maxArchiveSize = 128 << 20
+	// maxArchiveFiles bounds the number of files in an imported archive
+	maxArchiveFiles = 4096
+)
+
+// ArchiveFormat is the container format of an archive written by Export
+type ArchiveFormat string
+
+const (
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+	ArchiveZip   ArchiveFormat = "zip"
+)
+
+// ParseArchiveFormat returns the archive format called s. An empty s means tar.gz.
+func ParseArchiveFormat(s string) (ArchiveFormat, error) {
+	switch format := ArchiveFormat(s); format {
+	case "":
+		return ArchiveTarGz, nil
+	case ArchiveTarGz, ArchiveZip:
+		return format, nil
+	default:
+		return "", errors.Join(ErrUnsupportedArchiveFormat, fmt.Errorf("format %q", s))
+	}
+}
+
+func (f ArchiveFormat) ContentType() string {
+	if f == ArchiveZip {
+		return "application/zip"
+	}
+	return "application/gzip"
+}
+
+// ConflictMode decides what Import does with an article whose slug is taken already
+type ConflictMode string
+
+const (
+	ConflictSkip      ConflictMode = "skip"
+	ConflictOverwrite ConflictMode = "overwrite"
+)
+
+// ParseConflictMode returns the conflict mode called s. An empty s means skip.
+func ParseConflictMode(s string) (ConflictMode, error) {
+	switch mode := ConflictMode(s); mode {
+	case "":
+		return ConflictSkip, nil
+	case ConflictSkip, ConflictOverwrite:
+		return mode, nil
+	default:
+		return "", errors.Join(ErrInvalidConflictMode, fmt.Errorf("mode %q", s))
+	}
+}
+
+type ImportOptions struct {
+	Conflict ConflictMode
+	// DryRun reports what an import would do without saving anything
+	DryRun bool
+}
+
+type ImportStatus string
+
+const (
+	ImportCreated ImportStatus = "created"
+	ImportUpdated ImportStatus = "updated"
+	ImportSkipped ImportStatus = "skipped"
+	ImportFailed  ImportStatus = "failed"
+	// ImportUnchanged and ImportDeleted are only reported by Sync
+	ImportUnchanged ImportStatus = "unchanged"
+	ImportDeleted   ImportStatus = "deleted"
+)
+
+// ImportResult is what happened to a single file of an imported archive. Problems found in the markdown
+// of an imported article don't stop it from being saved, they are listed alongside. Markdown that can't be
+// rendered at all fails the import of the file.
+type ImportResult struct {
+	File     string       `json:"file"`
+	Slug     string       `json:"slug,omitempty"`
+	Status   ImportStatus `json:"status"`
+	Problems []string     `json:"problems,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// manifest lists the articles of an archive. It's the first file of the archive.
+type manifest struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Articles   []manifestEntry `json:"articles"`
+}
+
+type manifestEntry struct {
+	File  string `json:"file"`
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+}
+
+// Export writes every article to w as an archive holding a manifest and one markdown file with headers per
+// article, the same files the articles were created from.
+func (s *Service) Export(ctx context.Context, w io.Writer, format ArchiveFormat) error {
+	articles, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return errors.Join(ErrArticlesNotFound, err)
+	}
+
+	m := manifest{Version: archiveVersion, ExportedAt: time.Now().UTC()}
+	for _, article := range articles {
+		m.Articles = append(m.Articles, manifestEntry{
+			File:  articlesDir + url.PathEscape(article.Slug) + ".md",
+			Slug:  article.Slug,
+			Title: article.Title,
+		})
+	}
+	manifestData, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Join(ErrArticleExportFailed, err)
+	}
+
+	aw, err := newArchiveWriter(w, format)
+	if err != nil {
+		return err
+	}
+	if err := aw.add(manifestName, m.ExportedAt, manifestData); err != nil {
+		return errors.Join(ErrArticleExportFailed, err)
+	}
+	for i, article := range articles {
+		if err := aw.add(m.Articles[i].File, m.ExportedAt, MarshalArticle(article)); err != nil {
+			return errors.Join(ErrArticleExportFailed, err)
+		}
+	}
+	if err := aw.Close(); err != nil {
+		return errors.Join(ErrArticleExportFailed, err)
+	}
+	return nil
+}
+
+// Import creates the articles from an archive written by Export, either a tar.gz or a zip. Every markdown file
+// in the archive gets a result, the archive itself is rejected with ErrInvalidArchive only if it can't be read.
+func (s *Service) Import(ctx context.Context, archive io.Reader, options ImportOptions) ([]ImportResult, error) {
+	files, err := readArchive(archive)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidArchive, err)
+	}
+
+	var m *manifest
+	if data, ok := files.contents[manifestName]; ok {
+		m = new(manifest)
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, errors.Join(ErrInvalidArchive, err)
+		}
+		if m.Version != archiveVersion {
+			return nil, errors.Join(ErrInvalidArchive, fmt.Errorf("unsupported manifest version %d", m.Version))
+		}
+	}
+
+	var results []ImportResult
+	slugs := make(map[string]string)
+	for _, name := range files.names {
+		if !strings.HasSuffix(name, ".md") {
+			continue
+		}
+		result := s.importFile(ctx, name, files.contents[name], options, slugs)
+		if result.Status != ImportFailed {
+			slugs[result.Slug] = name
+		}
+		results = append(results, result)
+	}
+
+	if m != nil {
+		for _, entry := range m.Articles {
+			if _, ok := files.contents[entry.File]; !ok {
+				results = append(results, ImportResult{
+					File:   entry.File,
+					Slug:   entry.Slug,
+					Status: ImportFailed,
+					Error:  "listed in the manifest but missing from the archive",
+				})
+			}
+		}
+	}
+
+	if !options.DryRun {
+		s.refreshProblems(ctx, results)
+	}
+	return results, nil
+}
+
+// refreshProblems looks for the problems of the saved articles again. Links to articles saved later in the same
+// batch were reported as problems, they resolve now.
+func (s *Service) refreshProblems(ctx context.Context, results []ImportResult) {
+	for i := range results {
+		if len(results[i].Problems) > 0 && results[i].Status != ImportFailed {
+			results[i].Problems = s.problems(ctx, results[i].Slug, results[i].Problems)
+		}
+	}
+}
+
+// importFile imports the markdown file called name. slugs holds the slugs imported from the archive so far.
+func (s *Service) importFile(
+	ctx context.Context,
+	name string,
+	data []byte,
+	options ImportOptions,
+	slugs map[string]string,
+) ImportResult {
+	result := ImportResult{File: name}
+	fail := func(err error) ImportResult {
+		result.Status = ImportFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	var article Article
+	if err := UnmarshalToArticle(data, &article); err != nil {
+		return fail(errors.Join(ErrArticleUnmarshalingFailed, err))
+	}
+	result.Slug = article.Slug
+	if other, ok := slugs[article.Slug]; ok {
+		return fail(fmt.Errorf("slug %q is used by %s already", article.Slug, other))
+	}
+
+	existing, err := s.repo.GetBySlug(ctx, article.Slug)
+	if err != nil && !errors.Is(err, ErrArticleNotFound) {
+		return fail(err)
+	}
+	if existing != nil {
+		if options.Conflict != ConflictOverwrite {
+			result.Status = ImportSkipped
+			return result
+		}
+		article.ID = existing.ID
+	}
+
+	var validationErr *ValidationError
+	if err := s.render(ctx, &article); errors.As(err, &validationErr) {
+		// Nothing is rendered when the markdown can't be parsed at all, e.g. because it's too large
+		if article.Content != "" && article.ContentHTML == "" || article.Thumbnail != "" && article.ThumbnailHTML == "" {
+			return fail(err)
+		}
+		result.Problems = slices.Concat(validationErr.Problems, validationErr.Warnings)
+	} else if err != nil {
+		return fail(err)
+	}
+
+	if existing == nil {
+		result.Status = ImportCreated
+	} else {
+		result.Status = ImportUpdated
+	}
+	if options.DryRun {
+		return result
+	}
+
+	if existing == nil {
+		_, err = s.create(ctx, article)
+	} else {
+		_, err = s.update(ctx, existing, article)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return result
+}
+
+// problems renders the stored article under slug again and returns the problems found. The previous problems
+// are returned if that fails.
+func (s *Service) problems(ctx context.Context, slug string, previous []string) []string {
+	article, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil {
+		return previous
+	}
+
+	var validationErr *ValidationError
+	if err := s.render(ctx, article); errors.As(err, &validationErr) {
+		return slices.Concat(validationErr.Problems, validationErr.Warnings)
+	} else if err != nil {
+		return previous
+	}
+	return nil
+}
+
+type archiveWriter interface {
+	add(name string, modTime time.Time, data []byte) error
+	Close() error
+}
+
+func newArchiveWriter(w io.Writer, format ArchiveFormat) (archiveWriter, error) {
+	switch format {
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarGzWriter{gz: gz, tar: tar.NewWriter(gz)}, nil
+	case ArchiveZip:
+		return zipWriter{zip.NewWriter(w)}, nil
+	default:
+		return nil, errors.Join(ErrUnsupportedArchiveFormat, fmt.Errorf("format %q", format))
+	}
+}
+
+type tarGzWriter struct {
+	gz  *gzip.Writer
+	tar *tar.Writer
+}
+
+func (w *tarGzWriter) add(name string, modTime time.Time, data []byte) error {
+	err := w.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.tar.Write(data)
+	return err
+}
+
+func (w *tarGzWriter) Close() error {
+	return errors.Join(w.tar.Close(), w.gz.Close())
+}
+
+type zipWriter struct {
+	*zip.Writer
+}
+
+func (w zipWriter) add(name string, modTime time.Time, data []byte) error {
+	f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// archiveFiles maps the names of the regular files in an archive to their contents
+type archiveFiles struct {
+	contents map[string][]byte
+	// names keeps the order of the files in the archive
+	names []string
+	// size is the size of all the contents together
+	size int
+}
+
+// readArchive unpacks a tar.gz or a zip archive, told apart by their magic numbers
+func readArchive(r io.Reader) (archiveFiles, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return archiveFiles{}, err
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return readTarGz(bytes.NewReader(data))
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return readZip(bytes.NewReader(data), int64(len(data)))
+	default:
+		return archiveFiles{}, ErrUnsupportedArchiveFormat
+	}
+}
+
+func readTarGz(r io.Reader) (archiveFiles, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return archiveFiles{}, err
+	}
+	defer gz.Close()
+
+	files := archiveFiles{contents: make(map[string][]byte)}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		} else if err != nil {
+			return archiveFiles{}, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := files.read(header.Name, tr); err != nil {
+			return archiveFiles{}, err
+		}
+	}
+}
+
+func readZip(r io.ReaderAt, size int64) (archiveFiles, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return archiveFiles{}, err
+	}
+
+	files := archiveFiles{contents: make(map[string][]byte)}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return archiveFiles{}, err
+		}
+		err = files.read(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return archiveFiles{}, err
+		}
+	}
+	return files, nil
+}
+
+// read adds the file called name. Every file is limited to maxArchiveFileSize and the archive to maxArchiveFiles
+// files of maxArchiveSize bytes together, so a small compressed archive can't unpack into all the memory.
+func (f *archiveFiles) read(name string, r io.Reader) error {
+	if _, ok := f.contents[name]; ok {
+		return fmt.Errorf("%s is in the archive twice", name)
+	}
+	if len(f.names) == maxArchiveFiles {
+		return fmt.Errorf("the archive has more than %d files", maxArchiveFiles)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveFileSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxArchiveFileSize {
+		return fmt.Errorf("%s is larger than %d bytes", name, maxArchiveFileSize)
+	}
+	f.size += len(data)
+	if f.size > maxArchiveSize {
+		return fmt.Errorf("the archive unpacks to more than %d bytes", maxArchiveSize)
+	}
+	f.contents[name] = data
+	f.names = append(f.names, name)
+	return nil
+}
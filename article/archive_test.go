@@ -0,0 +1,208 @@
+package article_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	a "github.com/jannawro/blog/article"
+)
+
+func exportedArticles() []a.Article {
+	return []a.Article{
+		{
+			ID:              1,
+			Title:           "First article",
+			Slug:            "first-article",
+			Content:         "Links to [[second-article]].",
+			Tags:            []string{"go"},
+			PublicationDate: time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			ID:              2,
+			Title:           "Second article",
+			Thumbnail:       "The second one",
+			Slug:            "second-article",
+			Content:         "Cites [@gopl].",
+			Tags:            []string{"go", "books"},
+			References:      []a.Reference{{Key: "gopl", Text: "*The Go Programming Language*"}},
+			PublicationDate: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func export(t *testing.T, service *a.Service, format a.ArchiveFormat) []byte {
+	t.Helper()
+	var archive bytes.Buffer
+	require.NoError(t, service.Export(context.Background(), &archive, format))
+	return archive.Bytes()
+}
+
+func TestExportAndImport(t *testing.T) {
+	for _, format := range []a.ArchiveFormat{a.ArchiveTarGz, a.ArchiveZip} {
+		t.Run(string(format), func(t *testing.T) {
+			source, sourceRepo := setupTestService()
+			sourceRepo.SetArticles(exportedArticles())
+			archive := export(t, source, format)
+
+			target, _ := setupTestService()
+			results, err := target.Import(context.Background(), bytes.NewReader(archive), a.ImportOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, []a.ImportResult{
+				{File: "articles/first-article.md", Slug: "first-article", Status: a.ImportCreated},
+				{File: "articles/second-article.md", Slug: "second-article", Status: a.ImportCreated},
+			}, results)
+
+			for _, want := range exportedArticles() {
+				got, err := target.GetBySlug(context.Background(), want.Slug)
+				require.NoError(t, err)
+				assert.Equal(t, want.Title, got.Title)
+				assert.Equal(t, want.Thumbnail, got.Thumbnail)
+				assert.Equal(t, want.Content, got.Content)
+				assert.Equal(t, want.Tags, got.Tags)
+				assert.Equal(t, want.References, got.References)
+				assert.Equal(t, want.PublicationDate, got.PublicationDate)
+			}
+
+			// The link to the second article was rendered again once it was imported
+			first, err := target.GetBySlug(context.Background(), "first-article")
+			require.NoError(t, err)
+			assert.Contains(t, first.ContentHTML, `href="/article/second-article"`)
+		})
+	}
+}
+
+func TestImportConflicts(t *testing.T) {
+	source, sourceRepo := setupTestService()
+	sourceRepo.SetArticles(exportedArticles())
+	archive := export(t, source, a.ArchiveTarGz)
+
+	existing := exportedArticles()[1]
+	existing.ID = 7
+	existing.Content = "Older content"
+
+	t.Run("Skip", func(t *testing.T) {
+		target, targetRepo := setupTestService()
+		targetRepo.SetArticles([]a.Article{existing})
+
+		results, err := target.Import(context.Background(), bytes.NewReader(archive), a.ImportOptions{Conflict: a.ConflictSkip})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, a.ImportCreated, results[0].Status)
+		assert.Equal(t, a.ImportSkipped, results[1].Status)
+
+		kept, err := target.GetBySlug(context.Background(), existing.Slug)
+		require.NoError(t, err)
+		assert.Equal(t, "Older content", kept.Content)
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		target, targetRepo := setupTestService()
+		targetRepo.SetArticles([]a.Article{existing})
+
+		results, err := target.Import(context.Background(), bytes.NewReader(archive), a.ImportOptions{Conflict: a.ConflictOverwrite})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, a.ImportCreated, results[0].Status)
+		assert.Equal(t, a.ImportUpdated, results[1].Status)
+
+		updated, err := target.GetBySlug(context.Background(), existing.Slug)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), updated.ID)
+		assert.Equal(t, "Cites [@gopl].", updated.Content)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		target, targetRepo := setupTestService()
+		targetRepo.SetArticles([]a.Article{existing})
+
+		results, err := target.Import(context.Background(), bytes.NewReader(archive), a.ImportOptions{
+			Conflict: a.ConflictOverwrite,
+			DryRun:   true,
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, a.ImportCreated, results[0].Status)
+		assert.Equal(t, a.ImportUpdated, results[1].Status)
+
+		all, err := target.GetAll(context.Background(), nil)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Equal(t, "Older content", all[0].Content)
+	})
+}
+
+func TestImportReportsBrokenFiles(t *testing.T) {
+	archive := tarGz(t, map[string]string{
+		"manifest.json":            `{"version":1,"articles":[{"file":"articles/missing.md","slug":"missing"}]}`,
+		"articles/no-separator.md": "title:No separator\npublicationDate:2023-05-10\n",
+		"notes.txt":                "not an article",
+	})
+
+	service, _ := setupTestService()
+	results, err := service.Import(context.Background(), archive, a.ImportOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "articles/no-separator.md", results[0].File)
+	assert.Equal(t, a.ImportFailed, results[0].Status)
+	assert.NotEmpty(t, results[0].Error)
+	assert.Equal(t, "articles/missing.md", results[1].File)
+	assert.Equal(t, a.ImportFailed, results[1].Status)
+}
+
+func TestImportRejectsUnrenderableFiles(t *testing.T) {
+	tooLarge := "title:Too large\npublicationDate:2023-05-10\n===\n" + strings.Repeat("word ", 200_000)
+	archive := tarGz(t, map[string]string{"articles/too-large.md": tooLarge})
+
+	service, mockRepo := setupTestService()
+	results, err := service.Import(context.Background(), archive, a.ImportOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, a.ImportFailed, results[0].Status)
+	assert.Contains(t, results[0].Error, "the limit is")
+
+	articles, err := mockRepo.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, articles)
+}
+
+func TestImportInvalidArchive(t *testing.T) {
+	service, _ := setupTestService()
+	_, err := service.Import(context.Background(), bytes.NewReader([]byte("plain text")), a.ImportOptions{})
+	assert.ErrorIs(t, err, a.ErrInvalidArchive)
+
+	t.Run("Too many files", func(t *testing.T) {
+		files := make(map[string]string)
+		for i := range 4097 {
+			files[fmt.Sprintf("notes/%d.txt", i)] = ""
+		}
+		_, err := service.Import(context.Background(), tarGz(t, files), a.ImportOptions{})
+		assert.ErrorIs(t, err, a.ErrInvalidArchive)
+		assert.ErrorContains(t, err, "more than 4096 files")
+	})
+}
+
+// tarGz returns a tar.gz archive holding files
+func tarGz(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}))
+		_, err := io.WriteString(tw, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &archive
+}
@@ -1,8 +1,12 @@
 package article
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 )
@@ -16,30 +20,139 @@ const (
 // `title:Fondant recipe
 // publicationDate:2005-04-02
 // tags:cooking,sweets
+// references:
+// - wilton: Wilton. *Cake Decorating Basics*. 2010.
 // ===
 // # Markdown Title
-// Markdown contents...`
+// Markdown contents citing [@wilton]...`
 type Article struct {
+	ID              int64       `json:"id"`
+	Title           string      `json:"title"`
+	Thumbnail       string      `json:"thumbnail"`
+	ThumbnailHTML   string      `json:"thumbnail_html"`
+	Slug            string      `json:"slug"`
+	Content         string      `json:"content"`
+	ContentHTML     string      `json:"content_html"`
+	Tags            []string    `json:"tags"`
+	References      []Reference `json:"references,omitempty"`
+	PublicationDate time.Time   `json:"publication_date"`
+}
+
+// Reference is an entry in the bibliography of an article. Articles cite it with [@key].
+// The text is markdown.
+type Reference struct {
+	Key  string `json:"key"`
+	Text string `json:"text"`
+}
+
+type Articles []Article
+
+// ArticleSummary is the part of an article shown in lists, without the markdown and the rendered content
+type ArticleSummary struct {
 	ID              int64     `json:"id"`
 	Title           string    `json:"title"`
 	Thumbnail       string    `json:"thumbnail"`
+	ThumbnailHTML   string    `json:"thumbnail_html"`
 	Slug            string    `json:"slug"`
-	Content         string    `json:"content"`
 	Tags            []string  `json:"tags"`
 	PublicationDate time.Time `json:"publication_date"`
 }
 
-type Articles []Article
+type ArticleSummaries []ArticleSummary
+
+// ArchiveMonth is the number of articles published in a calendar month, in UTC
+type ArchiveMonth struct {
+	Year  int        `json:"year"`
+	Month time.Month `json:"month"`
+	Count int        `json:"count"`
+}
+
+// Archive counts the articles per month of publication, newest month first. It's meant for repositories
+// keeping their articles in memory.
+func (a Articles) Archive() []ArchiveMonth {
+	var months []ArchiveMonth
+	for _, article := range a {
+		year, month, _ := article.PublicationDate.UTC().Date()
+		key := ArchiveMonth{Year: year, Month: month, Count: 1}
+		i, found := slices.BinarySearchFunc(months, key, func(m, key ArchiveMonth) int {
+			// Newest first
+			return cmp.Or(cmp.Compare(key.Year, m.Year), cmp.Compare(key.Month, m.Month))
+		})
+		if found {
+			months[i].Count++
+		} else {
+			months = slices.Insert(months, i, key)
+		}
+	}
+	return months
+}
+
+// Summary returns the part of a shown in lists
+func (a Article) Summary() ArticleSummary {
+	return ArticleSummary{
+		ID:              a.ID,
+		Title:           a.Title,
+		Thumbnail:       a.Thumbnail,
+		ThumbnailHTML:   a.ThumbnailHTML,
+		Slug:            a.Slug,
+		Tags:            a.Tags,
+		PublicationDate: a.PublicationDate,
+	}
+}
+
+// Summaries returns the summaries of a, in the same order
+func (a Articles) Summaries() ArticleSummaries {
+	summaries := make(ArticleSummaries, len(a))
+	for i, article := range a {
+		summaries[i] = article.Summary()
+	}
+	return summaries
+}
+
+// PublishedIn reports whether a was published in [from, to). A zero from or to leaves that end open.
+func (a Article) PublishedIn(from, to time.Time) bool {
+	return (from.IsZero() || !a.PublicationDate.Before(from)) && (to.IsZero() || a.PublicationDate.Before(to))
+}
 
+// ArticleRepository stores articles. Implementations are checked against the same contract by repository/repotest:
+// lookups, updates and deletes of a missing article return an error wrapping ErrArticleNotFound.
 type ArticleRepository interface {
 	Create(ctx context.Context, article Article) (*Article, error)
+	// GetAll returns every article in the order they were created
 	GetAll(ctx context.Context) (Articles, error)
 	GetByID(ctx context.Context, id int64) (*Article, error)
 	GetBySlug(ctx context.Context, slug string) (*Article, error)
+	// GetByTags returns the articles having at least one of tags, in the order they were created
 	GetByTags(ctx context.Context, tags []string) (Articles, error)
+	// GetByPublicationDate returns the articles published in [from, to), oldest first. A zero from or to
+	// leaves that end of the range open.
+	GetByPublicationDate(ctx context.Context, from, to time.Time) (Articles, error)
+	// GetAllSummaries, GetSummariesByTags and GetSummariesByPublicationDate are GetAll, GetByTags and
+	// GetByPublicationDate reading only the summaries, for lists which don't show the content
+	GetAllSummaries(ctx context.Context) (ArticleSummaries, error)
+	GetSummariesByTags(ctx context.Context, tags []string) (ArticleSummaries, error)
+	GetSummariesByPublicationDate(ctx context.Context, from, to time.Time) (ArticleSummaries, error)
+	// GetArchive returns the months having at least one article, newest first
+	GetArchive(ctx context.Context) ([]ArchiveMonth, error)
+	// GetAllTags returns the tags used by any article, sorted and without duplicates
 	GetAllTags(ctx context.Context) ([]string, error)
 	Update(ctx context.Context, id int64, updated Article) (*Article, error)
 	Delete(ctx context.Context, id int64) error
+	// SetLinks replaces the slugs of the articles the article with sourceID links to
+	SetLinks(ctx context.Context, sourceID int64, targetSlugs []string) error
+	// GetBacklinks returns the articles linking to slug, newest first
+	GetBacklinks(ctx context.Context, slug string) (Articles, error)
+}
+
+// Renderer turns the markdown of an article into HTML. Articles are rendered once when they are written
+// and the result is stored alongside the markdown. Problems with the markdown itself, like links to
+// articles that don't exist, are reported as a *ValidationError. The HTML is rendered regardless,
+// with the invalid parts left out.
+type Renderer interface {
+	RenderContent(ctx context.Context, markdown string, references []Reference) (string, error)
+	RenderThumbnail(ctx context.Context, markdown string) (string, error)
+	// LinkTargets returns the slugs of the articles the markdown links to
+	LinkTargets(markdown string) []string
 }
 
 // UnmarshalToArticle parses a markdown file with specific headers and stores the result as an article in a
@@ -50,13 +163,30 @@ func UnmarshalToArticle(data []byte, a *Article) error {
 	}
 
 	headers := make(map[string]string)
+	var references []Reference
+	inReferences := false
 	headerLines := strings.Split(headersSection, "\n")
 	for _, line := range headerLines {
+		// References are listed one per line below the references header: "- key: text"
+		if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok && inReferences {
+			reference, err := parseReference(item)
+			if err != nil {
+				return err
+			}
+			if slices.ContainsFunc(references, func(r Reference) bool { return r.Key == reference.Key }) {
+				return errors.Join(ErrInvalidReference, fmt.Errorf("duplicate key %q", reference.Key))
+			}
+			references = append(references, reference)
+			continue
+		}
+		inReferences = false
+
 		if strings.Contains(line, ":") {
 			kv := strings.SplitN(line, ":", 2)
 			key := strings.TrimSpace(kv[0])
 			value := strings.TrimSpace(kv[1])
 			headers[key] = value
+			inReferences = key == "references"
 		}
 	}
 
@@ -69,11 +199,56 @@ func UnmarshalToArticle(data []byte, a *Article) error {
 	}
 	a.PublicationDate = date
 	a.Tags = strings.Split(headers["tags"], ",")
+	a.References = references
 	a.Content = strings.TrimSpace(bodySection)
 
 	return nil
 }
 
+// MarshalArticle is the inverse of UnmarshalToArticle. It returns a as a markdown file with headers.
+// Only the date of the publication date is kept.
+func MarshalArticle(a Article) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "title:%s\n", a.Title)
+	if a.Thumbnail != "" {
+		fmt.Fprintf(&b, "thumbnail:%s\n", a.Thumbnail)
+	}
+	fmt.Fprintf(&b, "publicationDate:%s\n", a.PublicationDate.Format(publicationDateFormat))
+	fmt.Fprintf(&b, "tags:%s\n", strings.Join(a.Tags, ","))
+	if len(a.References) > 0 {
+		b.WriteString("references:\n")
+		for _, r := range a.References {
+			fmt.Fprintf(&b, "- %s: %s\n", r.Key, r.Text)
+		}
+	}
+	b.WriteString(separator + "\n")
+	b.WriteString(a.Content)
+	b.WriteString("\n")
+	return []byte(b.String())
+}
+
+var referenceKey = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// SameMarkdown reports whether a and b would be marshaled to the same file, ignoring the rendered HTML and the ID
+func (a Article) SameMarkdown(b Article) bool {
+	return a.Title == b.Title &&
+		a.Thumbnail == b.Thumbnail &&
+		a.Slug == b.Slug &&
+		a.Content == b.Content &&
+		slices.Equal(a.Tags, b.Tags) &&
+		slices.Equal(a.References, b.References) &&
+		a.PublicationDate.Equal(b.PublicationDate)
+}
+
+func parseReference(item string) (Reference, error) {
+	key, text, found := strings.Cut(item, ":")
+	key, text = strings.TrimSpace(key), strings.TrimSpace(text)
+	if !found || !referenceKey.MatchString(key) || text == "" {
+		return Reference{}, errors.Join(ErrInvalidReference, fmt.Errorf("expected \"- key: text\", got %q", item))
+	}
+	return Reference{Key: key, Text: text}, nil
+}
+
 func Slugify(title string) string {
 	return strings.ReplaceAll(strings.ToLower(title), " ", "-")
 }
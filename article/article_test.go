@@ -41,3 +41,53 @@ func TestUnmarshalToArticle(t *testing.T) {
 	assert.Equal(`# Markdown Title
 Markdown contents...`, a.Content)
 }
+
+func TestUnmarshalToArticleReferences(t *testing.T) {
+	a := article1.Article{}
+	err := article1.UnmarshalToArticle([]byte(`title:Essay
+publicationDate:2024-01-02
+references:
+- knuth: Donald E. Knuth. *The Art of Computer Programming*. 1968.
+- dijkstra: Edsger W. Dijkstra. Go To Statement Considered Harmful: A Letter. 1968.
+tags:essays
+===
+As [@knuth] wrote...`), &a)
+	if err != nil {
+		t.Fatal("expected no error but got:", err)
+	}
+
+	assert.Equal(t, []article1.Reference{
+		{Key: "knuth", Text: "Donald E. Knuth. *The Art of Computer Programming*. 1968."},
+		{Key: "dijkstra", Text: "Edsger W. Dijkstra. Go To Statement Considered Harmful: A Letter. 1968."},
+	}, a.References)
+	assert.Equal(t, []string{"essays"}, a.Tags)
+}
+
+func TestUnmarshalToArticleInvalidReferences(t *testing.T) {
+	for _, references := range []string{
+		"- no key given",
+		"- two words: text",
+		"- knuth: TAOCP\n- knuth: TAOCP again",
+	} {
+		a := article1.Article{}
+		err := article1.UnmarshalToArticle([]byte("title:Essay\npublicationDate:2024-01-02\nreferences:\n"+references+"\n===\nBody"), &a)
+		assert.ErrorIs(t, err, article1.ErrInvalidReference, references)
+	}
+}
+
+func TestMarshalArticle(t *testing.T) {
+	original := article1.Article{
+		Title:           "Fondant recipe",
+		Thumbnail:       "This is why my fondant recipe is great.",
+		Slug:            "fondant-recipe",
+		Content:         "# Markdown Title\nMarkdown contents citing [@wilton]...",
+		Tags:            []string{"cooking", "sweets"},
+		References:      []article1.Reference{{Key: "wilton", Text: "Wilton. *Cake Decorating Basics*. 2010."}},
+		PublicationDate: time.Date(2005, 4, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	var unmarshaled article1.Article
+	err := article1.UnmarshalToArticle(article1.MarshalArticle(original), &unmarshaled)
+	assert.NoError(t, err)
+	assert.Equal(t, original, unmarshaled)
+}
@@ -0,0 +1,33 @@
+package article
+
+// ChangeOperation is the kind of write a Change describes
+type ChangeOperation string
+
+const (
+	ChangeCreated ChangeOperation = "created"
+	ChangeUpdated ChangeOperation = "updated"
+	ChangeDeleted ChangeOperation = "deleted"
+	// ChangeLinks means the article with the ID started or stopped linking to the slug
+	ChangeLinks ChangeOperation = "links_changed"
+	// ChangeUnknown means changes may have been missed, e.g. while reconnecting to the database, so any
+	// article may have changed
+	ChangeUnknown ChangeOperation = "unknown"
+)
+
+// Change describes a write to the stored articles, possibly made by another instance of the blog. The previous
+// slug and tags are those before an update or a deletion.
+type Change struct {
+	Operation    ChangeOperation `json:"operation"`
+	ID           int64           `json:"id"`
+	Slug         string          `json:"slug"`
+	Tags         []string        `json:"tags"`
+	PreviousSlug string          `json:"previous_slug"`
+	PreviousTags []string        `json:"previous_tags"`
+}
+
+// ChangeFeed publishes the changes made to the stored articles
+type ChangeFeed interface {
+	// Subscribe calls f with every change until unsubscribe is called. The changes are passed to the subscribers
+	// one at a time, in order, so a slow f delays the following changes.
+	Subscribe(f func(Change)) (unsubscribe func())
+}
@@ -3,15 +3,36 @@ package article
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 )
 
 var (
 	ErrSeparatorNotFound         = fmt.Errorf("headers and body separator '%s' not found", separator)
 	ErrDateFormatFailed          = errors.New("date formatting failed")
+	ErrInvalidReference          = errors.New("invalid reference")
 	ErrArticleUnmarshalingFailed = errors.New("article unmarshaling failed")
 	ErrArticleNotFound           = errors.New("article not found")
 	ErrArticlesNotFound          = errors.New("articles not found")
 	ErrArticleCreationFailed     = errors.New("article creation failed")
 	ErrArticleUpdateFailed       = errors.New("updating article failed")
 	ErrArticleDeletionFailed     = errors.New("deleting article failed")
+	ErrArticleRenderingFailed    = errors.New("rendering article failed")
+	ErrArticleExportFailed       = errors.New("exporting articles failed")
+	ErrInvalidArchive            = errors.New("invalid archive")
+	ErrUnsupportedArchiveFormat  = errors.New("unsupported archive format, expected tar.gz or zip")
+	ErrInvalidConflictMode       = errors.New("invalid conflict mode, expected skip or overwrite")
+	ErrUnknownField              = errors.New("unknown field, expected content")
 )
+
+// ValidationError is returned when an article can't be saved because of problems in its content,
+// e.g. a shortcode that doesn't exist. The problems are meant to be shown to the author.
+// Warnings don't stop the article from being saved, e.g. a link to an article that doesn't exist yet.
+type ValidationError struct {
+	Problems []string
+	Warnings []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid article: " + strings.Join(slices.Concat(e.Problems, e.Warnings), "; ")
+}
@@ -0,0 +1,40 @@
+package article
+
+import (
+	"context"
+	"time"
+)
+
+// EventStatus is how far the delivery of an event got
+type EventStatus string
+
+const (
+	EventPending   EventStatus = "pending"
+	EventDelivered EventStatus = "delivered"
+	// EventDead means delivering the event failed too often, it's kept for inspection but not retried
+	EventDead EventStatus = "dead"
+)
+
+// Event is a change to an article recorded in the outbox of the database. It's written in the same transaction as
+// the change, so it's delivered even if the process stops right after the change.
+type Event struct {
+	ID        int64           `json:"id"`
+	Operation ChangeOperation `json:"operation"`
+	// Article is the article after the change, or before it when it was deleted. The rendered HTML is left out.
+	Article    Article   `json:"article"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// Attempts counts the failed deliveries so far
+	Attempts int `json:"attempts"`
+}
+
+// EventRepository is the outbox the repositories record Create, Update and Delete in, see outbox.Dispatcher.
+type EventRepository interface {
+	// ClaimEvents returns up to limit pending events that are due, oldest first. They aren't claimed again for the
+	// lease, so several dispatchers can share the outbox.
+	ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]Event, error)
+	MarkEventDelivered(ctx context.Context, id int64) error
+	// MarkEventFailed records a failed delivery. The event is claimed again after retryIn, or never if dead is set.
+	MarkEventFailed(ctx context.Context, id int64, failure string, retryIn time.Duration, dead bool) error
+	// DeleteDeliveredEvents deletes the events delivered longer than age ago and returns how many there were
+	DeleteDeliveredEvents(ctx context.Context, age time.Duration) (int64, error)
+}
@@ -3,20 +3,52 @@ package article
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"slices"
+	"time"
 )
 
 type Service struct {
-	repo ArticleRepository
+	repo     ArticleRepository
+	renderer Renderer
 }
 
-func NewService(repo ArticleRepository) *Service {
-	return &Service{repo: repo}
+func NewService(repo ArticleRepository, renderer Renderer) *Service {
+	return &Service{
+		repo:     repo,
+		renderer: renderer,
+	}
+}
+
+// Create renders and saves article. It returns the warnings found in its content, e.g. links to articles
+// that don't exist yet.
+func (s *Service) Create(ctx context.Context, article Article) (*Article, []string, error) {
+	warnings, err := s.validate(ctx, &article)
+	if err != nil {
+		return nil, nil, errors.Join(ErrArticleCreationFailed, err)
+	}
+
+	a, err := s.create(ctx, article)
+	if err != nil {
+		return nil, nil, errors.Join(ErrArticleCreationFailed, err)
+	}
+	return a, warnings, nil
 }
 
-func (s *Service) Create(ctx context.Context, article Article) (*Article, error) {
+// create saves an already rendered article. The article is saved once the repository created it,
+// failing to update the links afterwards is only logged.
+func (s *Service) create(ctx context.Context, article Article) (*Article, error) {
 	a, err := s.repo.Create(ctx, article)
 	if err != nil {
-		return nil, errors.Join(ErrArticleCreationFailed, err)
+		return nil, err
+	}
+	if err := s.repo.SetLinks(ctx, a.ID, s.renderer.LinkTargets(a.Content)); err != nil {
+		slog.Error("Saving article links failed", "slug", a.Slug, "error", err)
+	}
+
+	// Articles linking to the new slug had a broken link until now
+	if err := s.rerenderBacklinks(ctx, a.Slug); err != nil {
+		slog.Error("Rerendering backlinks failed", "slug", a.Slug, "error", err)
 	}
 	return a, nil
 }
@@ -63,20 +95,115 @@ func (s *Service) GetByTags(
 	return articles, nil
 }
 
+// GetByPublicationDate returns the articles published in [from, to). A zero from or to leaves that end open.
+func (s *Service) GetByPublicationDate(
+	ctx context.Context,
+	from, to time.Time,
+	sortBy *SortOption,
+) (Articles, error) {
+	articles, err := s.repo.GetByPublicationDate(ctx, from, to)
+	if err != nil {
+		return nil, errors.Join(ErrArticlesNotFound, err)
+	}
+	if sortBy != nil {
+		articles.Sort(*sortBy)
+	}
+	return articles, nil
+}
+
+// GetAllSummaries is GetAll without the content of the articles
+func (s *Service) GetAllSummaries(ctx context.Context, sortBy *SortOption) (ArticleSummaries, error) {
+	summaries, err := s.repo.GetAllSummaries(ctx)
+	if err != nil {
+		return nil, errors.Join(ErrArticlesNotFound, err)
+	}
+	if sortBy != nil {
+		summaries.Sort(*sortBy)
+	}
+	return summaries, nil
+}
+
+// GetSummariesByTags is GetByTags without the content of the articles
+func (s *Service) GetSummariesByTags(
+	ctx context.Context,
+	tags []string,
+	sortBy *SortOption,
+) (ArticleSummaries, error) {
+	summaries, err := s.repo.GetSummariesByTags(ctx, tags)
+	if err != nil {
+		return nil, errors.Join(ErrArticlesNotFound, err)
+	}
+	if sortBy != nil {
+		summaries.Sort(*sortBy)
+	}
+	return summaries, nil
+}
+
+// GetSummariesByPublicationDate is GetByPublicationDate without the content of the articles
+func (s *Service) GetSummariesByPublicationDate(
+	ctx context.Context,
+	from, to time.Time,
+	sortBy *SortOption,
+) (ArticleSummaries, error) {
+	summaries, err := s.repo.GetSummariesByPublicationDate(ctx, from, to)
+	if err != nil {
+		return nil, errors.Join(ErrArticlesNotFound, err)
+	}
+	if sortBy != nil {
+		summaries.Sort(*sortBy)
+	}
+	return summaries, nil
+}
+
+// GetArchive returns the number of articles published in every month having any, newest first
+func (s *Service) GetArchive(ctx context.Context) ([]ArchiveMonth, error) {
+	months, err := s.repo.GetArchive(ctx)
+	if err != nil {
+		return nil, errors.Join(ErrArticlesNotFound, err)
+	}
+	return months, nil
+}
+
 func (s *Service) UpdateBySlug(
 	ctx context.Context,
 	slug string,
 	updatedArticle Article,
-) (*Article, error) {
+) (*Article, []string, error) {
 	existingArticle, err := s.repo.GetBySlug(ctx, slug)
 	if err != nil {
-		return nil, errors.Join(ErrArticleNotFound, err)
+		return nil, nil, errors.Join(ErrArticleNotFound, err)
 	}
 
 	updatedArticle.ID = existingArticle.ID
-	a, err := s.repo.Update(ctx, existingArticle.ID, updatedArticle)
+	warnings, err := s.validate(ctx, &updatedArticle)
+	if err != nil {
+		return nil, nil, errors.Join(ErrArticleUpdateFailed, err)
+	}
+
+	a, err := s.update(ctx, existingArticle, updatedArticle)
+	if err != nil {
+		return nil, nil, errors.Join(ErrArticleUpdateFailed, err)
+	}
+	return a, warnings, nil
+}
+
+// update replaces existing with an already rendered article. Like create, it only logs failing to
+// update the links once the article is saved.
+func (s *Service) update(ctx context.Context, existing *Article, updated Article) (*Article, error) {
+	a, err := s.repo.Update(ctx, existing.ID, updated)
 	if err != nil {
-		return nil, errors.Join(ErrArticleUpdateFailed, err)
+		return nil, err
+	}
+	if err := s.repo.SetLinks(ctx, a.ID, s.renderer.LinkTargets(a.Content)); err != nil {
+		slog.Error("Saving article links failed", "slug", a.Slug, "error", err)
+	}
+
+	if existing.Slug != a.Slug {
+		for _, slug := range []string{existing.Slug, a.Slug} {
+			if err := s.rerenderBacklinks(ctx, slug); err != nil {
+				slog.Error("Rerendering backlinks failed", "slug", slug, "error", err)
+			}
+		}
 	}
 	return a, nil
 }
@@ -93,6 +220,12 @@ func (s *Service) DeleteBySlug(ctx context.Context, slug string) error {
 	if err != nil {
 		return errors.Join(ErrArticleDeletionFailed, err)
 	}
+
+	// Links to the deleted article are broken now. The article is gone regardless, so failing to rerender them
+	// doesn't fail the deletion.
+	if err := s.rerenderBacklinks(ctx, slug); err != nil {
+		slog.Error("Rerendering backlinks failed", "slug", slug, "error", err)
+	}
 	return nil
 }
 
@@ -103,3 +236,130 @@ func (s *Service) GetAllTags(ctx context.Context) ([]string, error) {
 	}
 	return tags, nil
 }
+
+// RerenderAll renders every stored article again and saves the result. It should be run whenever
+// the renderer changes in a way that affects already published articles.
+func (s *Service) RerenderAll(ctx context.Context) (int, error) {
+	articles, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return 0, errors.Join(ErrArticlesNotFound, err)
+	}
+
+	for i, article := range articles {
+		if err := s.rerender(ctx, &article); err != nil {
+			return i, errors.Join(ErrArticleUpdateFailed, err)
+		}
+	}
+	return len(articles), nil
+}
+
+// Apply renders the articles affected by a change made outside the service again, e.g. a file of the
+// filesystem backend edited by hand, which only stores the markdown.
+func (s *Service) Apply(ctx context.Context, change Change) error {
+	switch change.Operation {
+	case ChangeCreated, ChangeUpdated:
+		article, err := s.repo.GetByID(ctx, change.ID)
+		if errors.Is(err, ErrArticleNotFound) {
+			// Deleted again in the meantime, its own change follows
+			return nil
+		} else if err != nil {
+			return errors.Join(ErrArticleNotFound, err)
+		}
+		if err := s.rerender(ctx, article); err != nil {
+			return errors.Join(ErrArticleUpdateFailed, err)
+		}
+		if err := s.rerenderBacklinks(ctx, change.Slug); err != nil {
+			return errors.Join(ErrArticleUpdateFailed, err)
+		}
+		if change.PreviousSlug != "" && change.PreviousSlug != change.Slug {
+			if err := s.rerenderBacklinks(ctx, change.PreviousSlug); err != nil {
+				return errors.Join(ErrArticleUpdateFailed, err)
+			}
+		}
+	case ChangeDeleted:
+		if err := s.rerenderBacklinks(ctx, change.PreviousSlug); err != nil {
+			return errors.Join(ErrArticleUpdateFailed, err)
+		}
+	case ChangeUnknown:
+		if _, err := s.RerenderAll(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBacklinks returns the articles linking to the article with slug
+func (s *Service) GetBacklinks(ctx context.Context, slug string) (Articles, error) {
+	articles, err := s.repo.GetBacklinks(ctx, slug)
+	if err != nil {
+		return nil, errors.Join(ErrArticlesNotFound, err)
+	}
+	return articles, nil
+}
+
+// rerenderBacklinks renders the articles linking to slug again, after an article under slug appeared or disappeared
+func (s *Service) rerenderBacklinks(ctx context.Context, slug string) error {
+	backlinks, err := s.repo.GetBacklinks(ctx, slug)
+	if err != nil {
+		return err
+	}
+
+	for _, article := range backlinks {
+		if err := s.rerender(ctx, &article); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rerender renders a stored article again and saves the result. The article is published already,
+// so problems found in its markdown are only logged.
+func (s *Service) rerender(ctx context.Context, article *Article) error {
+	var validationErr *ValidationError
+	if err := s.render(ctx, article); errors.As(err, &validationErr) {
+		slog.Warn("Article has problems", "slug", article.Slug,
+			"problems", slices.Concat(validationErr.Problems, validationErr.Warnings),
+		)
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := s.repo.Update(ctx, article.ID, *article); err != nil {
+		return err
+	}
+	return s.repo.SetLinks(ctx, article.ID, s.renderer.LinkTargets(article.Content))
+}
+
+// validate renders article like render. The warnings of a *ValidationError are returned on their own,
+// it's only returned as an error when it has problems.
+func (s *Service) validate(ctx context.Context, article *Article) ([]string, error) {
+	err := s.render(ctx, article)
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) && len(validationErr.Problems) == 0 {
+		return validationErr.Warnings, nil
+	}
+	return nil, err
+}
+
+// render stores the rendered HTML in article. It is stored even if a *ValidationError is returned.
+func (s *Service) render(ctx context.Context, article *Article) error {
+	contentHTML, contentErr := s.renderer.RenderContent(ctx, article.Content, article.References)
+	thumbnailHTML, thumbnailErr := s.renderer.RenderThumbnail(ctx, article.Thumbnail)
+
+	// The problems of both are reported as a single *ValidationError
+	var contentProblems, thumbnailProblems *ValidationError
+	if errors.As(contentErr, &contentProblems) && errors.As(thumbnailErr, &thumbnailProblems) {
+		contentErr = &ValidationError{
+			Problems: slices.Concat(contentProblems.Problems, thumbnailProblems.Problems),
+			Warnings: slices.Concat(contentProblems.Warnings, thumbnailProblems.Warnings),
+		}
+		thumbnailErr = nil
+	}
+
+	article.ContentHTML = contentHTML
+	article.ThumbnailHTML = thumbnailHTML
+	if err := errors.Join(contentErr, thumbnailErr); err != nil {
+		return errors.Join(ErrArticleRenderingFailed, err)
+	}
+	return nil
+}
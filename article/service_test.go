@@ -2,6 +2,7 @@ package article_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -9,12 +10,14 @@ import (
 	"github.com/stretchr/testify/require"
 
 	a "github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
 	"github.com/jannawro/blog/repository/mock"
 )
 
 func setupTestService() (*a.Service, *mock.Repository) {
 	mockRepo := mock.NewRepository()
-	articleService := a.NewService(mockRepo)
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, components.DefaultShortcodes(), mockRepo)
+	articleService := a.NewService(mockRepo, renderer)
 	return articleService, mockRepo
 }
 
@@ -32,7 +35,7 @@ func TestCreate(t *testing.T) {
 		PublicationDate: time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC),
 	}
 
-	response, err := service.Create(ctx, testArticle)
+	response, _, err := service.Create(ctx, testArticle)
 	assert.NoError(t, err)
 
 	require.NotNil(t, response)
@@ -241,7 +244,7 @@ func TestUpdateByTitle(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			updatedArticle, err := service.UpdateBySlug(ctx, tt.initialSlug, tt.updated)
+			updatedArticle, _, err := service.UpdateBySlug(ctx, tt.initialSlug, tt.updated)
 
 			if tt.expectedErr {
 				assert.Error(t, err)
@@ -294,3 +297,183 @@ func TestDeleteByTitle(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateStoresRenderedHTML(t *testing.T) {
+	service, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	created, _, err := service.Create(ctx, a.Article{
+		Title:     "Rendered",
+		Slug:      "rendered",
+		Thumbnail: "A *short* thumbnail",
+		Content:   "# Heading\nSome **bold** text.",
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, created.ThumbnailHTML, "<em>short</em>")
+	assert.Contains(t, created.ContentHTML, `id="heading"`)
+	assert.Contains(t, created.ContentHTML, "<strong>bold</strong>")
+
+	stored, err := mockRepo.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ContentHTML, stored.ContentHTML)
+	assert.Equal(t, created.ThumbnailHTML, stored.ThumbnailHTML)
+}
+
+func TestRerenderAll(t *testing.T) {
+	service, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	mockRepo.SetArticles([]a.Article{
+		{ID: 1, Slug: "article-1", Content: "First *article*", ContentHTML: "stale"},
+		{ID: 2, Slug: "article-2", Content: "Second *article*"},
+	})
+
+	count, err := service.RerenderAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	for _, id := range []int64{1, 2} {
+		stored, err := mockRepo.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Contains(t, stored.ContentHTML, "<em>article</em>")
+	}
+}
+
+func TestBacklinks(t *testing.T) {
+	service, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	_, _, err := service.Create(ctx, a.Article{
+		Title:           "Target",
+		Slug:            "target",
+		Content:         "Nothing to see here.",
+		PublicationDate: time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	source, _, err := service.Create(ctx, a.Article{
+		Title:           "Source",
+		Slug:            "source",
+		Content:         "Read [[target]] and [[target|this one]] again.",
+		PublicationDate: time.Date(2023, 5, 11, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Contains(t, source.ContentHTML, `<a href="/article/target">Target</a>`)
+	assert.Contains(t, source.ContentHTML, `<a href="/article/target">this one</a>`)
+
+	backlinks, err := service.GetBacklinks(ctx, "target")
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, "source", backlinks[0].Slug)
+
+	t.Run("Save links to missing articles with a warning", func(t *testing.T) {
+		broken, warnings, err := service.Create(ctx, a.Article{Title: "Broken", Slug: "broken", Content: "See [[missing]]."})
+		require.NoError(t, err)
+		assert.Equal(t, []string{`[[missing]]: no article with slug "missing"`}, warnings)
+		assert.Contains(t, broken.ContentHTML, "See missing.")
+
+		_, warnings, err = service.UpdateBySlug(ctx, "broken", a.Article{Title: "Broken", Slug: "broken", Content: "See [[source]]."})
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("Reject invalid shortcodes", func(t *testing.T) {
+		_, _, err := service.Create(ctx, a.Article{Title: "Invalid", Slug: "invalid", Content: "{{< youtub abc >}}"})
+		var validationErr *a.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Problems, 1)
+	})
+
+	t.Run("Rerender linking articles when the target is deleted", func(t *testing.T) {
+		require.NoError(t, service.DeleteBySlug(ctx, "target"))
+
+		stored, err := mockRepo.GetBySlug(ctx, "source")
+		require.NoError(t, err)
+		assert.NotContains(t, stored.ContentHTML, `href="/article/target"`)
+		assert.Contains(t, stored.ContentHTML, "Read target and this one again.")
+	})
+}
+
+// failingLinks is a repository failing to save and look up links
+type failingLinks struct {
+	*mock.Repository
+}
+
+func (failingLinks) SetLinks(context.Context, int64, []string) error {
+	return errors.New("links table unavailable")
+}
+
+func (failingLinks) GetBacklinks(context.Context, string) (a.Articles, error) {
+	return nil, errors.New("links table unavailable")
+}
+
+func TestSaveSurvivesLinkFailures(t *testing.T) {
+	mockRepo := mock.NewRepository()
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, components.DefaultShortcodes(), mockRepo)
+	service := a.NewService(failingLinks{mockRepo}, renderer)
+	ctx := context.Background()
+
+	created, _, err := service.Create(ctx, a.Article{Title: "Saved", Slug: "saved", Content: "Nothing to see here."})
+	require.NoError(t, err)
+	assert.Equal(t, "saved", created.Slug)
+
+	updated, _, err := service.UpdateBySlug(ctx, "saved", a.Article{Title: "Saved", Slug: "saved", Content: "Still nothing."})
+	require.NoError(t, err)
+	assert.Equal(t, "Still nothing.", updated.Content)
+
+	require.NoError(t, service.DeleteBySlug(ctx, "saved"))
+	_, err = mockRepo.GetBySlug(ctx, "saved")
+	assert.ErrorIs(t, err, a.ErrArticleNotFound)
+}
+
+func TestApply(t *testing.T) {
+	service, mockRepo := setupTestService()
+	ctx := context.Background()
+
+	// Articles changed outside the service, e.g. in the files of the filesystem backend
+	mockRepo.SetArticles([]a.Article{
+		{ID: 1, Title: "Target", Slug: "target", Content: "Nothing to see here."},
+		{ID: 2, Title: "Source", Slug: "source", Content: "Read [[target]]."},
+	})
+	require.NoError(t, service.Apply(ctx, a.Change{Operation: a.ChangeUnknown}))
+	source, err := mockRepo.GetBySlug(ctx, "source")
+	require.NoError(t, err)
+	assert.Contains(t, source.ContentHTML, `<a href="/article/target">Target</a>`)
+
+	t.Run("Updated", func(t *testing.T) {
+		target, err := mockRepo.GetBySlug(ctx, "target")
+		require.NoError(t, err)
+		target.Title = "Renamed"
+		target.Content = "Something *new*."
+		_, err = mockRepo.Update(ctx, target.ID, *target)
+		require.NoError(t, err)
+
+		require.NoError(t, service.Apply(ctx, a.Change{
+			Operation:    a.ChangeUpdated,
+			ID:           target.ID,
+			Slug:         "target",
+			PreviousSlug: "target",
+		}))
+
+		target, err = mockRepo.GetBySlug(ctx, "target")
+		require.NoError(t, err)
+		assert.Contains(t, target.ContentHTML, "<em>new</em>")
+		source, err := mockRepo.GetBySlug(ctx, "source")
+		require.NoError(t, err)
+		assert.Contains(t, source.ContentHTML, `<a href="/article/target">Renamed</a>`)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		require.NoError(t, mockRepo.Delete(ctx, 1))
+
+		require.NoError(t, service.Apply(ctx, a.Change{Operation: a.ChangeDeleted, ID: 1, PreviousSlug: "target"}))
+
+		source, err := mockRepo.GetBySlug(ctx, "source")
+		require.NoError(t, err)
+		assert.NotContains(t, source.ContentHTML, `href="/article/target"`)
+	})
+
+	t.Run("Created and deleted again", func(t *testing.T) {
+		assert.NoError(t, service.Apply(ctx, a.Change{Operation: a.ChangeCreated, ID: 42, Slug: "gone"}))
+	})
+}
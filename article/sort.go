@@ -18,19 +18,30 @@ const (
 // Sort sorts the Articles slice based on the given SortOption
 func (a Articles) Sort(option SortOption) {
 	sort.Slice(a, func(i, j int) bool {
-		switch option {
-		case SortByTitle:
-			return strings.ToLower(a[i].Title) < strings.ToLower(a[j].Title)
-		case SortByPublicationDate:
-			return a[i].PublicationDate.Before(a[j].PublicationDate)
-		case SortByID:
-			return a[i].ID < a[j].ID
-		default:
-			return false
-		}
+		return option.less(a[i].Summary(), a[j].Summary())
 	})
 }
 
+// Sort sorts the ArticleSummaries slice based on the given SortOption
+func (s ArticleSummaries) Sort(option SortOption) {
+	sort.Slice(s, func(i, j int) bool {
+		return option.less(s[i], s[j])
+	})
+}
+
+func (option SortOption) less(a, b ArticleSummary) bool {
+	switch option {
+	case SortByTitle:
+		return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+	case SortByPublicationDate:
+		return a.PublicationDate.Before(b.PublicationDate)
+	case SortByID:
+		return a.ID < b.ID
+	default:
+		return false
+	}
+}
+
 func GetSortOption(r *http.Request) SortOption {
 	sortParam := r.URL.Query().Get("sort")
 	switch sortParam {
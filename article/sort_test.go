@@ -45,3 +45,20 @@ func TestArticlesSort(t *testing.T) {
 		assert.Equal(t, int64(3), sorted[2].ID)
 	})
 }
+
+func TestArticleSummariesSort(t *testing.T) {
+	summaries := article1.ArticleSummaries{
+		{ID: 3, Title: "c Article", PublicationDate: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 1, Title: "B Article", PublicationDate: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Title: "a Article", PublicationDate: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	summaries.Sort(article1.SortByTitle)
+	assert.Equal(t, []int64{2, 1, 3}, []int64{summaries[0].ID, summaries[1].ID, summaries[2].ID})
+
+	summaries.Sort(article1.SortByPublicationDate)
+	assert.Equal(t, []int64{3, 2, 1}, []int64{summaries[0].ID, summaries[1].ID, summaries[2].ID})
+
+	summaries.Sort(article1.SortByID)
+	assert.Equal(t, []int64{1, 2, 3}, []int64{summaries[0].ID, summaries[1].ID, summaries[2].ID})
+}
@@ -0,0 +1,93 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Sync makes the stored articles match the markdown files in files, keyed by their names. Articles are created or
+// updated from the files, articles without a file are deleted and articles whose markdown didn't change are left
+// alone. Like Import, problems found in the markdown don't stop an article from being saved.
+//
+// Markdown files without article headers, like a README, are skipped. Nothing is deleted if any of the articles
+// can't be read, it may hold an article under a new name, or if there are no articles at all, which more likely
+// means they are missing than that every article should go.
+func (s *Service) Sync(ctx context.Context, files map[string][]byte) ([]ImportResult, error) {
+	stored, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, errors.Join(ErrArticlesNotFound, err)
+	}
+	storedBySlug := make(map[string]Article, len(stored))
+	for _, article := range stored {
+		storedBySlug[article.Slug] = article
+	}
+
+	var results []ImportResult
+	slugs := make(map[string]string)
+	failed := false
+	articles := 0
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if !looksLikeArticle(files[name]) {
+			slog.Info("Skipping a file that isn't an article", "file", name)
+			continue
+		}
+		articles++
+
+		var article Article
+		if err := UnmarshalToArticle(files[name], &article); err == nil {
+			existing, ok := storedBySlug[article.Slug]
+			if _, duplicate := slugs[article.Slug]; ok && !duplicate && existing.SameMarkdown(article) {
+				slugs[article.Slug] = name
+				results = append(results, ImportResult{File: name, Slug: article.Slug, Status: ImportUnchanged})
+				continue
+			}
+		}
+
+		result := s.importFile(ctx, name, files[name], ImportOptions{Conflict: ConflictOverwrite}, slugs)
+		if result.Status == ImportFailed {
+			failed = true
+		} else {
+			slugs[result.Slug] = name
+		}
+		results = append(results, result)
+	}
+
+	if failed {
+		slog.Warn("Not deleting articles, some files failed to sync")
+	} else if articles == 0 {
+		slog.Warn("Not deleting articles, there are no articles to sync")
+	} else {
+		for _, article := range stored {
+			if _, ok := slugs[article.Slug]; ok {
+				continue
+			}
+			result := ImportResult{Slug: article.Slug, Status: ImportDeleted}
+			if err := s.DeleteBySlug(ctx, article.Slug); err != nil {
+				result.Status = ImportFailed
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+		}
+	}
+
+	s.refreshProblems(ctx, results)
+	return results, nil
+}
+
+// looksLikeArticle reports whether data starts with the headers of an article, as opposed to other markdown
+func looksLikeArticle(data []byte) bool {
+	headers, _, found := strings.Cut(string(data), separator)
+	if !found {
+		return false
+	}
+	for _, line := range strings.Split(headers, "\n") {
+		if key, _, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) == "title" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package article_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	a "github.com/jannawro/blog/article"
+)
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	service, mockRepo := setupTestService()
+	files := map[string][]byte{
+		"first.md":  a.MarshalArticle(exportedArticles()[0]),
+		"second.md": a.MarshalArticle(exportedArticles()[1]),
+	}
+
+	results, err := service.Sync(ctx, files)
+	require.NoError(t, err)
+	assert.Equal(t, []a.ImportResult{
+		{File: "first.md", Slug: "first-article", Status: a.ImportCreated},
+		{File: "second.md", Slug: "second-article", Status: a.ImportCreated},
+	}, results)
+	first, err := mockRepo.GetBySlug(ctx, "first-article")
+	require.NoError(t, err)
+	// The link to the article synced after it resolves
+	assert.Contains(t, first.ContentHTML, `<a href="/article/second-article">Second article</a>`)
+
+	t.Run("Unchanged", func(t *testing.T) {
+		results, err := service.Sync(ctx, files)
+		require.NoError(t, err)
+		assert.Equal(t, []a.ImportResult{
+			{File: "first.md", Slug: "first-article", Status: a.ImportUnchanged},
+			{File: "second.md", Slug: "second-article", Status: a.ImportUnchanged},
+		}, results)
+	})
+
+	t.Run("Updated and deleted", func(t *testing.T) {
+		updated := exportedArticles()[1]
+		updated.Content = "Rewritten."
+		results, err := service.Sync(ctx, map[string][]byte{"second.md": a.MarshalArticle(updated)})
+		require.NoError(t, err)
+		assert.Equal(t, []a.ImportResult{
+			{File: "second.md", Slug: "second-article", Status: a.ImportUpdated},
+			{Slug: "first-article", Status: a.ImportDeleted},
+		}, results)
+
+		stored, err := mockRepo.GetBySlug(ctx, "second-article")
+		require.NoError(t, err)
+		assert.Equal(t, "Rewritten.", stored.Content)
+		_, err = mockRepo.GetBySlug(ctx, "first-article")
+		assert.ErrorIs(t, err, a.ErrArticleNotFound)
+	})
+
+	t.Run("Nothing is deleted when a file fails", func(t *testing.T) {
+		broken := []byte("title:Broken\npublicationDate:yesterday\n===\nBroken.")
+		results, err := service.Sync(ctx, map[string][]byte{"broken.md": broken})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, a.ImportFailed, results[0].Status)
+
+		_, err = mockRepo.GetBySlug(ctx, "second-article")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Nothing is deleted without files", func(t *testing.T) {
+		results, err := service.Sync(ctx, map[string][]byte{})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		_, err = mockRepo.GetBySlug(ctx, "second-article")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Files that aren't articles are skipped", func(t *testing.T) {
+		results, err := service.Sync(ctx, map[string][]byte{
+			"README.md":       []byte("# Articles\n\nOne file per article."),
+			"CONTRIBUTING.md": []byte("Headings\n===\n\nOpen a pull request."),
+		})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		_, err = mockRepo.GetBySlug(ctx, "second-article")
+		assert.NoError(t, err)
+	})
+}
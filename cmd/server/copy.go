@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository/backend"
+)
+
+const copyUsage = `Usage: copy [--force] <target database URL>
+
+Copies every article, the links between them and the media records from the database of --database-url to the
+target database, which has to be empty. Article IDs are kept where the target supports it. Media files stay in the
+media directory.
+
+The copy isn't atomic, a failure leaves what was copied so far in the target. Run it again with --force to copy
+into a target that isn't empty, articles and media already there are kept and the rest is copied.
+`
+
+var (
+	errCopyUsage    = errors.New("invalid copy arguments")
+	errCopyMismatch = errors.New("the target doesn't hold everything the source does")
+)
+
+func copyCommand(
+	store *backend.Backend,
+	args []string,
+	options backend.Options,
+	linkTargets func(markdown string) []string,
+) error {
+	var copyOptions backend.CopyOptions
+	if len(args) > 0 && args[0] == "--force" {
+		copyOptions.Force = true
+		args = args[1:]
+	}
+	if len(args) != 1 || args[0] == "help" {
+		fmt.Fprint(os.Stderr, copyUsage)
+		if len(args) == 1 {
+			return nil
+		}
+		return errCopyUsage
+	}
+
+	// The target is written to only, its replicas don't matter
+	options.ReplicaURLs = nil
+	ctx := middleware.WithPrimaryReads(context.Background())
+	target, err := backend.Open(ctx, args[0], options)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := target.Close(); err != nil {
+			slog.Error("Encountered an unexpected error when closing the target database", "error", err)
+		}
+	}()
+
+	slog.Info("Copying the database...")
+	report, err := backend.Copy(ctx, target, store, linkTargets, copyOptions)
+	if err != nil {
+		return err
+	}
+	if !report.PreservedIDs {
+		slog.Warn("The target database can't keep article IDs, the copied articles got new ones")
+	}
+	for _, c := range []struct {
+		kind  string
+		count backend.Count
+	}{
+		{"articles", report.Articles},
+		{"tags", report.Tags},
+		{"links", report.Links},
+		{"media", report.Media},
+		{"variants", report.Variants},
+	} {
+		slog.Info("Copied "+c.kind, "source", c.count.Source, "target", c.count.Target, "matches", c.count.Matches())
+	}
+	if !report.Matches() {
+		return errCopyMismatch
+	}
+	slog.Info("Copied the database")
+	return nil
+}
@@ -4,28 +4,52 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
+	"github.com/jannawro/blog/gitsync"
 	"github.com/jannawro/blog/handlers/assets"
 	"github.com/jannawro/blog/handlers/html"
+	mediahandler "github.com/jannawro/blog/handlers/media"
 	"github.com/jannawro/blog/handlers/rest"
+	"github.com/jannawro/blog/media"
 	"github.com/jannawro/blog/middleware"
-	"github.com/jannawro/blog/repository/postgres"
-	"github.com/jannawro/blog/repository/postgres/migrations"
+	"github.com/jannawro/blog/outbox"
+	"github.com/jannawro/blog/repository"
+	"github.com/jannawro/blog/repository/backend"
+	"github.com/jannawro/blog/repository/cache"
 )
 
 var (
-	port        string
-	apiKey      string
-	databaseURL string
-	logLevel    string
+	port           string
+	apiKey         string
+	databaseURL    string
+	replicaURLs    string
+	dbPool         poolFlags
+	dbQueryTimeout string
+	dbRetry        retryFlags
+	logLevel       string
+	embedHosts     string
+	mediaDir       string
+	imageWidths    string
+	includeDir     string
+	maxArticleSize string
+	autoMigrate    bool
+	cacheSize      string
+	cacheTTL       string
+	gitSync        gitSyncFlags
+	webhookURLs    string
+	webhookSecret  string
+	logEvents      bool
 )
 
 const assetsPath = "/assets/"
@@ -38,25 +62,149 @@ func main() {
 	}))
 	slog.SetDefault(logger)
 
-	postgresDatabase, err := postgres.NewDatabase(databaseURL)
+	command := flag.Arg(0)
+	pool, err := dbPool.parse()
+	if err != nil {
+		panic(err)
+	}
+	queryTimeout, err := time.ParseDuration(dbQueryTimeout)
+	if err != nil {
+		panic(fmt.Errorf("invalid query timeout %q: %w", dbQueryTimeout, err))
+	}
+	retry, err := dbRetry.parse()
+	if err != nil {
+		panic(err)
+	}
+	cacheOptions, err := parseCacheOptions(cacheSize, cacheTTL)
+	if err != nil {
+		panic(err)
+	}
+	serving := command == "" || command == "serve"
+	options := backend.Options{
+		Options: repository.Options{
+			SkipMigrations: !autoMigrate || command == "migrate",
+			QueryTimeout:   queryTimeout,
+		},
+		Pool:  pool,
+		Retry: retry,
+		// The cache learns about writes made by other instances from the changes
+		ListenForChanges: serving && cacheOptions.Size > 0,
+	}
+	if replicaURLs != "" {
+		options.ReplicaURLs = strings.Split(replicaURLs, ",")
+	}
+	store, err := backend.Open(context.Background(), databaseURL, options)
 	if err != nil {
 		panic(err)
 	}
 	defer func() {
-		err = postgresDatabase.Close()
+		err = store.Close()
 		if err != nil {
 			slog.Error("Encountered an unexptected error when closing the database connection", "error", err)
 			panic(err)
 		}
 	}()
-	postgresRepo, err := postgres.NewRepository(postgresDatabase, migrations.Files())
+
+	if command == "migrate" {
+		if err := migrateCommand(store, flag.Args()[1:]); err != nil {
+			slog.Error("Encountered an unexpected error when migrating the database", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	mediaStorage, err := media.NewLocalStorage(mediaDir)
+	if err != nil {
+		panic(err)
+	}
+	variantWidths, err := parseWidths(imageWidths)
 	if err != nil {
 		panic(err)
 	}
+	mediaService := media.NewService(store.Media, mediaStorage, variantWidths)
+
+	sanitizerConfig := components.DefaultSanitizerConfig()
+	if embedHosts != "" {
+		sanitizerConfig.EmbedHosts = strings.Split(embedHosts, ",")
+	}
+	rendererOptions := components.DefaultRendererOptions()
+	rendererOptions.IncludeDir = includeDir
+	if maxArticleSize != "" {
+		rendererOptions.MaxDocumentSize, err = strconv.Atoi(maxArticleSize)
+		if err != nil {
+			panic(fmt.Errorf("invalid max article size %q: %w", maxArticleSize, err))
+		}
+	}
+	articles := store.Articles
+	var cacheStats func() cache.Stats
+	if cacheOptions.Size > 0 {
+		if len(store.Replicas) > 0 {
+			cacheOptions.ReplicaLag = cache.DefaultReplicaLag
+		}
+		cached := cache.NewRepository(store.Articles, cacheOptions)
+		articles, cacheStats = cached, cached.Stats
+		if store.Changes != nil {
+			store.Changes.Subscribe(cached.Apply)
+		}
+	}
+
+	renderer := components.NewRenderer(
+		rendererOptions,
+		components.NewSanitizer(sanitizerConfig),
+		mediaService,
+		components.DefaultShortcodes(),
+		articles,
+	)
+
+	articleService := article.NewService(articles, renderer)
+
+	switch command {
+	case "", "serve":
+		if store.Unrendered {
+			rerender(articleService)
+			// Subscribed after the cache, so the cache drops the changed articles before they're rendered
+			store.Changes.Subscribe(func(change article.Change) {
+				if err := articleService.Apply(context.Background(), change); err != nil {
+					slog.Error("Encountered an unexpected error when rendering changed articles",
+						"operation", change.Operation, "slug", change.Slug, "error", err)
+				}
+			})
+		}
+	case "rerender":
+		rerender(articleService)
+		return
+	case "copy":
+		if store.Unrendered {
+			rerender(articleService)
+		}
+		if err := copyCommand(store, flag.Args()[1:], options, renderer.LinkTargets); err != nil {
+			slog.Error("Encountered an unexpected error when copying the database", "error", err)
+			os.Exit(1)
+		}
+		return
+	default:
+		slog.Error("Unknown command", "command", command)
+		os.Exit(2)
+	}
 
-	articleService := article.NewService(postgresRepo)
 	htmlHandler := html.NewHandler(articleService, assetsPath)
 	restHandler := rest.NewHandler(articleService)
+	mediaHandler := rest.NewMediaHandler(mediaService)
+	diagnosticsHandler := rest.NewDiagnosticsHandler(store.Stats, cacheStats)
+
+	var syncer *gitsync.Syncer
+	if gitSync.url != "" {
+		syncOptions, err := gitSync.parse()
+		if err != nil {
+			panic(err)
+		}
+		syncer = gitsync.NewSyncer(articleService, syncOptions)
+		defer func() {
+			if err := syncer.Close(); err != nil {
+				slog.Warn("Failed to remove the checkout of the article repository", "error", err)
+			}
+		}()
+	}
 
 	assetsRouter := http.NewServeMux()
 	assetsRouter.Handle("GET "+assetsPath, assets.Serve(assetsPath))
@@ -64,7 +212,11 @@ func main() {
 	frontendRouter := http.NewServeMux()
 	frontendRouter.Handle("GET /", htmlHandler.ServeBlog())
 	frontendRouter.Handle("GET /index", htmlHandler.ServeIndex())
+	frontendRouter.Handle("GET /archive", htmlHandler.ServeArchive())
+	frontendRouter.Handle("GET /archive/{year}", htmlHandler.ServeArchivePeriod("year", "month"))
+	frontendRouter.Handle("GET /archive/{year}/{month}", htmlHandler.ServeArchivePeriod("year", "month"))
 	frontendRouter.Handle("GET /article/{title}", htmlHandler.ServeArticle("title"))
+	frontendRouter.Handle("GET "+media.PathPrefix+"{hash}/{name}", mediahandler.Serve(mediaService, "hash", "name"))
 	frontendStack := middleware.CreateStack(
 		middleware.Logging(),
 	)
@@ -78,8 +230,18 @@ func main() {
 	apiRouter.Handle("PUT /api/articles/{title}", restHandler.UpdateArticleByTitle("title"))
 	apiRouter.Handle("DELETE /api/articles/{title}", restHandler.DeleteArticleByTitle("title"))
 	apiRouter.Handle("GET /api/tags", restHandler.GetAllTags())
+	apiRouter.Handle("GET /api/export", restHandler.ExportArticles())
+	apiRouter.Handle("POST /api/import", restHandler.ImportArticles())
+	apiRouter.Handle("POST /api/media", mediaHandler.UploadMedia())
+	apiRouter.Handle("GET /api/media", mediaHandler.GetAllMedia())
+	apiRouter.Handle("GET /api/diagnostics/database", diagnosticsHandler.DatabaseStats())
+	apiRouter.Handle("GET /api/diagnostics/cache", diagnosticsHandler.CacheStats())
+	if syncer != nil {
+		apiRouter.Handle("POST /api/sync", rest.NewSyncHandler(syncer).Sync())
+	}
 	apiStack := middleware.CreateStack(
 		middleware.Logging(),
+		middleware.PrimaryReads(),
 		middleware.APIKeyAuth(middleware.APIKeyConfig{
 			KeyName: "X-API-Key",
 			Keys: map[string]bool{
@@ -107,6 +269,15 @@ func main() {
 		}
 	}()
 
+	workersCtx, stopWorkers := context.WithCancel(middleware.WithPrimaryReads(context.Background()))
+	defer stopWorkers()
+	if syncer != nil {
+		go syncer.Run(workersCtx)
+	}
+	if store.Events != nil {
+		go outbox.NewDispatcher(store.Events, eventSinks(), outbox.DefaultOptions()).Run(workersCtx)
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 	<-quit
@@ -121,18 +292,200 @@ func main() {
 	}
 }
 
+// eventSinks returns the sinks the article events are delivered to. Without any the events are dropped.
+func eventSinks() []outbox.Sink {
+	var sinks []outbox.Sink
+	if logEvents {
+		sinks = append(sinks, outbox.LogSink{})
+	}
+	for _, url := range strings.Split(webhookURLs, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			sinks = append(sinks, outbox.NewWebhookSink(url, webhookSecret))
+		}
+	}
+	return sinks
+}
+
+func rerender(articleService *article.Service) {
+	slog.Info("Rendering all articles...")
+	count, err := articleService.RerenderAll(middleware.WithPrimaryReads(context.Background()))
+	if err != nil {
+		slog.Error("Encountered an unexpected error when rendering articles", "rendered", count, "error", err)
+		panic(err)
+	}
+	slog.Info("Rendered all articles", "rendered", count)
+}
+
 func parseArguments() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Commands:")
+		fmt.Fprintln(flag.CommandLine.Output(), "  serve     Run the blog server (default)")
+		fmt.Fprintln(flag.CommandLine.Output(), "  rerender  Render all stored articles again, e.g. after the renderer changed")
+		fmt.Fprintln(flag.CommandLine.Output(), "  migrate   Manage the database schema, run \"migrate help\" for the subcommands")
+		fmt.Fprintln(flag.CommandLine.Output(), "  copy      Copy everything to another, empty database, run \"copy help\" for details")
+		fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+		flag.PrintDefaults()
+	}
+
 	flag.StringVar(&port, "port", os.Getenv("PORT"), "The port the server should listen on. The default is 8888.")
 	flag.StringVar(&apiKey, "api-key", os.Getenv("API_KEY"), "API Key for the /api endpoints.")
 	flag.StringVar(&databaseURL,
 		"database-url",
 		os.Getenv("DATABASE_URL"),
-		"Database URL. Should be a connection string containing auth credentials and configuration.",
+		"Database URL. The scheme selects the backend: "+strings.Join(backend.Schemes(), ", ")+
+			". memory:// keeps everything in memory and loses it on restart, fs://<dir>?writable=true&poll=5s serves "+
+			"the markdown files in a directory.",
+	)
+	flag.StringVar(&replicaURLs,
+		"database-replica-urls",
+		os.Getenv("DATABASE_REPLICA_URLS"),
+		"Comma separated list of database URLs of read replicas. Page reads are spread over them, API requests always use the primary.",
+	)
+	flag.StringVar(&dbPool.maxOpenConns,
+		"db-max-open-conns",
+		os.Getenv("DB_MAX_OPEN_CONNS"),
+		"Maximum number of open connections per database. Unlimited by default.",
+	)
+	flag.StringVar(&dbPool.maxIdleConns,
+		"db-max-idle-conns",
+		os.Getenv("DB_MAX_IDLE_CONNS"),
+		"Maximum number of idle connections kept per database. The default is 2.",
+	)
+	flag.StringVar(&dbPool.connMaxLifetime,
+		"db-conn-max-lifetime",
+		os.Getenv("DB_CONN_MAX_LIFETIME"),
+		"How long a database connection may be reused, e.g. 30m. Unlimited by default.",
+	)
+	flag.StringVar(&dbPool.connMaxIdleTime,
+		"db-conn-max-idle-time",
+		os.Getenv("DB_CONN_MAX_IDLE_TIME"),
+		"How long a database connection may stay idle before it's closed, e.g. 5m. Unlimited by default.",
+	)
+	flag.StringVar(&dbQueryTimeout,
+		"db-query-timeout",
+		envOrDefault("DB_QUERY_TIMEOUT", "10s"),
+		"Longest a single database call may take before it's cancelled, 0 disables the limit.",
+	)
+	flag.StringVar(&dbRetry.timeout,
+		"db-connect-timeout",
+		envOrDefault("DB_CONNECT_TIMEOUT", "1m"),
+		"How long to keep retrying to connect to and migrate the database on startup, 0 gives up after the first attempt.",
+	)
+	flag.StringVar(&dbRetry.initialBackoff,
+		"db-connect-backoff",
+		envOrDefault("DB_CONNECT_BACKOFF", "500ms"),
+		"Wait before the first retry to connect to the database. It doubles with every retry.",
+	)
+	flag.StringVar(&dbRetry.maxBackoff,
+		"db-connect-max-backoff",
+		envOrDefault("DB_CONNECT_MAX_BACKOFF", "10s"),
+		"Longest wait between retries to connect to the database.",
 	)
 	flag.StringVar(&logLevel, "log-level", os.Getenv("LOG_LEVEL"), "Set the log level (debug, info, warn, error)")
+	flag.StringVar(&mediaDir, "media-dir", envOrDefault("MEDIA_DIR", "data/media"), "Directory uploaded media files are stored in.")
+	flag.StringVar(&imageWidths,
+		"image-widths",
+		envOrDefault("IMAGE_WIDTHS", joinWidths(media.DefaultVariantWidths)),
+		"Comma separated list of widths, in pixels, resized variants of uploaded images are generated at.",
+	)
+	flag.StringVar(&embedHosts,
+		"embed-hosts",
+		os.Getenv("EMBED_HOSTS"),
+		"Comma separated list of hosts articles may embed iframes from. Defaults to YouTube, itch.io and GitHub gists.",
+	)
+	flag.StringVar(&includeDir,
+		"include-dir",
+		os.Getenv("INCLUDE_DIR"),
+		"Directory articles can include files from with {{file}}. Includes are disabled when empty.",
+	)
+	flag.StringVar(&maxArticleSize,
+		"max-article-size",
+		os.Getenv("MAX_ARTICLE_SIZE"),
+		"Largest article markdown accepted, in bytes. The default is 512KiB, 0 disables the limit.",
+	)
+	flag.StringVar(&cacheSize,
+		"cache-size",
+		envOrDefault("CACHE_SIZE", strconv.Itoa(cache.DefaultOptions().Size)),
+		"Number of article reads kept in memory, 0 disables the cache.",
+	)
+	flag.StringVar(&cacheTTL,
+		"cache-ttl",
+		envOrDefault("CACHE_TTL", cache.DefaultOptions().TTL.String()),
+		"How long a cached article read is served. Changes made through another instance show up after at most this "+
+			"long, the postgres backend notifies about them right away.",
+	)
+	flag.StringVar(&gitSync.url,
+		"git-sync-url",
+		os.Getenv("GIT_SYNC_URL"),
+		"Git repository holding the articles as markdown files, a URL or a local path. The articles are created, "+
+			"updated and deleted to match it on startup, at the sync interval and on POST /api/sync. Disabled when empty.",
+	)
+	flag.StringVar(&gitSync.branch,
+		"git-sync-branch",
+		os.Getenv("GIT_SYNC_BRANCH"),
+		"Branch of the git repository to sync. The default branch of the repository by default.",
+	)
+	flag.StringVar(&gitSync.dir,
+		"git-sync-dir",
+		os.Getenv("GIT_SYNC_DIR"),
+		"Directory the git repository is checked out in. A temporary directory by default.",
+	)
+	flag.StringVar(&gitSync.path,
+		"git-sync-path",
+		os.Getenv("GIT_SYNC_PATH"),
+		"Directory of the articles inside the git repository. The root of the repository by default.",
+	)
+	flag.StringVar(&gitSync.interval,
+		"git-sync-interval",
+		envOrDefault("GIT_SYNC_INTERVAL", "5m"),
+		"How often the git repository is synced, 0 only syncs on startup and on POST /api/sync.",
+	)
+	flag.StringVar(&webhookURLs,
+		"event-webhook-urls",
+		os.Getenv("EVENT_WEBHOOK_URLS"),
+		"Comma separated list of URLs every article change is posted to as JSON. Failed deliveries are retried. "+
+			"Only the postgres and mysql backends record changes.",
+	)
+	flag.StringVar(&webhookSecret,
+		"event-webhook-secret",
+		os.Getenv("EVENT_WEBHOOK_SECRET"),
+		"Secret the article change webhooks are signed with in the X-Blog-Signature header. Unsigned when empty.",
+	)
+	flag.BoolVar(&logEvents,
+		"log-events",
+		os.Getenv("LOG_EVENTS") == "true",
+		"Log every article change.",
+	)
+	flag.BoolVar(&autoMigrate,
+		"auto-migrate",
+		os.Getenv("AUTO_MIGRATE") != "false",
+		"Migrate the database to the latest schema on startup. Disable it to run the migrate command separately.",
+	)
 	flag.Parse()
 }
 
+func parseCacheOptions(size, ttl string) (cache.Options, error) {
+	var options cache.Options
+	var err error
+	options.Size, err = strconv.Atoi(size)
+	if err != nil {
+		return options, fmt.Errorf("invalid cache size %q: %w", size, err)
+	}
+	options.TTL, err = time.ParseDuration(ttl)
+	if err != nil {
+		return options, fmt.Errorf("invalid cache TTL %q: %w", ttl, err)
+	}
+	return options, nil
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 func parseLogLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
@@ -147,3 +500,106 @@ func parseLogLevel(level string) slog.Level {
 		return slog.LevelInfo
 	}
 }
+
+// poolFlags holds the raw connection pool flags, empty values keep the database/sql defaults
+type poolFlags struct {
+	maxOpenConns    string
+	maxIdleConns    string
+	connMaxLifetime string
+	connMaxIdleTime string
+}
+
+func (f poolFlags) parse() (repository.PoolOptions, error) {
+	var pool repository.PoolOptions
+	var err error
+	if f.maxOpenConns != "" {
+		if pool.MaxOpenConns, err = strconv.Atoi(f.maxOpenConns); err != nil {
+			return pool, fmt.Errorf("invalid max open connections %q: %w", f.maxOpenConns, err)
+		}
+	}
+	if f.maxIdleConns != "" {
+		if pool.MaxIdleConns, err = strconv.Atoi(f.maxIdleConns); err != nil {
+			return pool, fmt.Errorf("invalid max idle connections %q: %w", f.maxIdleConns, err)
+		}
+	}
+	if f.connMaxLifetime != "" {
+		if pool.ConnMaxLifetime, err = time.ParseDuration(f.connMaxLifetime); err != nil {
+			return pool, fmt.Errorf("invalid connection max lifetime %q: %w", f.connMaxLifetime, err)
+		}
+	}
+	if f.connMaxIdleTime != "" {
+		if pool.ConnMaxIdleTime, err = time.ParseDuration(f.connMaxIdleTime); err != nil {
+			return pool, fmt.Errorf("invalid connection max idle time %q: %w", f.connMaxIdleTime, err)
+		}
+	}
+	return pool, nil
+}
+
+// retryFlags holds the raw flags configuring how opening the database is retried
+type retryFlags struct {
+	timeout        string
+	initialBackoff string
+	maxBackoff     string
+}
+
+func (f retryFlags) parse() (backend.RetryOptions, error) {
+	var retry backend.RetryOptions
+	var err error
+	if retry.Timeout, err = time.ParseDuration(f.timeout); err != nil {
+		return retry, fmt.Errorf("invalid database connect timeout %q: %w", f.timeout, err)
+	}
+	if retry.InitialBackoff, err = time.ParseDuration(f.initialBackoff); err != nil {
+		return retry, fmt.Errorf("invalid database connect backoff %q: %w", f.initialBackoff, err)
+	}
+	if retry.MaxBackoff, err = time.ParseDuration(f.maxBackoff); err != nil {
+		return retry, fmt.Errorf("invalid database connect max backoff %q: %w", f.maxBackoff, err)
+	}
+	return retry, nil
+}
+
+// gitSyncFlags holds the raw flags configuring the git sync
+type gitSyncFlags struct {
+	url      string
+	branch   string
+	dir      string
+	path     string
+	interval string
+}
+
+func (f gitSyncFlags) parse() (gitsync.Options, error) {
+	interval, err := time.ParseDuration(f.interval)
+	if err != nil {
+		return gitsync.Options{}, fmt.Errorf("invalid git sync interval %q: %w", f.interval, err)
+	}
+	return gitsync.Options{
+		URL:      f.url,
+		Branch:   f.branch,
+		Dir:      f.dir,
+		Path:     f.path,
+		Interval: interval,
+	}, nil
+}
+
+func parseWidths(widths string) ([]int, error) {
+	var parsed []int
+	for _, width := range strings.Split(widths, ",") {
+		width = strings.TrimSpace(width)
+		if width == "" {
+			continue
+		}
+		w, err := strconv.Atoi(width)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image width %q: %w", width, err)
+		}
+		parsed = append(parsed, w)
+	}
+	return parsed, nil
+}
+
+func joinWidths(widths []int) string {
+	formatted := make([]string, len(widths))
+	for i, w := range widths {
+		formatted[i] = strconv.Itoa(w)
+	}
+	return strings.Join(formatted, ",")
+}
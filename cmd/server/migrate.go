@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jannawro/blog/repository/backend"
+)
+
+const migrateUsage = `Usage: migrate <subcommand>
+
+Subcommands:
+  up         Apply all pending migrations
+  down [N]   Roll back the last N migrations, 1 by default
+  goto V     Migrate up or down to version V
+  version    Print the current version and whether it is dirty
+  force V    Set the version to V without running migrations, e.g. to clear the dirty flag after fixing a failed
+             migration by hand. V may be -1 to mark the database as never migrated.
+`
+
+var errMigrateUsage = errors.New("invalid migrate arguments")
+
+func migrateCommand(store *backend.Backend, args []string) error {
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		if len(args) == 0 {
+			return errMigrateUsage
+		}
+		return nil
+	}
+
+	m, err := store.Migration()
+	if err != nil {
+		return err
+	}
+
+	subcommand, args := args[0], args[1:]
+	switch subcommand {
+	case "up":
+		err = m.Up()
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("%w: down takes a positive number of migrations, got %q", errMigrateUsage, args[0])
+			}
+		}
+		err = m.Steps(-steps)
+	case "goto":
+		version, parseErr := versionArgument(subcommand, args)
+		if parseErr != nil {
+			return parseErr
+		}
+		if version < 0 {
+			return fmt.Errorf("%w: goto takes a version of 0 or more", errMigrateUsage)
+		}
+		err = m.Migrate(uint(version))
+	case "force":
+		version, parseErr := versionArgument(subcommand, args)
+		if parseErr != nil {
+			return parseErr
+		}
+		err = m.Force(version)
+	case "version":
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return fmt.Errorf("%w: unknown subcommand %q", errMigrateUsage, subcommand)
+	}
+	if errors.Is(err, migrate.ErrNoChange) {
+		slog.Info("The database schema is already at the requested version")
+	} else if err != nil {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		slog.Info("The database has no migrations applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("Database schema version", "version", version, "dirty", dirty)
+	return nil
+}
+
+func versionArgument(subcommand string, args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("%w: %s takes exactly one version", errMigrateUsage, subcommand)
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid version %q", errMigrateUsage, args[0])
+	}
+	return version, nil
+}
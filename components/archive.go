@@ -0,0 +1,46 @@
+package components
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/jannawro/blog/article"
+)
+
+// archiveYear is a year of the archive together with its months, newest first
+type archiveYear struct {
+	Year   int
+	Count  int
+	Months []article.ArchiveMonth
+}
+
+// archiveYears groups months, ordered newest first, by year
+func archiveYears(months []article.ArchiveMonth) []archiveYear {
+	var years []archiveYear
+	for _, month := range months {
+		if len(years) == 0 || years[len(years)-1].Year != month.Year {
+			years = append(years, archiveYear{Year: month.Year})
+		}
+		year := &years[len(years)-1]
+		year.Count += month.Count
+		year.Months = append(year.Months, month)
+	}
+	return years
+}
+
+func archiveYearURL(year int) templ.SafeURL {
+	return templ.SafeURL("/archive/" + strconv.Itoa(year))
+}
+
+func archiveMonthURL(month article.ArchiveMonth) templ.SafeURL {
+	return templ.SafeURL("/archive/" + strconv.Itoa(month.Year) + "/" + strconv.Itoa(int(month.Month)))
+}
+
+// archiveHeading names the period of an archive page, the month is zero for a whole year
+func archiveHeading(year int, month time.Month) string {
+	if month == 0 {
+		return strconv.Itoa(year)
+	}
+	return month.String() + " " + strconv.Itoa(year)
+}
@@ -0,0 +1,93 @@
+package components
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+
+	"github.com/jannawro/blog/article"
+)
+
+// BibliographyEntry is a reference rendered at the end of an article
+type BibliographyEntry struct {
+	ID   string
+	HTML string
+}
+
+// numberReferences numbers references in the order they are listed, starting at 1
+func numberReferences(references []article.Reference) map[string]int {
+	numbers := make(map[string]int, len(references))
+	for i, reference := range references {
+		numbers[reference.Key] = i + 1
+	}
+	return numbers
+}
+
+// checkCitations returns a problem for every citation in doc without a reference
+func checkCitations(doc ast.Node, numbers map[string]int) []string {
+	var problems []string
+	ast.WalkFunc(doc, func(node ast.Node, entering bool) ast.WalkStatus {
+		citation, ok := node.(*ast.Citation)
+		if !ok || !entering {
+			return ast.GoToNext
+		}
+		for _, key := range citation.Destination {
+			if _, ok := numbers[string(key)]; !ok {
+				problems = append(problems, fmt.Sprintf("[@%s]: no reference with key %q", key, key))
+			}
+		}
+		return ast.GoToNext
+	})
+	return problems
+}
+
+// renderCitation renders [@key] as the number of the reference in the bibliography, linking to it.
+// Several keys, [@a; @b], are rendered as [1, 2]. Keys without a reference are rendered as written.
+func renderCitation(w io.Writer, citation *ast.Citation, numbers map[string]int) (ast.WalkStatus, bool) {
+	io.WriteString(w, "<cite>[")
+	for i, key := range citation.Destination {
+		if i > 0 {
+			io.WriteString(w, ", ")
+		}
+		if number, ok := numbers[string(key)]; ok {
+			io.WriteString(w, `<a href="#`)
+			html.EscapeHTML(w, []byte(referenceID(string(key))))
+			fmt.Fprintf(w, `">%d</a>`, number)
+		} else {
+			io.WriteString(w, "@")
+			html.EscapeHTML(w, key)
+		}
+		if i < len(citation.Suffix) {
+			if suffix := bytes.TrimSpace(citation.Suffix[i]); len(suffix) > 0 {
+				io.WriteString(w, ", ")
+				html.EscapeHTML(w, suffix)
+			}
+		}
+	}
+	io.WriteString(w, "]</cite>")
+	return ast.GoToNext, true
+}
+
+// bibliographyEntries renders the markdown of every reference
+func bibliographyEntries(references []article.Reference) []BibliographyEntry {
+	renderer := html.NewRenderer(html.RendererOptions{Flags: html.CommonFlags})
+
+	entries := make([]BibliographyEntry, len(references))
+	for i, reference := range references {
+		doc := markdown.Parse([]byte(reference.Text), parser.NewWithExtensions(parser.CommonExtensions))
+		var b strings.Builder
+		renderInline(&b, doc, renderer)
+		entries[i] = BibliographyEntry{ID: referenceID(reference.Key), HTML: b.String()}
+	}
+	return entries
+}
+
+func referenceID(key string) string {
+	return "ref:" + key
+}
@@ -4,4 +4,32 @@ import (
 	"github.com/gomarkdown/markdown/parser"
 )
 
-const extensions parser.Extensions = parser.CommonExtensions | parser.Mmark
+// RendererOptions controls which markdown features articles can use and how large they can get.
+// Article markdown is sent through the API, so nothing reaching outside the document is enabled
+// unless configured.
+type RendererOptions struct {
+	// Extensions are the gomarkdown parser extensions articles are parsed with
+	Extensions parser.Extensions
+	// IncludeDir is the only directory files can be included from with Mmark's {{file.md}} syntax.
+	// Includes are disabled when it is empty, even if parser.Includes is one of the Extensions.
+	IncludeDir string
+	// MaxDocumentSize limits the size of the markdown in bytes. Larger documents aren't rendered. Zero means no limit.
+	MaxDocumentSize int
+	// MaxNestingDepth limits how deeply lists and block quotes can be nested. Deeper blocks are left out.
+	// Zero means no limit.
+	MaxNestingDepth int
+	// Sidenotes renders footnotes in the margin next to the text referencing them, instead of in a list
+	// at the end of the article. Narrow screens show them below the text once their number is tapped.
+	Sidenotes bool
+}
+
+// DefaultRendererOptions enables the common extensions, footnotes and Mmark syntax without includes.
+// Footnotes are rendered as sidenotes.
+func DefaultRendererOptions() RendererOptions {
+	return RendererOptions{
+		Extensions:      parser.CommonExtensions | parser.Footnotes | parser.Mmark,
+		MaxDocumentSize: 512 << 10,
+		MaxNestingDepth: 8,
+		Sidenotes:       true,
+	}
+}
@@ -0,0 +1,119 @@
+package components
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+
+	"github.com/jannawro/blog/media"
+)
+
+// ImageResolver looks up images uploaded to the blog together with their resized variants.
+// It is implemented by media.Service.
+type ImageResolver interface {
+	Image(ctx context.Context, src string) (*media.Image, error)
+}
+
+// imageSizes tells browsers how wide article images are displayed, so they can pick a variant
+// before the page is laid out. The article column is at most 70ch of text-xl, about 770px.
+const imageSizes = "(min-width: 800px) 770px, 100vw"
+
+// resolveImages looks up every uploaded image referenced in doc. Other images are left out.
+func resolveImages(ctx context.Context, resolver ImageResolver, doc ast.Node) (map[string]*media.Image, error) {
+	images := make(map[string]*media.Image)
+	if resolver == nil {
+		return images, nil
+	}
+
+	var err error
+	ast.WalkFunc(doc, func(node ast.Node, entering bool) ast.WalkStatus {
+		img, ok := node.(*ast.Image)
+		if !ok || !entering {
+			return ast.GoToNext
+		}
+		src := string(img.Destination)
+		if _, seen := images[src]; seen || !strings.HasPrefix(src, media.PathPrefix) {
+			return ast.GoToNext
+		}
+
+		resolved, resolveErr := resolver.Image(ctx, src)
+		if resolveErr != nil && !errors.Is(resolveErr, media.ErrMediaNotFound) {
+			err = resolveErr
+			return ast.Terminate
+		}
+		images[src] = resolved
+		return ast.GoToNext
+	})
+
+	return images, err
+}
+
+// renderImage renders an image loaded lazily. Uploaded images get their dimensions, so the page doesn't
+// jump around while they load, and a srcset of their variants. WebP variants are offered through
+// a <picture> source when there is one for every width.
+func renderImage(w io.Writer, img *ast.Image, entering bool, resolved *media.Image) (ast.WalkStatus, bool) {
+	if !entering {
+		return ast.GoToNext, true
+	}
+
+	var srcset, webpSrcset []string
+	if resolved != nil {
+		for _, v := range resolved.Variants {
+			candidate := v.URL() + " " + strconv.Itoa(v.Width) + "w"
+			if v.ContentType == resolved.ContentType {
+				srcset = append(srcset, candidate)
+			} else if v.ContentType == "image/webp" {
+				webpSrcset = append(webpSrcset, candidate)
+			}
+		}
+		if len(srcset) > 0 {
+			srcset = append(srcset, resolved.URL()+" "+strconv.Itoa(resolved.Width)+"w")
+		}
+	}
+	usePicture := len(webpSrcset) > 0 && len(webpSrcset) == len(srcset)-1
+
+	if usePicture {
+		fmt.Fprintf(w, `<picture><source type="image/webp" srcset="%s" sizes="%s">`,
+			strings.Join(webpSrcset, ", "), imageSizes)
+	}
+
+	io.WriteString(w, `<img src="`)
+	html.EscLink(w, img.Destination)
+	io.WriteString(w, `" alt="`)
+	html.EscapeHTML(w, []byte(altText(img)))
+	io.WriteString(w, `"`)
+	if len(img.Title) > 0 {
+		io.WriteString(w, ` title="`)
+		html.EscapeHTML(w, img.Title)
+		io.WriteString(w, `"`)
+	}
+	if len(srcset) > 0 {
+		fmt.Fprintf(w, ` srcset="%s" sizes="%s"`, strings.Join(srcset, ", "), imageSizes)
+	}
+	if resolved != nil && resolved.Width > 0 {
+		fmt.Fprintf(w, ` width="%d" height="%d"`, resolved.Width, resolved.Height)
+	}
+	io.WriteString(w, ` loading="lazy" decoding="async">`)
+
+	if usePicture {
+		io.WriteString(w, `</picture>`)
+	}
+	return ast.SkipChildren, true
+}
+
+func altText(img *ast.Image) string {
+	var b strings.Builder
+	ast.WalkFunc(img, func(node ast.Node, entering bool) ast.WalkStatus {
+		if leaf := node.AsLeaf(); entering && leaf != nil {
+			b.Write(leaf.Literal)
+		}
+		return ast.GoToNext
+	})
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package components_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jannawro/blog/components"
+	"github.com/jannawro/blog/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubImages map[string]*media.Image
+
+func (s stubImages) Image(_ context.Context, src string) (*media.Image, error) {
+	if img, ok := s[src]; ok {
+		return img, nil
+	}
+	return nil, media.ErrMediaNotFound
+}
+
+var (
+	originalHash  = strings.Repeat("a", 64)
+	smallHash     = strings.Repeat("b", 64)
+	smallWebPHash = strings.Repeat("c", 64)
+)
+
+func TestRenderContentResponsiveImages(t *testing.T) {
+	photo := &media.Image{
+		Media: media.Media{Hash: originalHash, Name: "photo.jpg", ContentType: "image/jpeg", Width: 1000, Height: 500},
+		Variants: []media.Variant{
+			{Hash: smallHash, Name: "photo-320w.jpg", ContentType: "image/jpeg", Width: 320, Height: 160},
+			{Hash: smallWebPHash, Name: "photo-320w.webp", ContentType: "image/webp", Width: 320, Height: 160},
+		},
+	}
+	images := stubImages{photo.URL(): photo}
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), images, nil, nil)
+
+	html, err := renderer.RenderContent(context.Background(), "![A photo]("+photo.URL()+` "Title")`, nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, html, `<picture><source type="image/webp" srcset="/media/`+smallWebPHash+`/photo-320w.webp 320w"`)
+	assert.Contains(t, html, `src="/media/`+originalHash+`/photo.jpg"`)
+	assert.Contains(t, html, `alt="A photo"`)
+	assert.Contains(t, html, `title="Title"`)
+	assert.Contains(t, html, `srcset="/media/`+smallHash+`/photo-320w.jpg 320w, /media/`+originalHash+`/photo.jpg 1000w"`)
+	assert.Contains(t, html, `sizes="(min-width: 800px) 770px, 100vw"`)
+	assert.Contains(t, html, `width="1000" height="500"`)
+	assert.Contains(t, html, `loading="lazy"`)
+	assert.Contains(t, html, `</picture>`)
+}
+
+func TestRenderContentOtherImages(t *testing.T) {
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), stubImages{}, nil, nil)
+
+	html, err := renderer.RenderContent(context.Background(),
+		"![external](https://example.com/photo.jpg)\n\n![missing](/media/"+originalHash+"/missing.jpg)", nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, html, `<img src="https://example.com/photo.jpg" alt="external" loading="lazy" decoding="async">`)
+	assert.Contains(t, html, `<img src="/media/`+originalHash+`/missing.jpg" alt="missing" loading="lazy" decoding="async">`)
+	assert.NotContains(t, html, "srcset")
+	assert.NotContains(t, html, "<picture>")
+}
+
+func TestSanitizerRestrictsSrcset(t *testing.T) {
+	sanitizer := components.NewSanitizer(components.DefaultSanitizerConfig())
+
+	html := sanitizer.Sanitize(`<img src="/x.jpg" srcset="https://evil.example/x.jpg 100w" sizes="100vw">`)
+	assert.NotContains(t, html, "evil.example")
+
+	allowed := `srcset="/media/` + smallHash + `/photo-320w.jpg 320w"`
+	assert.Contains(t, sanitizer.Sanitize(`<img src="/x.jpg" `+allowed+`>`), allowed)
+}
@@ -0,0 +1,64 @@
+package components
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+
+	"github.com/gomarkdown/markdown/ast"
+)
+
+// maxIncludes limits the number of files a single document can include, so files including
+// each other can't make the parser read the same files over and over.
+const maxIncludes = 64
+
+// includer reads the files included with Mmark's {{file}} syntax from root. Paths are relative to
+// the including file and can't leave root. Line addresses are ignored, files are included whole.
+type includer struct {
+	root     fs.FS
+	count    int
+	problems []string
+}
+
+func (i *includer) read(from, file string, _ []byte) []byte {
+	name := path.Join(path.Dir(from), file)
+	if !fs.ValidPath(name) {
+		i.problems = append(i.problems, fmt.Sprintf("{{%s}}: outside of the include directory", file))
+		return nil
+	}
+
+	i.count++
+	if i.count > maxIncludes {
+		i.problems = append(i.problems, fmt.Sprintf("{{%s}}: more than %d includes", file, maxIncludes))
+		return nil
+	}
+
+	data, err := fs.ReadFile(i.root, name)
+	if err != nil {
+		i.problems = append(i.problems, fmt.Sprintf("{{%s}}: can't be included", file))
+		return nil
+	}
+	return data
+}
+
+// limitNesting removes the lists and block quotes nested deeper than maxDepth from node.
+// It returns whether anything was removed.
+func limitNesting(node ast.Node, depth, maxDepth int) bool {
+	removed := false
+	for _, child := range slices.Clone(node.GetChildren()) {
+		childDepth := depth
+		switch child.(type) {
+		case *ast.List, *ast.BlockQuote, *ast.Aside:
+			childDepth++
+		}
+
+		if childDepth > maxDepth {
+			ast.RemoveFromTree(child)
+			removed = true
+		} else if limitNesting(child, childDepth, maxDepth) {
+			removed = true
+		}
+	}
+	return removed
+}
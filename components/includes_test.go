@@ -0,0 +1,90 @@
+package components_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gomarkdown/markdown/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
+)
+
+func newOptionsRenderer(options components.RendererOptions) *components.Renderer {
+	return components.NewRenderer(options, components.NewSanitizer(components.DefaultSanitizerConfig()), nil, nil, nil)
+}
+
+func TestIncludesDisabledByDefault(t *testing.T) {
+	options := components.DefaultRendererOptions()
+	options.Extensions |= parser.Includes
+
+	html, err := newOptionsRenderer(options).RenderContent(context.Background(), "{{/etc/passwd}}", nil)
+	require.NoError(t, err)
+	assert.NotContains(t, html, "root:")
+}
+
+func TestIncludes(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "snippet.md"), []byte("Included *text*"), 0o644))
+
+	options := components.DefaultRendererOptions()
+	options.Extensions |= parser.Includes
+	options.IncludeDir = dir
+	renderer := newOptionsRenderer(options)
+	ctx := context.Background()
+
+	t.Run("file in the include directory", func(t *testing.T) {
+		html, err := renderer.RenderContent(ctx, "{{snippet.md}}", nil)
+		require.NoError(t, err)
+		assert.Contains(t, html, "Included <em>text</em>")
+	})
+
+	t.Run("file outside of the include directory", func(t *testing.T) {
+		_, err := renderer.RenderContent(ctx, "{{../secret.md}}", nil)
+		var validationErr *article.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []string{"{{../secret.md}}: outside of the include directory"}, validationErr.Problems)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := renderer.RenderContent(ctx, "{{missing.md}}", nil)
+		var validationErr *article.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []string{"{{missing.md}}: can't be included"}, validationErr.Problems)
+	})
+}
+
+func TestMaxDocumentSize(t *testing.T) {
+	options := components.DefaultRendererOptions()
+	options.MaxDocumentSize = 10
+
+	html, err := newOptionsRenderer(options).RenderContent(context.Background(), strings.Repeat("a", 11), nil)
+	var validationErr *article.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{"markdown is 11 bytes long, the limit is 10"}, validationErr.Problems)
+	assert.Empty(t, html)
+}
+
+func TestMaxNestingDepth(t *testing.T) {
+	options := components.DefaultRendererOptions()
+	options.MaxNestingDepth = 2
+	renderer := newOptionsRenderer(options)
+	ctx := context.Background()
+
+	html, err := renderer.RenderContent(ctx, "- one\n  - two", nil)
+	require.NoError(t, err)
+	assert.Contains(t, html, "two")
+
+	html, err = renderer.RenderContent(ctx, "Before\n\n> - one\n>   - two\n\nAfter", nil)
+	var validationErr *article.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{"lists and quotes can't be nested more than 2 levels deep"}, validationErr.Problems)
+	assert.Contains(t, html, "one")
+	assert.NotContains(t, html, "two")
+	assert.Contains(t, html, "After")
+}
@@ -0,0 +1,171 @@
+package components
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+
+	"github.com/jannawro/blog/media"
+)
+
+// Heading is a section heading of a rendered markdown document
+type Heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// Document is rendered markdown together with the headings it contains, in document order
+type Document struct {
+	HTML     string
+	Headings []Heading
+}
+
+// documentOptions is what renderDocument needs to know about a document besides the document itself
+type documentOptions struct {
+	// images are the uploaded images in the document by src. They are rendered with their responsive variants.
+	images map[string]*media.Image
+	// citations are the numbers of the references of the document by key
+	citations map[string]int
+	// sidenotes renders footnotes next to the text referencing them instead of at the end
+	sidenotes bool
+}
+
+// renderDocument renders a parsed document to HTML. Every heading gets a deterministic, unique slug ID
+// and a "#" permalink pointing at it. The same IDs are returned in Document.Headings so they
+// can be used to build a table of contents. Images are loaded lazily.
+func renderDocument(doc ast.Node, options documentOptions) Document {
+	headings := assignHeadingIDs(doc)
+
+	var renderer *html.Renderer
+	sidenotes := 0
+	renderer = html.NewRenderer(html.RendererOptions{
+		Flags: html.CommonFlags,
+		RenderNodeHook: func(w io.Writer, node ast.Node, entering bool) (ast.WalkStatus, bool) {
+			switch node := node.(type) {
+			case *ast.Heading:
+				return renderHeadingWithAnchor(w, node, entering)
+			case *ast.Image:
+				return renderImage(w, node, entering, options.images[string(node.Destination)])
+			case *ast.Citation:
+				return renderCitation(w, node, options.citations)
+			case *ast.Link:
+				if node.NoteID != 0 && options.sidenotes {
+					if entering {
+						sidenotes++
+					}
+					return renderSidenote(w, node, entering, "sn:"+strconv.Itoa(sidenotes), renderer)
+				}
+			case *ast.List:
+				// Every footnote has been rendered as a sidenote already
+				if node.IsFootnotesList && options.sidenotes {
+					return ast.SkipChildren, true
+				}
+			}
+			return ast.GoToNext, false
+		},
+	})
+
+	return Document{
+		HTML:     string(markdown.Render(doc, renderer)),
+		Headings: headings,
+	}
+}
+
+// assignHeadingIDs sets a unique ID on every heading in doc. Explicit IDs ({#id}) are kept as the
+// base for the slug, otherwise the heading text is used. Duplicates get a numeric suffix.
+func assignHeadingIDs(doc ast.Node) []Heading {
+	var headings []Heading
+	seen := make(map[string]bool)
+
+	ast.WalkFunc(doc, func(node ast.Node, entering bool) ast.WalkStatus {
+		hdr, ok := node.(*ast.Heading)
+		if !ok || !entering || hdr.IsTitleblock {
+			return ast.GoToNext
+		}
+
+		text := headingText(hdr)
+		base := SlugifyHeading(hdr.HeadingID)
+		if base == "" {
+			base = SlugifyHeading(text)
+		}
+		if base == "" {
+			base = "section"
+		}
+
+		id := base
+		for i := 1; seen[id]; i++ {
+			id = fmt.Sprintf("%s-%d", base, i)
+		}
+		seen[id] = true
+		hdr.HeadingID = id
+
+		headings = append(headings, Heading{
+			Level: hdr.Level,
+			ID:    id,
+			Text:  text,
+		})
+		return ast.SkipChildren
+	})
+
+	return headings
+}
+
+func headingText(hdr *ast.Heading) string {
+	var b strings.Builder
+	ast.WalkFunc(hdr, func(node ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.GoToNext
+		}
+		switch n := node.(type) {
+		case *ast.Text:
+			b.Write(n.Literal)
+		case *ast.Code:
+			b.Write(n.Literal)
+		}
+		return ast.GoToNext
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// SlugifyHeading turns heading text into an URL fragment: lowercase letters and digits
+// separated by single dashes.
+func SlugifyHeading(text string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if pendingDash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			pendingDash = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			pendingDash = true
+		}
+	}
+	return b.String()
+}
+
+func renderHeadingWithAnchor(w io.Writer, hdr *ast.Heading, entering bool) (ast.WalkStatus, bool) {
+	if hdr.IsTitleblock {
+		return ast.GoToNext, false
+	}
+
+	if entering {
+		fmt.Fprintf(w, "\n<h%d id=\"%s\" class=\"heading-with-anchor\">", hdr.Level, hdr.HeadingID)
+	} else {
+		fmt.Fprintf(w,
+			"<a class=\"heading-anchor\" href=\"#%s\" aria-label=\"Link to this section\">#</a></h%d>\n",
+			hdr.HeadingID, hdr.Level,
+		)
+	}
+	return ast.GoToNext, true
+}
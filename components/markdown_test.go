@@ -0,0 +1,77 @@
+package components_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jannawro/blog/components"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderContentHeadingIDs(t *testing.T) {
+	md := `# Getting Started
+Intro.
+
+## Install the *CLI* tool
+Steps.
+
+## Getting started
+Again.
+
+## Getting Started
+And again.
+
+### ` + "`go test`" + ` & friends {#Custom_ID}
+Done.`
+
+	html, err := newOptionsRenderer(components.DefaultRendererOptions()).RenderContent(context.Background(), md, nil)
+	require.NoError(t, err)
+
+	for _, id := range []string{
+		"getting-started",
+		"install-the-cli-tool",
+		"getting-started-1",
+		"getting-started-2",
+		"custom-id",
+	} {
+		assert.Contains(t, html, `id="`+id+`"`)
+		// The permalink of the heading and its entry in the table of contents
+		assert.Equal(t, 2, strings.Count(html, `href="#`+id+`"`), id)
+	}
+	assert.Contains(t, html, "Install the CLI tool")
+	assert.Contains(t, html, "go test &amp; friends")
+}
+
+func TestRenderContentIsDeterministic(t *testing.T) {
+	md := "## Same\n\n## Same\n\n## Other"
+	renderer := newOptionsRenderer(components.DefaultRendererOptions())
+
+	first, err := renderer.RenderContent(context.Background(), md, nil)
+	require.NoError(t, err)
+	second, err := renderer.RenderContent(context.Background(), md, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+}
+
+func TestSlugifyHeading(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Hello World", "hello-world"},
+		{"  Leading and trailing  ", "leading-and-trailing"},
+		{"What's new in Go 1.23?", "whats-new-in-go-123"},
+		{"snake_case and kebab-case", "snake-case-and-kebab-case"},
+		{"Zażółć gęślą jaźń", "zażółć-gęślą-jaźń"},
+		{"!!!", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, components.SlugifyHeading(tt.text))
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package components
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+
+	"github.com/jannawro/blog/article"
+)
+
+// Renderer renders article markdown into the HTML stored with every article.
+// It implements article.Renderer.
+type Renderer struct {
+	options    RendererOptions
+	includes   fs.FS
+	sanitizer  *Sanitizer
+	images     ImageResolver
+	shortcodes Shortcodes
+	articles   ArticleResolver
+}
+
+// NewRenderer returns a Renderer parsing markdown as configured by options. Uploaded images are
+// rendered with their responsive variants looked up through images, which may be nil. Articles can
+// use the given shortcodes and link to the articles found through articles.
+func NewRenderer(
+	options RendererOptions,
+	sanitizer *Sanitizer,
+	images ImageResolver,
+	shortcodes Shortcodes,
+	articles ArticleResolver,
+) *Renderer {
+	r := &Renderer{
+		options:    options,
+		sanitizer:  sanitizer,
+		images:     images,
+		shortcodes: shortcodes,
+		articles:   articles,
+	}
+	if options.IncludeDir != "" {
+		r.includes = os.DirFS(options.IncludeDir)
+	}
+	return r
+}
+
+// RenderContent renders and sanitizes the body of an article. When the article has more than one
+// heading, a table of contents is rendered in front of it. References are listed in a bibliography
+// at the end and can be cited with [@key]. Invalid shortcodes and citations of missing references are reported
+// as an *article.ValidationError together with the rest of the rendered HTML. Links to missing articles are
+// reported as its warnings, any other error looking up a linked article fails the rendering.
+func (r *Renderer) RenderContent(ctx context.Context, md string, references []article.Reference) (string, error) {
+	shortcodes := &shortcodeParser{ctx: ctx, shortcodes: r.shortcodes}
+	links := &wikiLinker{ctx: ctx, articles: r.articles}
+
+	parsed, problems := r.parse(md, func(p *parser.Parser) {
+		p.Opts.ParserHook = shortcodes.parse
+		links.register(p)
+	})
+	if parsed == nil {
+		return "", &article.ValidationError{Problems: problems}
+	}
+	if links.err != nil {
+		return "", links.err
+	}
+
+	images, err := resolveImages(ctx, r.images, parsed)
+	if err != nil {
+		return "", err
+	}
+
+	citations := numberReferences(references)
+	problems = append(problems, checkCitations(parsed, citations)...)
+
+	doc := renderDocument(parsed, documentOptions{
+		images:    images,
+		citations: citations,
+		sidenotes: r.options.Sidenotes,
+	})
+	if len(references) > 0 {
+		var buf bytes.Buffer
+		buf.WriteString(doc.HTML)
+		if err := Bibliography(bibliographyEntries(references)).Render(ctx, &buf); err != nil {
+			return "", err
+		}
+		doc.HTML = buf.String()
+	}
+
+	body := r.sanitizer.Sanitize(doc.HTML)
+	if len(doc.Headings) > 1 {
+		var buf bytes.Buffer
+		if err := TableOfContents(doc.Headings).Render(ctx, &buf); err != nil {
+			return "", err
+		}
+		buf.WriteString(body)
+		body = buf.String()
+	}
+
+	problems = append(problems, shortcodes.problems...)
+	if len(problems) > 0 || len(links.problems) > 0 {
+		return body, &article.ValidationError{Problems: problems, Warnings: links.problems}
+	}
+	return body, nil
+}
+
+// LinkTargets returns the slugs of the articles linked to with wiki-links in md
+func (r *Renderer) LinkTargets(md string) []string {
+	links := &wikiLinker{}
+	r.parse(md, links.register)
+	return links.targets
+}
+
+// RenderThumbnail renders and sanitizes the short excerpt shown on article cards.
+func (r *Renderer) RenderThumbnail(_ context.Context, md string) (string, error) {
+	parsed, problems := r.parse(md, nil)
+	if parsed == nil {
+		return "", &article.ValidationError{Problems: problems}
+	}
+
+	rendered := markdown.Render(parsed, html.NewRenderer(html.RendererOptions{Flags: html.CommonFlags}))
+	thumbnail := r.sanitizer.Sanitize(string(rendered))
+	if len(problems) > 0 {
+		return thumbnail, &article.ValidationError{Problems: problems}
+	}
+	return thumbnail, nil
+}
+
+// parse parses md within the limits set by the options. configure, if given, is called with the parser
+// before parsing starts. The returned document is nil when md is too large to be parsed at all.
+func (r *Renderer) parse(md string, configure func(p *parser.Parser)) (ast.Node, []string) {
+	if r.options.MaxDocumentSize > 0 && len(md) > r.options.MaxDocumentSize {
+		return nil, []string{fmt.Sprintf("markdown is %d bytes long, the limit is %d", len(md), r.options.MaxDocumentSize)}
+	}
+
+	extensions := r.options.Extensions
+	if r.includes == nil {
+		extensions &^= parser.Includes
+	}
+	p := parser.NewWithExtensions(extensions)
+	includes := &includer{root: r.includes}
+	if r.includes != nil {
+		p.Opts.ReadIncludeFn = includes.read
+	}
+	if configure != nil {
+		configure(p)
+	}
+	// The include parser reads past an include on the last line when it doesn't end in a newline
+	if !strings.HasSuffix(md, "\n") {
+		md += "\n"
+	}
+	doc := markdown.Parse([]byte(md), p)
+
+	problems := includes.problems
+	if r.options.MaxNestingDepth > 0 && limitNesting(doc, 0, r.options.MaxNestingDepth) {
+		problems = append(problems, fmt.Sprintf("lists and quotes can't be nested more than %d levels deep", r.options.MaxNestingDepth))
+	}
+	return doc, problems
+}
@@ -0,0 +1,156 @@
+package components
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+
+	"github.com/jannawro/blog/media"
+)
+
+// SanitizerConfig controls what HTML is allowed to survive in rendered articles
+// on top of what markdown itself produces.
+type SanitizerConfig struct {
+	// EmbedHosts are the hosts iframes are allowed to point at, e.g. "www.youtube-nocookie.com".
+	EmbedHosts []string
+	// AllowRawHTML lets through the harmless parts of raw HTML written in markdown (details, figures,
+	// definitions...). Scripts, event handlers and inline styles are removed regardless.
+	AllowRawHTML bool
+}
+
+// DefaultSanitizerConfig allows the embeds used on the blog: YouTube videos, itch.io games and gists.
+func DefaultSanitizerConfig() SanitizerConfig {
+	return SanitizerConfig{
+		EmbedHosts: []string{
+			"www.youtube-nocookie.com",
+			"www.youtube.com",
+			"itch.io",
+			"gist.github.com",
+		},
+		AllowRawHTML: true,
+	}
+}
+
+// mediaCandidate matches a single srcset candidate pointing at an uploaded file, e.g. "/media/<hash>/photo-640w.jpg 640w"
+var mediaCandidate = regexp.QuoteMeta(media.PathPrefix) + `[0-9a-f]{64}/[a-zA-Z0-9._\-]+ [0-9]+w`
+
+var (
+	elementID   = regexp.MustCompile(`^[a-zA-Z0-9:\-_.]+$`)
+	mediaSrcset = regexp.MustCompile(`^` + mediaCandidate + `(, ` + mediaCandidate + `)*$`)
+	sizesValue  = regexp.MustCompile(`^[a-z0-9(): ,\-]+$`)
+	dimension   = regexp.MustCompile(`^[0-9]+(%|px)?$`)
+	embedPolicy = regexp.MustCompile(`^[a-zA-Z\-; ]*$`)
+)
+
+// rendererClasses are the classes the renderer puts on each element: heading anchors, footnotes,
+// sidenotes, the bibliography and the wrappers of shortcodes. Any other class, e.g. one written in raw
+// HTML, is removed so articles can't borrow the styling of the page around them.
+var rendererClasses = map[string][]string{
+	"h1":      {"heading-with-anchor"},
+	"h2":      {"heading-with-anchor"},
+	"h3":      {"heading-with-anchor"},
+	"h4":      {"heading-with-anchor"},
+	"h5":      {"heading-with-anchor"},
+	"h6":      {"heading-with-anchor"},
+	"a":       {"heading-anchor", "footnote-return"},
+	"sup":     {"footnote-ref"},
+	"div":     {"footnotes", "not-prose my-8", "not-prose my-8 aspect-video w-full"},
+	"label":   {"sidenote-number"},
+	"input":   {"sidenote-toggle"},
+	"span":    {"sidenote", "sidenote-number"},
+	"section": {"bibliography"},
+	"iframe":  {"h-full w-full", "w-full max-w-[552px]", "h-96 w-full border-2 border-[#1a1a1a] rounded-md"},
+}
+
+// codeLanguage is the class fenced code blocks get from their info string, e.g. "language-go"
+var codeLanguage = regexp.MustCompile(`^language-[a-zA-Z0-9_+\-]+$`)
+
+// Sanitizer removes everything from rendered HTML that is not explicitly allowed,
+// so that markdown can't be used to inject scripts into the blog.
+type Sanitizer struct {
+	policy *bluemonday.Policy
+}
+
+func NewSanitizer(config SanitizerConfig) *Sanitizer {
+	var p *bluemonday.Policy
+	if config.AllowRawHTML {
+		p = bluemonday.UGCPolicy()
+	} else {
+		p = markdownPolicy()
+	}
+
+	// Articles are written by the blog author, links to other sites don't need to be marked.
+	p.RequireNoFollowOnLinks(false)
+
+	// Heading anchors, footnotes and fenced code blocks rely on ids and classes
+	p.AllowAttrs("id").Matching(elementID).Globally()
+	for element, classes := range rendererClasses {
+		p.AllowAttrs("class").Matching(exactly(classes)).OnElements(element)
+	}
+	p.AllowAttrs("class").Matching(codeLanguage).OnElements("code")
+	p.AllowAttrs("aria-label").Matching(bluemonday.Paragraph).Globally()
+
+	// Sidenotes are toggled on narrow screens with a checkbox labelled with the note's number
+	p.AllowElements("cite", "section")
+	p.AllowAttrs("for").Matching(elementID).OnElements("label")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+
+	// Responsive images. Their srcset may only point at uploaded media.
+	p.AllowElements("picture")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^image/[a-z]+$`)).OnElements("source")
+	p.AllowAttrs("srcset").Matching(mediaSrcset).OnElements("img", "source")
+	p.AllowAttrs("sizes").Matching(sizesValue).OnElements("img", "source")
+	p.AllowAttrs("width", "height").Matching(bluemonday.Integer).OnElements("img")
+	p.AllowAttrs("loading").Matching(regexp.MustCompile(`^(lazy|eager)$`)).OnElements("img")
+	p.AllowAttrs("decoding").Matching(regexp.MustCompile(`^(async|sync|auto)$`)).OnElements("img")
+
+	if len(config.EmbedHosts) > 0 {
+		p.AllowAttrs("src").Matching(embedSource(config.EmbedHosts)).OnElements("iframe")
+		p.AllowAttrs("width", "height").Matching(dimension).OnElements("iframe")
+		p.AllowAttrs("title").Matching(bluemonday.Paragraph).OnElements("iframe")
+		p.AllowAttrs("allow").Matching(embedPolicy).OnElements("iframe")
+		p.AllowAttrs("loading").Matching(regexp.MustCompile(`^(lazy|eager)$`)).OnElements("iframe")
+		p.AllowAttrs("allowfullscreen", "frameborder").OnElements("iframe")
+	}
+
+	return &Sanitizer{policy: p}
+}
+
+// Sanitize returns html with all disallowed elements and attributes removed.
+func (s *Sanitizer) Sanitize(html string) string {
+	return s.policy.Sanitize(html)
+}
+
+// markdownPolicy allows only the elements produced by the markdown renderer.
+func markdownPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowStandardURLs()
+	p.AllowElements(
+		"h1", "h2", "h3", "h4", "h5", "h6", "p", "br", "hr", "div", "span", "blockquote",
+		"ul", "ol", "li", "dl", "dt", "dd", "pre", "code", "em", "strong", "del", "sup", "sub",
+		"table", "thead", "tbody", "tfoot", "tr", "th", "td", "figure", "figcaption", "aside",
+	)
+	p.AllowAttrs("href", "title").OnElements("a")
+	p.AllowAttrs("src", "alt", "title").OnElements("img")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|right|center)$`)).OnElements("th", "td")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	return p
+}
+
+// exactly matches one of values and nothing else
+func exactly(values []string) *regexp.Regexp {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = regexp.QuoteMeta(value)
+	}
+	return regexp.MustCompile(`^(` + strings.Join(quoted, "|") + `)$`)
+}
+
+func embedSource(hosts []string) *regexp.Regexp {
+	quoted := make([]string, len(hosts))
+	for i, host := range hosts {
+		quoted[i] = regexp.QuoteMeta(host)
+	}
+	return regexp.MustCompile(`^https://(` + strings.Join(quoted, "|") + `)/`)
+}
@@ -0,0 +1,149 @@
+package components_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRendererRemovesXSSPayloads(t *testing.T) {
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, components.DefaultShortcodes(), nil)
+	ctx := context.Background()
+
+	payloads := []struct {
+		name     string
+		markdown string
+	}{
+		{"script tag", `<script>alert(1)</script>`},
+		{"script tag inside paragraph", `Hello <script>alert(1)</script> world`},
+		{"image onerror", `<img src=x onerror=alert(1)>`},
+		{"svg onload", `<svg onload=alert(1)><circle r="1"/></svg>`},
+		{"javascript link in markdown", `[click me](javascript:alert(1))`},
+		{"javascript link in html", `<a href="javascript:alert(1)">click me</a>`},
+		{"encoded javascript link", `<a href="jav&#x09;ascript:alert(1)">click me</a>`},
+		{"data uri link", `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`},
+		{"iframe from unknown host", `<iframe src="https://evil.example/"></iframe>`},
+		{"iframe with javascript", `<iframe src="javascript:alert(1)"></iframe>`},
+		{"iframe srcdoc", `<iframe srcdoc="<script>alert(1)</script>"></iframe>`},
+		{"inline style", `<div style="background:url(javascript:alert(1))">x</div>`},
+		{"style tag", `<style>body{background:url("javascript:alert(1)")}</style>`},
+		{"object embed", `<object data="https://evil.example/x.swf"></object>`},
+		{"form", `<form action="https://evil.example"><input name="password"></form>`},
+		{"meta refresh", `<meta http-equiv="refresh" content="0;url=https://evil.example">`},
+		{"event handler on allowed element", `<p onclick="alert(1)">x</p>`},
+		{"broken markup", `<img src="x" alt="<script>alert(1)</script>" onerror="alert(1)"`},
+	}
+
+	forbidden := []string{
+		"<script", "onerror", "onload", "onclick", "javascript:", "data:text", "evil.example",
+		"<style", "style=", "<object", "<form", "<input", "<meta", "srcdoc",
+	}
+
+	for _, tt := range payloads {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := renderer.RenderContent(ctx, tt.markdown, nil)
+			require.NoError(t, err)
+			thumbnail, err := renderer.RenderThumbnail(ctx, tt.markdown)
+			require.NoError(t, err)
+
+			for _, html := range []string{content, thumbnail} {
+				lower := strings.ToLower(html)
+				for _, f := range forbidden {
+					assert.NotContains(t, lower, f)
+				}
+			}
+		})
+	}
+}
+
+func TestRendererKeepsAllowedContent(t *testing.T) {
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, components.DefaultShortcodes(), nil)
+	ctx := context.Background()
+
+	md := "## First\n\n" +
+		"```go\nfmt.Println(\"hi\")\n```\n\n" +
+		"## Second\n\n" +
+		`<iframe src="https://www.youtube-nocookie.com/embed/abc123" width="560" height="315" ` +
+		`allow="autoplay; encrypted-media" allowfullscreen></iframe>` + "\n\n" +
+		"A footnote[^1] and a [link](https://example.com).\n\n" +
+		"[^1]: The note."
+
+	html, err := renderer.RenderContent(ctx, md, nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, html, `id="first"`)
+	assert.Contains(t, html, `class="heading-anchor"`)
+	assert.Contains(t, html, `href="#second"`)
+	assert.Contains(t, html, `class="language-go"`)
+	assert.Contains(t, html, `<iframe src="https://www.youtube-nocookie.com/embed/abc123"`)
+	assert.Contains(t, html, `allowfullscreen`)
+	assert.Contains(t, html, `href="https://example.com"`)
+	assert.Contains(t, html, `Table of contents`)
+}
+
+func TestSanitizerEmbedHostsAreConfigurable(t *testing.T) {
+	iframe := `<iframe src="https://player.vimeo.com/video/1"></iframe>`
+
+	defaults := components.NewSanitizer(components.DefaultSanitizerConfig())
+	assert.NotContains(t, defaults.Sanitize(iframe), "vimeo")
+
+	custom := components.NewSanitizer(components.SanitizerConfig{EmbedHosts: []string{"player.vimeo.com"}})
+	assert.Contains(t, custom.Sanitize(iframe), `src="https://player.vimeo.com/video/1"`)
+	assert.NotContains(t, custom.Sanitize(`<iframe src="https://www.youtube.com/embed/x"></iframe>`), "youtube")
+}
+
+func TestSanitizerWithoutRawHTML(t *testing.T) {
+	config := components.DefaultSanitizerConfig()
+	config.AllowRawHTML = false
+	sanitizer := components.NewSanitizer(config)
+
+	html := sanitizer.Sanitize(`<details><summary>More</summary><p>Hidden <strong>text</strong></p></details>`)
+
+	assert.NotContains(t, html, "<details>")
+	assert.Contains(t, html, "<p>Hidden <strong>text</strong></p>")
+}
+
+func TestSanitizerRestrictsClasses(t *testing.T) {
+	sanitizer := components.NewSanitizer(components.DefaultSanitizerConfig())
+
+	kept := []string{
+		`<h2 id="a" class="heading-with-anchor">A</h2>`,
+		`<pre><code class="language-go">x</code></pre>`,
+		`<sup class="footnote-ref" id="fnref:1"><a href="#fn:1">1</a></sup>`,
+		`<section class="bibliography"><h2>References</h2></section>`,
+	}
+	for _, html := range kept {
+		assert.Equal(t, html, sanitizer.Sanitize(html))
+	}
+
+	injected := []string{
+		`<div class="fixed inset-0 z-50 bg-[#FF0000]">x</div>`,
+		`<p class="heading-with-anchor">x</p>`,
+		`<code class="language-go hidden">x</code>`,
+		`<span class="sidenote hidden">x</span>`,
+		`<section class="min-h-screen">x</section>`,
+	}
+	for _, html := range injected {
+		assert.NotContains(t, sanitizer.Sanitize(html), "class=", html)
+	}
+}
+
+func TestRendererKeepsItsOwnClasses(t *testing.T) {
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, components.DefaultShortcodes(), nil)
+
+	md := "{{< youtube dQw4w9WgXcQ >}}\n\nA footnote[^1].\n\n[^1]: The note."
+	html, err := renderer.RenderContent(context.Background(), md, []article.Reference{{Key: "gopl", Text: "The book"}})
+	require.NoError(t, err)
+
+	assert.Contains(t, html, `class="not-prose my-8 aspect-video w-full"`)
+	assert.Contains(t, html, `class="h-full w-full"`)
+	assert.Contains(t, html, `class="sidenote"`)
+	assert.Contains(t, html, `class="sidenote-number"`)
+	assert.Contains(t, html, `class="sidenote-toggle"`)
+	assert.Contains(t, html, `class="bibliography"`)
+}
@@ -0,0 +1,220 @@
+package components
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/gomarkdown/markdown/ast"
+)
+
+// Shortcode renders a shortcode used in article markdown. Shortcodes are written on their own line:
+//
+//	{{< youtube dQw4w9WgXcQ >}}
+//	{{< figure src="/media/<hash>/cat.jpg" caption="The cat" >}}
+//
+// An error is reported to the author of the article, so it should say what is wrong with the arguments.
+type Shortcode func(args ShortcodeArgs) (templ.Component, error)
+
+// Shortcodes maps shortcode names to their implementations. New shortcodes are added by adding them to the map.
+type Shortcodes map[string]Shortcode
+
+// DefaultShortcodes are the shortcodes available in articles: YouTube videos, itch.io games, gists and figures.
+func DefaultShortcodes() Shortcodes {
+	return Shortcodes{
+		"youtube": youtubeShortcode,
+		"itch":    itchShortcode,
+		"gist":    gistShortcode,
+		"figure":  figureShortcode,
+	}
+}
+
+// ShortcodeArgs are the arguments a shortcode was called with. Arguments are separated by spaces,
+// can be quoted when they contain spaces and can be given by name: {{< figure "/cat.jpg" caption="The cat" >}}
+type ShortcodeArgs struct {
+	Positional []string
+	Named      map[string]string
+}
+
+// Get returns the argument called name or, when it wasn't given by name, the positional argument at index.
+// An empty string is returned when the argument is missing.
+func (a ShortcodeArgs) Get(index int, name string) string {
+	if value, ok := a.Named[name]; ok {
+		return value
+	}
+	if index < len(a.Positional) {
+		return a.Positional[index]
+	}
+	return ""
+}
+
+var (
+	shortcodeName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	argumentName  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*=`)
+)
+
+// shortcodeParser expands shortcodes while markdown is parsed. It is used as a gomarkdown parser hook,
+// so shortcodes in code blocks are left alone. Problems are collected instead of stopping the parser.
+type shortcodeParser struct {
+	ctx        context.Context
+	shortcodes Shortcodes
+	problems   []string
+}
+
+func (p *shortcodeParser) parse(data []byte) (ast.Node, []byte, int) {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	consumed := len(line)
+	if consumed < len(data) {
+		consumed++
+	}
+
+	trimmed := strings.TrimSpace(string(line))
+	indent := len(line) - len(bytes.TrimLeft(line, " "))
+	if indent > 3 || !strings.HasPrefix(trimmed, "{{<") {
+		return nil, nil, 0
+	}
+
+	html, err := p.render(trimmed)
+	if err != nil {
+		p.problems = append(p.problems, fmt.Sprintf("%s: %v", trimmed, err))
+		return nil, nil, consumed
+	}
+	return &ast.HTMLBlock{Leaf: ast.Leaf{Literal: []byte(html)}}, nil, consumed
+}
+
+func (p *shortcodeParser) render(raw string) (string, error) {
+	inner, ok := strings.CutSuffix(strings.TrimPrefix(raw, "{{<"), ">}}")
+	if !ok {
+		return "", errors.New("shortcode has to end with >}} on the same line")
+	}
+
+	fields, err := splitArguments(inner)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) == 0 || !shortcodeName.MatchString(fields[0]) {
+		return "", errors.New("missing shortcode name")
+	}
+
+	name := fields[0]
+	shortcode, ok := p.shortcodes[name]
+	if !ok {
+		return "", fmt.Errorf("unknown shortcode %q", name)
+	}
+
+	args := ShortcodeArgs{Named: make(map[string]string)}
+	for _, field := range fields[1:] {
+		if loc := argumentName.FindStringIndex(field); loc != nil {
+			args.Named[field[:loc[1]-1]] = field[loc[1]:]
+		} else {
+			args.Positional = append(args.Positional, field)
+		}
+	}
+
+	component, err := shortcode(args)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := component.Render(p.ctx, &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// splitArguments splits s on spaces. Double quoted parts are unquoted and kept together,
+// so both "a b" and caption="a b" are single arguments.
+func splitArguments(s string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	inField := false
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			end := i + 1
+			for ; end < len(s) && s[end] != '"'; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(s) {
+				return nil, errors.New("unterminated quoted argument")
+			}
+			unquoted, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted argument %s", s[i:end+1])
+			}
+			current.WriteString(unquoted)
+			inField = true
+			i = end
+		case c == ' ' || c == '\t':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteByte(c)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+var (
+	youtubeID = regexp.MustCompile(`^[a-zA-Z0-9_-]{11}$`)
+	itchID    = regexp.MustCompile(`^[0-9]+$`)
+	gistID    = regexp.MustCompile(`^[a-zA-Z0-9-]+/[0-9a-f]+$`)
+)
+
+// youtubeShortcode embeds a YouTube video: {{< youtube <video id> [title] >}}
+func youtubeShortcode(args ShortcodeArgs) (templ.Component, error) {
+	id := args.Get(0, "id")
+	if !youtubeID.MatchString(id) {
+		return nil, fmt.Errorf("expected a YouTube video id, got %q", id)
+	}
+	return YouTubeEmbed(id, args.Get(1, "title")), nil
+}
+
+// itchShortcode embeds an itch.io game widget: {{< itch <game id> >}}.
+// The game id is the number shown in the embed code on the game's itch.io page.
+func itchShortcode(args ShortcodeArgs) (templ.Component, error) {
+	id := args.Get(0, "id")
+	if !itchID.MatchString(id) {
+		return nil, fmt.Errorf("expected a numeric itch.io game id, got %q", id)
+	}
+	return ItchEmbed(id), nil
+}
+
+// gistShortcode embeds a GitHub gist: {{< gist <user>/<gist id> >}}
+func gistShortcode(args ShortcodeArgs) (templ.Component, error) {
+	id := args.Get(0, "id")
+	if !gistID.MatchString(id) {
+		return nil, fmt.Errorf("expected a gist as <user>/<id>, got %q", id)
+	}
+	return GistEmbed(id), nil
+}
+
+// figureShortcode renders an image with a caption: {{< figure <src> [caption] [alt] >}}
+func figureShortcode(args ShortcodeArgs) (templ.Component, error) {
+	src := args.Get(0, "src")
+	if src == "" {
+		return nil, errors.New("missing image src")
+	}
+	caption := args.Get(1, "caption")
+	alt := args.Get(2, "alt")
+	if alt == "" {
+		alt = caption
+	}
+	return Figure(src, caption, alt), nil
+}
@@ -0,0 +1,115 @@
+package components_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
+)
+
+func newShortcodeRenderer(shortcodes components.Shortcodes) *components.Renderer {
+	return components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, shortcodes, nil)
+}
+
+func TestDefaultShortcodes(t *testing.T) {
+	renderer := newShortcodeRenderer(components.DefaultShortcodes())
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		markdown string
+		expected []string
+	}{
+		{
+			"youtube",
+			`{{< youtube dQw4w9WgXcQ "Never gonna" >}}`,
+			[]string{`<iframe src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ"`, `title="Never gonna"`, `allowfullscreen`},
+		},
+		{
+			"itch",
+			`{{< itch 1234567 >}}`,
+			[]string{`<iframe src="https://itch.io/embed/1234567"`},
+		},
+		{
+			"gist",
+			`{{< gist jannawro/0123abcd >}}`,
+			[]string{`<iframe src="https://gist.github.com/jannawro/0123abcd.pibb"`},
+		},
+		{
+			"figure with named arguments",
+			`{{< figure src="/cat.jpg" caption="The cat, asleep" >}}`,
+			[]string{`<figure>`, `<img src="/cat.jpg" alt="The cat, asleep"`, `<figcaption>The cat, asleep</figcaption>`},
+		},
+		{
+			"figure with positional arguments",
+			`{{< figure /cat.jpg "The cat" "A grey cat" >}}`,
+			[]string{`<img src="/cat.jpg" alt="A grey cat"`, `<figcaption>The cat</figcaption>`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := renderer.RenderContent(ctx, "Before\n\n"+tt.markdown+"\n\nAfter", nil)
+			require.NoError(t, err)
+
+			assert.Contains(t, html, "<p>Before</p>")
+			assert.Contains(t, html, "<p>After</p>")
+			assert.NotContains(t, html, "{{&lt;")
+			for _, expected := range tt.expected {
+				assert.Contains(t, html, expected)
+			}
+		})
+	}
+}
+
+func TestShortcodesInCodeAreLeftAlone(t *testing.T) {
+	renderer := newShortcodeRenderer(components.DefaultShortcodes())
+
+	html, err := renderer.RenderContent(context.Background(), "```\n{{< youtube dQw4w9WgXcQ >}}\n```\n\n    {{< unknown >}}", nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, html, "{{&lt; youtube dQw4w9WgXcQ &gt;}}")
+	assert.Contains(t, html, "{{&lt; unknown &gt;}}")
+	assert.NotContains(t, html, "<iframe")
+}
+
+func TestInvalidShortcodesAreReported(t *testing.T) {
+	renderer := newShortcodeRenderer(components.DefaultShortcodes())
+
+	_, err := renderer.RenderContent(context.Background(),
+		"{{< youtub dQw4w9WgXcQ >}}\n\n{{< youtube not-an-id >}}\n\n{{< figure >}}\n\n{{< gist \"unterminated >}}\n\n{{< itch 1", nil)
+
+	var validationErr *article.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Problems, 5)
+	assert.Contains(t, validationErr.Problems[0], `unknown shortcode "youtub"`)
+	assert.Contains(t, validationErr.Problems[1], "expected a YouTube video id")
+	assert.Contains(t, validationErr.Problems[2], "missing image src")
+	assert.Contains(t, validationErr.Problems[3], "unterminated quoted argument")
+	assert.Contains(t, validationErr.Problems[4], ">}}")
+}
+
+func TestCustomShortcodes(t *testing.T) {
+	shortcodes := components.DefaultShortcodes()
+	shortcodes["shout"] = func(args components.ShortcodeArgs) (templ.Component, error) {
+		text := args.Get(0, "text")
+		if text == "" {
+			return nil, errors.New("nothing to shout")
+		}
+		return templ.Raw("<p><strong>" + text + "!</strong></p>"), nil
+	}
+	renderer := newShortcodeRenderer(shortcodes)
+
+	html, err := renderer.RenderContent(context.Background(), `{{< shout text="hello there" >}}`, nil)
+	require.NoError(t, err)
+	assert.Contains(t, html, "<p><strong>hello there!</strong></p>")
+
+	_, err = renderer.RenderContent(context.Background(), `{{< shout >}}`, nil)
+	assert.ErrorContains(t, err, "nothing to shout")
+}
@@ -0,0 +1,57 @@
+package components
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+)
+
+// renderSidenote renders a footnote reference as a sidenote: the number of the footnote followed by
+// its text, which is moved into the margin on wide screens. On narrow screens the text is hidden
+// until the number is tapped. A checkbox keeps track of that, so no JavaScript is needed.
+func renderSidenote(w io.Writer, link *ast.Link, entering bool, id string, renderer *html.Renderer) (ast.WalkStatus, bool) {
+	if !entering {
+		return ast.GoToNext, true
+	}
+
+	number := strconv.Itoa(link.NoteID)
+	fmt.Fprintf(w, `<label for="%s" class="sidenote-number">%s</label>`, id, number)
+	fmt.Fprintf(w, `<input type="checkbox" id="%s" class="sidenote-toggle">`, id)
+	fmt.Fprintf(w, `<span class="sidenote"><span class="sidenote-number">%s</span> `, number)
+	if link.Footnote != nil {
+		renderInline(w, link.Footnote, renderer)
+	}
+	io.WriteString(w, `</span>`)
+	return ast.SkipChildren, true
+}
+
+// renderInline renders the children of node without the paragraphs around them, so they can be
+// placed in running text. Paragraphs are separated with line breaks.
+func renderInline(w io.Writer, node ast.Node, renderer *html.Renderer) {
+	render := func(node ast.Node) {
+		ast.WalkFunc(node, func(node ast.Node, entering bool) ast.WalkStatus {
+			return renderer.RenderNode(w, node, entering)
+		})
+	}
+
+	afterParagraph := false
+	for _, child := range node.GetChildren() {
+		paragraph, ok := child.(*ast.Paragraph)
+		if !ok {
+			render(child)
+			afterParagraph = false
+			continue
+		}
+
+		if afterParagraph {
+			io.WriteString(w, "<br>")
+		}
+		for _, inline := range paragraph.Children {
+			render(inline)
+		}
+		afterParagraph = true
+	}
+}
@@ -0,0 +1,62 @@
+package components_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
+)
+
+func TestSidenotes(t *testing.T) {
+	renderer := newOptionsRenderer(components.DefaultRendererOptions())
+
+	html, err := renderer.RenderContent(context.Background(), "A claim.[^1] Another.[^2]\n\n[^1]: The *proof*.\n\n    More proof.\n\n[^2]: Short.", nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, html, `<label for="sn:1" class="sidenote-number">1</label><input type="checkbox" id="sn:1" class="sidenote-toggle">`)
+	assert.Contains(t, html, `<span class="sidenote"><span class="sidenote-number">1</span> The <em>proof</em>.<br>More proof.</span>`)
+	assert.Contains(t, html, `<span class="sidenote"><span class="sidenote-number">2</span> Short.</span>`)
+	assert.NotContains(t, html, `class="footnotes"`)
+}
+
+func TestFootnotesWithoutSidenotes(t *testing.T) {
+	options := components.DefaultRendererOptions()
+	options.Sidenotes = false
+
+	html, err := newOptionsRenderer(options).RenderContent(context.Background(), "A claim.[^1]\n\n[^1]: The proof.", nil)
+	require.NoError(t, err)
+	assert.Contains(t, html, `class="footnotes"`)
+	assert.NotContains(t, html, `class="sidenote"`)
+}
+
+func TestCitations(t *testing.T) {
+	renderer := newOptionsRenderer(components.DefaultRendererOptions())
+	references := []article.Reference{
+		{Key: "knuth", Text: "Donald E. Knuth. *The Art of Computer Programming*."},
+		{Key: "dijkstra", Text: "Edsger W. Dijkstra. [Go To Statement Considered Harmful](https://example.com/goto)."},
+	}
+
+	html, err := renderer.RenderContent(context.Background(), "See [@dijkstra, p. 147] and [@knuth; @dijkstra].", references)
+	require.NoError(t, err)
+
+	assert.Contains(t, html, `<cite>[<a href="#ref:dijkstra">2</a>, p. 147]</cite>`)
+	assert.Contains(t, html, `<cite>[<a href="#ref:knuth">1</a>, <a href="#ref:dijkstra">2</a>]</cite>`)
+	assert.Contains(t, html, `<section class="bibliography"><h2>References</h2>`)
+	assert.Contains(t, html, `<li id="ref:knuth">Donald E. Knuth. <em>The Art of Computer Programming</em>.</li>`)
+	assert.Contains(t, html, `<li id="ref:dijkstra">Edsger W. Dijkstra. <a href="https://example.com/goto">Go To Statement Considered Harmful</a>.</li>`)
+}
+
+func TestCitationOfMissingReference(t *testing.T) {
+	renderer := newOptionsRenderer(components.DefaultRendererOptions())
+
+	html, err := renderer.RenderContent(context.Background(), "See [@missing].", nil)
+	var validationErr *article.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{`[@missing]: no reference with key "missing"`}, validationErr.Problems)
+	assert.Contains(t, html, "<cite>[@missing]</cite>")
+	assert.NotContains(t, html, "bibliography")
+}
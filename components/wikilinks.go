@@ -0,0 +1,90 @@
+package components
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/parser"
+
+	"github.com/jannawro/blog/article"
+)
+
+// ArticleResolver looks up the articles wiki-links point at. It is implemented by article.ArticleRepository.
+type ArticleResolver interface {
+	GetBySlug(ctx context.Context, slug string) (*article.Article, error)
+}
+
+// wikiLinker parses links to other articles written as [[slug]] or [[slug|text]]. Without
+// an explicit text the link shows the title of the linked article. Links are resolved while
+// parsing. Links to missing articles are rendered as plain text and collected as problems, the
+// first error looking up an article is kept in err.
+type wikiLinker struct {
+	ctx      context.Context
+	articles ArticleResolver
+	next     parser.InlineParser
+	targets  []string
+	problems []string
+	err      error
+}
+
+// register makes p parse wiki-links. Without articles the links are only collected as targets.
+func (l *wikiLinker) register(p *parser.Parser) {
+	l.next = p.RegisterInline('[', l.parse)
+}
+
+func (l *wikiLinker) parse(p *parser.Parser, data []byte, offset int) (int, ast.Node) {
+	rest := data[offset:]
+	if !bytes.HasPrefix(rest, []byte("[[")) {
+		return l.next(p, data, offset)
+	}
+	end := bytes.Index(rest, []byte("]]"))
+	if end < 0 || bytes.ContainsAny(rest[2:end], "\n[") {
+		return l.next(p, data, offset)
+	}
+
+	target, text, _ := strings.Cut(string(rest[2:end]), "|")
+	slug := strings.TrimSpace(target)
+	text = strings.TrimSpace(text)
+	if slug == "" {
+		return l.next(p, data, offset)
+	}
+	consumed := end + 2
+
+	if !slices.Contains(l.targets, slug) {
+		l.targets = append(l.targets, slug)
+	}
+	if l.articles == nil {
+		return consumed, textNode(text, slug)
+	}
+
+	linked, err := l.articles.GetBySlug(l.ctx, slug)
+	if err != nil && !errors.Is(err, article.ErrArticleNotFound) {
+		if l.err == nil {
+			l.err = err
+		}
+		return consumed, textNode(text, slug)
+	}
+	if linked == nil {
+		l.problems = append(l.problems, fmt.Sprintf("[[%s]]: no article with slug %q", slug, slug))
+		return consumed, textNode(text, slug)
+	}
+
+	if text == "" {
+		text = linked.Title
+	}
+	link := &ast.Link{Destination: []byte("/article/" + linked.Slug)}
+	ast.AppendChild(link, &ast.Text{Leaf: ast.Leaf{Literal: []byte(text)}})
+	return consumed, link
+}
+
+func textNode(text, fallback string) ast.Node {
+	if text == "" {
+		text = fallback
+	}
+	return &ast.Text{Leaf: ast.Leaf{Literal: []byte(text)}}
+}
@@ -0,0 +1,93 @@
+package components_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
+)
+
+type stubArticles map[string]article.Article
+
+func (s stubArticles) GetBySlug(_ context.Context, slug string) (*article.Article, error) {
+	if a, ok := s[slug]; ok {
+		return &a, nil
+	}
+	return nil, article.ErrArticleNotFound
+}
+
+type failingArticles struct{ err error }
+
+func (f failingArticles) GetBySlug(context.Context, string) (*article.Article, error) {
+	return nil, f.err
+}
+
+func newLinkRenderer() *components.Renderer {
+	articles := stubArticles{
+		"go-generics": {Title: "Go Generics", Slug: "go-generics"},
+	}
+	return components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, nil, articles)
+}
+
+func TestWikiLinks(t *testing.T) {
+	renderer := newLinkRenderer()
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		markdown string
+		expected string
+	}{
+		{"title of the linked article", "See [[go-generics]].", `See <a href="/article/go-generics">Go Generics</a>.`},
+		{"explicit text", "See [[go-generics|this post]].", `See <a href="/article/go-generics">this post</a>.`},
+		{"regular links are untouched", "See [Go](https://go.dev).", `<a href="https://go.dev"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := renderer.RenderContent(ctx, tt.markdown, nil)
+			require.NoError(t, err)
+			assert.Contains(t, html, tt.expected)
+		})
+	}
+}
+
+func TestWikiLinksInCode(t *testing.T) {
+	html, err := newLinkRenderer().RenderContent(context.Background(), "`[[missing]]`\n\n```\n[[missing]]\n```", nil)
+	require.NoError(t, err)
+	assert.NotContains(t, html, "<a ")
+	assert.Contains(t, html, "[[missing]]")
+}
+
+func TestUnresolvedWikiLinks(t *testing.T) {
+	html, err := newLinkRenderer().RenderContent(context.Background(), "See [[missing|the post]] and [[gone]].", nil)
+
+	var validationErr *article.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{
+		`[[missing]]: no article with slug "missing"`,
+		`[[gone]]: no article with slug "gone"`,
+	}, validationErr.Warnings)
+	assert.Empty(t, validationErr.Problems)
+	assert.Contains(t, html, "See the post and gone.")
+}
+
+func TestWikiLinksLookupFailure(t *testing.T) {
+	lookupErr := errors.New("connection refused")
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, nil, failingArticles{lookupErr})
+
+	_, err := renderer.RenderContent(context.Background(), "See [[go-generics]].", nil)
+	require.ErrorIs(t, err, lookupErr)
+	var validationErr *article.ValidationError
+	assert.False(t, errors.As(err, &validationErr), "a failed lookup isn't a problem with the markdown")
+}
+
+func TestLinkTargets(t *testing.T) {
+	targets := newLinkRenderer().LinkTargets("[[b]], [[a|A]], [[b]] and `[[code]]`")
+	assert.Equal(t, []string{"b", "a"}, targets)
+}
@@ -0,0 +1,8 @@
+package gitsync
+
+import "errors"
+
+var (
+	ErrGitFailed  = errors.New("running git failed")
+	ErrSyncFailed = errors.New("syncing articles failed")
+)
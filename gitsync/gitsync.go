@@ -0,0 +1,221 @@
+// Package gitsync keeps the stored articles in sync with the markdown files in a git repository, so the content of
+// record can live in git and be published by pushing.
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jannawro/blog/article"
+)
+
+type Options struct {
+	// URL is anything git clone takes, a remote URL or the path of a local repository
+	URL string
+	// Branch is checked out, the default branch of the remote if empty
+	Branch string
+	// Dir is where the repository is checked out. A temporary directory is used if it's empty, it is removed by
+	// Syncer.Close.
+	Dir string
+	// Path is the directory of the articles inside the repository, the root if it's empty
+	Path string
+	// Interval is how often Run syncs, zero syncs once
+	Interval time.Duration
+}
+
+// Report is the outcome of a sync
+type Report struct {
+	// Commit is the synced commit
+	Commit  string                 `json:"commit"`
+	Results []article.ImportResult `json:"results"`
+}
+
+// Syncer pulls the repository and applies its markdown files to the articles, one sync at a time.
+type Syncer struct {
+	service *article.Service
+	options Options
+
+	mutex sync.Mutex
+	dir   string
+	// temporary is set when dir was created by the syncer
+	temporary bool
+}
+
+func NewSyncer(service *article.Service, options Options) *Syncer {
+	return &Syncer{
+		service: service,
+		options: options,
+		dir:     options.Dir,
+	}
+}
+
+// Sync pulls the latest commit and creates, updates and deletes articles to match its markdown files, see
+// article.Service.Sync.
+func (s *Syncer) Sync(ctx context.Context) (Report, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	commit, err := s.pull(ctx)
+	if err != nil {
+		return Report{}, errors.Join(ErrSyncFailed, err)
+	}
+	files, err := readFiles(filepath.Join(s.dir, s.options.Path))
+	if err != nil {
+		return Report{}, errors.Join(ErrSyncFailed, err)
+	}
+	results, err := s.service.Sync(ctx, files)
+	if err != nil {
+		return Report{}, errors.Join(ErrSyncFailed, err)
+	}
+	return Report{Commit: commit, Results: results}, nil
+}
+
+// Close removes the checkout if it's in a temporary directory. A later sync clones the repository again.
+func (s *Syncer) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.temporary {
+		return nil
+	}
+	err := os.RemoveAll(s.dir)
+	s.dir, s.temporary = "", false
+	return err
+}
+
+// Run syncs right away and then at the configured interval until ctx is done, or only once if the interval is
+// zero. Failures are logged and retried at the next interval.
+func (s *Syncer) Run(ctx context.Context) {
+	s.logSync(ctx)
+	if s.options.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.logSync(ctx)
+		}
+	}
+}
+
+func (s *Syncer) logSync(ctx context.Context) {
+	report, err := s.Sync(ctx)
+	if err != nil {
+		slog.Error("Encountered an unexpected error when syncing articles", "url", s.options.URL, "error", err)
+		return
+	}
+
+	counts := make(map[article.ImportStatus]int)
+	for _, result := range report.Results {
+		counts[result.Status]++
+		if result.Status == article.ImportFailed {
+			slog.Warn("Syncing an article failed", "file", result.File, "slug", result.Slug, "error", result.Error)
+		}
+	}
+	slog.Info("Synced articles", "commit", report.Commit,
+		"created", counts[article.ImportCreated],
+		"updated", counts[article.ImportUpdated],
+		"deleted", counts[article.ImportDeleted],
+		"unchanged", counts[article.ImportUnchanged],
+		"failed", counts[article.ImportFailed],
+	)
+}
+
+// pull clones the repository on the first sync and fetches the latest commit afterwards, discarding anything
+// else in the checkout. It returns the checked out commit.
+func (s *Syncer) pull(ctx context.Context) (string, error) {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "blog-content-")
+		if err != nil {
+			return "", err
+		}
+		s.dir, s.temporary = dir, true
+	}
+
+	if _, err := os.Stat(filepath.Join(s.dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		args := []string{"clone", "--depth", "1"}
+		if s.options.Branch != "" {
+			args = append(args, "--branch", s.options.Branch)
+		}
+		if _, err := git(ctx, "", append(args, "--", s.options.URL, s.dir)...); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	} else {
+		ref := "HEAD"
+		if s.options.Branch != "" {
+			ref = s.options.Branch
+		}
+		if _, err := git(ctx, s.dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return "", err
+		}
+		if _, err := git(ctx, s.dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+
+	return git(ctx, s.dir, "rev-parse", "HEAD")
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Never wait for credentials on a terminal
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Join(ErrGitFailed,
+			fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String())))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// readFiles returns the contents of the markdown files in dir and its subdirectories, keyed by their paths
+// relative to dir. Hidden files and directories are skipped.
+func readFiles(dir string) (map[string][]byte, error) {
+	contents := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		contents[filepath.ToSlash(name)] = data
+		return nil
+	})
+	return contents, err
+}
@@ -0,0 +1,158 @@
+package gitsync_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
+	"github.com/jannawro/blog/gitsync"
+	"github.com/jannawro/blog/repository/mock"
+)
+
+// contentRepo is a bare repository the syncer pulls from and a clone of it the test pushes from
+type contentRepo struct {
+	t    *testing.T
+	bare string
+	work string
+}
+
+func newContentRepo(t *testing.T) *contentRepo {
+	r := &contentRepo{t: t, bare: t.TempDir(), work: t.TempDir()}
+	r.git("", "init", "--quiet", "--bare", "--initial-branch=main", r.bare)
+	r.git("", "init", "--quiet", "--initial-branch=main", r.work)
+	r.git(r.work, "remote", "add", "origin", r.bare)
+	return r
+}
+
+func (r *contentRepo) git(dir string, args ...string) {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(r.t, err, string(output))
+}
+
+func (r *contentRepo) write(name string, a article.Article) {
+	r.t.Helper()
+	path := filepath.Join(r.work, name)
+	require.NoError(r.t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(r.t, os.WriteFile(path, article.MarshalArticle(a), 0o644))
+}
+
+func (r *contentRepo) push(message string) {
+	r.t.Helper()
+	r.git(r.work, "add", "--all")
+	r.git(r.work, "commit", "--quiet", "--message", message)
+	r.git(r.work, "push", "--quiet", "origin", "main")
+}
+
+func newArticle(title, content string) article.Article {
+	return article.Article{
+		Title:           title,
+		Slug:            article.Slugify(title),
+		Content:         content,
+		Tags:            []string{"go"},
+		PublicationDate: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func setupTestService() (*article.Service, *mock.Repository) {
+	mockRepo := mock.NewRepository()
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, components.DefaultShortcodes(), mockRepo)
+	return article.NewService(mockRepo, renderer), mockRepo
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	content := newContentRepo(t)
+	content.write("articles/first.md", newArticle("First", "Links to [[second]]."))
+	content.write("articles/second.md", newArticle("Second", "The *second* one."))
+	content.write("README.md", newArticle("Outside the articles", "Not synced."))
+	content.push("Add articles")
+
+	service, mockRepo := setupTestService()
+	syncer := gitsync.NewSyncer(service, gitsync.Options{
+		URL:    content.bare,
+		Branch: "main",
+		Dir:    filepath.Join(t.TempDir(), "checkout"),
+		Path:   "articles",
+	})
+
+	report, err := syncer.Sync(ctx)
+	require.NoError(t, err)
+	assert.Len(t, report.Commit, 40)
+	assert.Equal(t, []article.ImportResult{
+		{File: "first.md", Slug: "first", Status: article.ImportCreated},
+		{File: "second.md", Slug: "second", Status: article.ImportCreated},
+	}, report.Results)
+	second, err := mockRepo.GetBySlug(ctx, "second")
+	require.NoError(t, err)
+	assert.Contains(t, second.ContentHTML, "<em>second</em>")
+
+	content.write("articles/second.md", newArticle("Second", "Rewritten."))
+	require.NoError(t, os.Remove(filepath.Join(content.work, "articles", "first.md")))
+	content.push("Rewrite the second article")
+
+	next, err := syncer.Sync(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, report.Commit, next.Commit)
+	assert.Equal(t, []article.ImportResult{
+		{File: "second.md", Slug: "second", Status: article.ImportUpdated},
+		{Slug: "first", Status: article.ImportDeleted},
+	}, next.Results)
+	second, err = mockRepo.GetBySlug(ctx, "second")
+	require.NoError(t, err)
+	assert.Equal(t, "Rewritten.", second.Content)
+	_, err = mockRepo.GetBySlug(ctx, "first")
+	assert.ErrorIs(t, err, article.ErrArticleNotFound)
+}
+
+func TestCloseRemovesTemporaryCheckout(t *testing.T) {
+	ctx := context.Background()
+	content := newContentRepo(t)
+	content.write("first.md", newArticle("First", "The first one."))
+	content.push("Add an article")
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	service, _ := setupTestService()
+	syncer := gitsync.NewSyncer(service, gitsync.Options{URL: content.bare})
+	for range 2 {
+		_, err := syncer.Sync(ctx)
+		require.NoError(t, err)
+	}
+	// Syncs share one checkout
+	checkouts, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Len(t, checkouts, 1)
+
+	require.NoError(t, syncer.Close())
+	checkouts, err = os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, checkouts)
+
+	// The repository is cloned again after closing
+	_, err = syncer.Sync(ctx)
+	require.NoError(t, err)
+	require.NoError(t, syncer.Close())
+}
+
+func TestSyncUnreachableRepository(t *testing.T) {
+	service, _ := setupTestService()
+	syncer := gitsync.NewSyncer(service, gitsync.Options{
+		URL: filepath.Join(t.TempDir(), "missing"),
+		Dir: filepath.Join(t.TempDir(), "checkout"),
+	})
+
+	_, err := syncer.Sync(context.Background())
+	assert.ErrorIs(t, err, gitsync.ErrSyncFailed)
+	assert.ErrorIs(t, err, gitsync.ErrGitFailed)
+}
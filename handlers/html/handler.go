@@ -4,7 +4,9 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	a "github.com/jannawro/blog/article"
 	"github.com/jannawro/blog/components"
@@ -49,8 +51,16 @@ func (h *Handler) ServeArticle(slugPathParam string) http.Handler {
 			return
 		}
 
+		slog.Debug("Fetching backlinks", "requestID", middleware.ReqIDFromCtx(r.Context()), "slug", slug)
+		backlinks, err := h.service.GetBacklinks(ctx, slug)
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()), "slug", slug)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		// Generate HTML using ArticlePage component
-		articlePage := components.ArticlePage(*article, h.assetsPath)
+		articlePage := components.ArticlePage(*article, backlinks, h.assetsPath)
 
 		// Render the HTML
 		err = articlePage.Render(ctx, w)
@@ -69,7 +79,7 @@ func (h *Handler) ServeBlog() http.Handler {
 		sortOption := a.GetSortOption(r)
 		tags := r.URL.Query()["tag"]
 
-		var articles a.Articles
+		var articles a.ArticleSummaries
 		var err error
 
 		if len(tags) > 0 {
@@ -77,10 +87,10 @@ func (h *Handler) ServeBlog() http.Handler {
 				"requestID", middleware.ReqIDFromCtx(r.Context()),
 				"sortOption", sortOption,
 			)
-			articles, err = h.service.GetByTags(ctx, tags, &sortOption)
+			articles, err = h.service.GetSummariesByTags(ctx, tags, &sortOption)
 		} else {
 			slog.Debug("Fetching all articles", "requestID", middleware.ReqIDFromCtx(r.Context()), "sortOption", sortOption)
-			articles, err = h.service.GetAll(ctx, &sortOption)
+			articles, err = h.service.GetAllSummaries(ctx, &sortOption)
 		}
 
 		if err != nil {
@@ -113,14 +123,14 @@ func (h *Handler) ServeIndex() http.Handler {
 		}
 
 		// Initialize map for tagged articles
-		taggedArticles := make(map[string][]a.Article)
+		taggedArticles := make(map[string][]a.ArticleSummary)
 
 		// Fetch articles for each tag
 		for _, tag := range tags {
 			slog.Debug("Fetching articles by tags: "+strings.Join(tags, ", "),
 				"requestID", middleware.ReqIDFromCtx(r.Context()),
 			)
-			articles, err := h.service.GetByTags(ctx, []string{tag}, nil)
+			articles, err := h.service.GetSummariesByTags(ctx, []string{tag}, nil)
 			if err != nil {
 				http.Error(w, "Failed to fetch articles for tag: "+tag, http.StatusInternalServerError)
 				return
@@ -137,3 +147,72 @@ func (h *Handler) ServeIndex() http.Handler {
 		}
 	})
 }
+
+func (h *Handler) ServeArchive() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		slog.Debug("Serving archive", "requestID", middleware.ReqIDFromCtx(r.Context()))
+
+		months, err := h.service.GetArchive(ctx)
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, "Failed to fetch archive", http.StatusInternalServerError)
+			return
+		}
+
+		archivePage := components.ArchivePage(months, h.assetsPath)
+		err = archivePage.Render(ctx, w)
+		if err != nil {
+			http.Error(w, "Failed to render archive page", http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// ServeArchivePeriod serves the articles published in a year, or in a month of it when the route has
+// monthPathParam as well
+func (h *Handler) ServeArchivePeriod(yearPathParam, monthPathParam string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		year, err := strconv.Atoi(r.PathValue(yearPathParam))
+		if err != nil || year < 1 || year > 9999 {
+			http.Error(w, "Invalid year", http.StatusNotFound)
+			return
+		}
+		var month time.Month
+		if monthStr := r.PathValue(monthPathParam); monthStr != "" {
+			m, err := strconv.Atoi(monthStr)
+			if err != nil || m < 1 || m > 12 {
+				http.Error(w, "Invalid month", http.StatusNotFound)
+				return
+			}
+			month = time.Month(m)
+		}
+
+		from := time.Date(year, max(month, time.January), 1, 0, 0, 0, 0, time.UTC)
+		to := from.AddDate(1, 0, 0)
+		if month != 0 {
+			to = from.AddDate(0, 1, 0)
+		}
+
+		slog.Debug("Serving archive period",
+			"requestID", middleware.ReqIDFromCtx(r.Context()),
+			"year", year,
+			"month", month,
+		)
+		articles, err := h.service.GetSummariesByPublicationDate(ctx, from, to, nil)
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, "Failed to fetch articles", http.StatusInternalServerError)
+			return
+		}
+
+		periodPage := components.ArchivePeriodPage(year, month, articles, h.assetsPath)
+		err = periodPage.Render(ctx, w)
+		if err != nil {
+			http.Error(w, "Failed to render archive page", http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package media
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	m "github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/middleware"
+)
+
+// Serve returns an http.Handler serving uploaded media. Files never change under their hash,
+// so they can be cached by browsers and proxies for as long as they like.
+func Serve(service *m.Service, hashPathParam, namePathParam string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash := r.PathValue(hashPathParam)
+		name := r.PathValue(namePathParam)
+
+		slog.Debug("Serving media", "requestID", middleware.ReqIDFromCtx(r.Context()), "hash", hash, "name", name)
+		media, content, err := service.Open(r.Context(), hash, name)
+		if err != nil {
+			if errors.Is(err, m.ErrMediaNotFound) {
+				http.NotFound(w, r)
+			} else {
+				slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", media.ContentType)
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("ETag", `"`+media.Hash+`"`)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		http.ServeContent(w, r, media.Name, media.CreatedAt, content)
+	})
+}
@@ -0,0 +1,68 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mediahandler "github.com/jannawro/blog/handlers/media"
+	"github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServe(t *testing.T) {
+	storage, err := media.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	service := media.NewService(mock.NewMediaRepository(), storage, nil)
+
+	content := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\b\x00\x00\x00\x00:~\x9bU" +
+		"\x00\x00\x00\x0fIDATx\x9c\x00\x02\x00\xfd\xff\x02\x00\x03\x00\x00\x06\x00\x03!\xfc\xac\x06\x00\x00\x00\x00IEND\xaeB`\x82")
+	uploaded, err := service.Upload(context.Background(), "photo.png", bytes.NewReader(content))
+	require.NoError(t, err)
+
+	router := http.NewServeMux()
+	router.Handle("GET /media/{hash}/{name}", mediahandler.Serve(service, "hash", "name"))
+
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := middleware.SetReqID(httptest.NewRequest("GET", path, nil))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Serve an uploaded file", func(t *testing.T) {
+		rr := serve(uploaded.URL(), nil)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, content, rr.Body.Bytes())
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+		assert.Equal(t, `"`+uploaded.Hash+`"`, rr.Header().Get("ETag"))
+	})
+
+	t.Run("Revalidate with ETag", func(t *testing.T) {
+		rr := serve(uploaded.URL(), http.Header{"If-None-Match": {`"` + uploaded.Hash + `"`}})
+
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+	})
+
+	t.Run("Unknown name", func(t *testing.T) {
+		rr := serve("/media/"+uploaded.Hash+"/other.png", nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Unknown hash", func(t *testing.T) {
+		rr := serve("/media/"+string(bytes.Repeat([]byte("a"), 64))+"/photo.png", nil)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	a "github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/middleware"
+)
+
+// maxImportSize bounds the size of an uploaded archive
+const maxImportSize = 64 << 20
+
+type importResponse struct {
+	DryRun  bool             `json:"dry_run"`
+	Results []a.ImportResult `json:"results"`
+}
+
+// ExportArticles streams every article as an archive. The format is picked with the "format" query
+// parameter, tar.gz (the default) or zip.
+func (h *Handler) ExportArticles() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format, err := a.ParseArchiveFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, a.ErrUnsupportedArchiveFormat.Error(), http.StatusBadRequest)
+			return
+		}
+
+		slog.Debug("Exporting articles", "requestID", middleware.ReqIDFromCtx(r.Context()), "format", format)
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf(`attachment; filename="articles-%s.%s"`, time.Now().UTC().Format("2006-01-02"), format),
+		)
+		err = h.service.Export(r.Context(), w, format)
+		if errors.Is(err, a.ErrArticlesNotFound) {
+			// Nothing was written yet
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			w.Header().Del("Content-Disposition")
+			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+		} else if err != nil {
+			// The archive is streamed, it's too late to change the response
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+		}
+	})
+}
+
+// ImportArticles creates articles from an archive written by ExportArticles, sent as the request body.
+// Articles whose slug is taken are skipped unless the "conflict" query parameter is "overwrite".
+// With "dry_run=true" nothing is saved. Responds with what happened to every file of the archive.
+func (h *Handler) ImportArticles() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+		conflict, err := a.ParseConflictMode(r.URL.Query().Get("conflict"))
+		if err != nil {
+			slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, a.ErrInvalidConflictMode.Error(), http.StatusBadRequest)
+			return
+		}
+		options := a.ImportOptions{Conflict: conflict}
+		if dryRun := r.URL.Query().Get("dry_run"); dryRun != "" {
+			options.DryRun, err = strconv.ParseBool(dryRun)
+			if err != nil {
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, "Invalid dry_run value", http.StatusBadRequest)
+				return
+			}
+		}
+
+		slog.Debug("Importing articles", "requestID", middleware.ReqIDFromCtx(r.Context()),
+			"conflict", options.Conflict,
+			"dryRun", options.DryRun,
+		)
+		results, err := h.service.Import(r.Context(), r.Body, options)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesErr):
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, "Archive too large", http.StatusRequestEntityTooLarge)
+			case errors.Is(err, a.ErrInvalidArchive):
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(importResponse{DryRun: options.DryRun, Results: results})
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportAndImportArticles(t *testing.T) {
+	source, sourceRepo := setupTest()
+	sourceRepo.SetArticles([]article.Article{
+		{ID: 1, Title: "Article 1", Slug: "article-1", Content: "Content 1", Tags: []string{"tag1"}, PublicationDate: time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Title: "Article 2", Slug: "article-2", Content: "Content 2", Tags: []string{"tag2"}, PublicationDate: time.Date(2023, 5, 11, 0, 0, 0, 0, time.UTC)},
+	})
+
+	req := middleware.SetReqID(httptest.NewRequest("GET", "/api/export?format=zip", nil))
+	rr := httptest.NewRecorder()
+	source.ExportArticles().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), ".zip")
+	archive := rr.Body.Bytes()
+
+	target, targetRepo := setupTest()
+	targetRepo.SetArticles([]article.Article{{ID: 5, Title: "Article 2", Slug: "article-2", Content: "Older content"}})
+
+	t.Run("Dry run", func(t *testing.T) {
+		req := middleware.SetReqID(httptest.NewRequest("POST", "/api/import?dry_run=true", bytes.NewReader(archive)))
+		rr := httptest.NewRecorder()
+		target.ImportArticles().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response struct {
+			DryRun  bool                   `json:"dry_run"`
+			Results []article.ImportResult `json:"results"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.True(t, response.DryRun)
+		assert.Equal(t, []article.ImportResult{
+			{File: "articles/article-1.md", Slug: "article-1", Status: article.ImportCreated},
+			{File: "articles/article-2.md", Slug: "article-2", Status: article.ImportSkipped},
+		}, response.Results)
+		all, err := targetRepo.GetAll(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		req := middleware.SetReqID(httptest.NewRequest("POST", "/api/import?conflict=overwrite", bytes.NewReader(archive)))
+		rr := httptest.NewRecorder()
+		target.ImportArticles().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response struct {
+			Results []article.ImportResult `json:"results"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		require.Len(t, response.Results, 2)
+		assert.Equal(t, article.ImportCreated, response.Results[0].Status)
+		assert.Equal(t, article.ImportUpdated, response.Results[1].Status)
+		all, err := targetRepo.GetAll(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	})
+
+	t.Run("Invalid conflict mode", func(t *testing.T) {
+		req := middleware.SetReqID(httptest.NewRequest("POST", "/api/import?conflict=merge", bytes.NewReader(archive)))
+		rr := httptest.NewRecorder()
+		target.ImportArticles().ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Invalid archive", func(t *testing.T) {
+		req := middleware.SetReqID(httptest.NewRequest("POST", "/api/import", bytes.NewReader([]byte("not an archive"))))
+		rr := httptest.NewRecorder()
+		target.ImportArticles().ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestExportArticlesUnsupportedFormat(t *testing.T) {
+	handler, _ := setupTest()
+
+	req := middleware.SetReqID(httptest.NewRequest("GET", "/api/export?format=rar", nil))
+	rr := httptest.NewRecorder()
+	handler.ExportArticles().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
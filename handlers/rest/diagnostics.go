@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository/cache"
+)
+
+type DiagnosticsHandler struct {
+	databaseStats func() map[string]sql.DBStats
+	cacheStats    func() cache.Stats
+}
+
+// NewDiagnosticsHandler creates a handler reporting the statistics databaseStats returns, e.g. those of
+// backend.Backend.Stats, and the ones of the article cache. cacheStats is nil if the cache is disabled.
+func NewDiagnosticsHandler(
+	databaseStats func() map[string]sql.DBStats,
+	cacheStats func() cache.Stats,
+) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		databaseStats: databaseStats,
+		cacheStats:    cacheStats,
+	}
+}
+
+type poolStatsResponse struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+// DatabaseStats reports the connection pool statistics of every database the blog uses, keyed by the role of
+// the database.
+func (h *DiagnosticsHandler) DatabaseStats() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := h.databaseStats()
+		response := make(map[string]poolStatsResponse, len(stats))
+		for name, s := range stats {
+			response[name] = poolStatsResponse{
+				MaxOpenConnections: s.MaxOpenConnections,
+				OpenConnections:    s.OpenConnections,
+				InUse:              s.InUse,
+				Idle:               s.Idle,
+				WaitCount:          s.WaitCount,
+				WaitDuration:       s.WaitDuration.String(),
+				MaxIdleClosed:      s.MaxIdleClosed,
+				MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+				MaxLifetimeClosed:  s.MaxLifetimeClosed,
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+type cacheStatsResponse struct {
+	Enabled   bool   `json:"enabled"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// CacheStats reports how the article cache is doing
+func (h *DiagnosticsHandler) CacheStats() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response cacheStatsResponse
+		if h.cacheStats != nil {
+			stats := h.cacheStats()
+			response = cacheStatsResponse{
+				Enabled:   true,
+				Hits:      stats.Hits,
+				Misses:    stats.Misses,
+				Evictions: stats.Evictions,
+				Entries:   stats.Entries,
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(response)
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			return
+		}
+	})
+}
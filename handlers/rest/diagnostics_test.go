@@ -0,0 +1,66 @@
+package rest_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jannawro/blog/handlers/rest"
+	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseStats(t *testing.T) {
+	handler := rest.NewDiagnosticsHandler(func() map[string]sql.DBStats {
+		return map[string]sql.DBStats{
+			"primary":   {MaxOpenConnections: 10, OpenConnections: 3, InUse: 1, Idle: 2, WaitDuration: 1500 * time.Millisecond},
+			"replica-0": {OpenConnections: 1, Idle: 1},
+		}
+	}, nil)
+
+	req := middleware.SetReqID(httptest.NewRequest("GET", "/api/diagnostics/database", nil))
+	rr := httptest.NewRecorder()
+	handler.DatabaseStats().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response map[string]map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Contains(t, response, "primary")
+	assert.EqualValues(t, 10, response["primary"]["max_open_connections"])
+	assert.EqualValues(t, 1, response["primary"]["in_use"])
+	assert.Equal(t, "1.5s", response["primary"]["wait_duration"])
+	assert.EqualValues(t, 1, response["replica-0"]["open_connections"])
+}
+
+func TestCacheStats(t *testing.T) {
+	t.Run("Enabled", func(t *testing.T) {
+		handler := rest.NewDiagnosticsHandler(nil, func() cache.Stats {
+			return cache.Stats{Hits: 10, Misses: 3, Evictions: 1, Entries: 2}
+		})
+
+		req := middleware.SetReqID(httptest.NewRequest("GET", "/api/diagnostics/cache", nil))
+		rr := httptest.NewRecorder()
+		handler.CacheStats().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"enabled":true,"hits":10,"misses":3,"evictions":1,"entries":2}`, rr.Body.String())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		handler := rest.NewDiagnosticsHandler(nil, nil)
+
+		req := middleware.SetReqID(httptest.NewRequest("GET", "/api/diagnostics/cache", nil))
+		rr := httptest.NewRecorder()
+		handler.CacheStats().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"enabled":false,"hits":0,"misses":0,"evictions":0,"entries":0}`, rr.Body.String())
+	})
+}
@@ -3,17 +3,27 @@ package rest
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	a "github.com/jannawro/blog/article"
 	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository"
 )
 
 const internalServerErrorMsg = "Internal server error"
 
+// savedArticle is the response to creating or updating an article. The warnings, e.g. links to articles
+// that don't exist yet, didn't stop the article from being saved.
+type savedArticle struct {
+	*a.Article
+	Warnings []string `json:"warnings,omitempty"`
+}
+
 type Handler struct {
 	service *a.Service
 }
@@ -64,15 +74,27 @@ func (h *Handler) CreateArticle() http.Handler {
 			"requestID", middleware.ReqIDFromCtx(r.Context()),
 			"articleDetails", articleData.Article,
 		)
-		createdArticle, err := h.service.Create(r.Context(), unmarshaledArticle)
+		createdArticle, warnings, err := h.service.Create(r.Context(), unmarshaledArticle)
 		if err != nil {
-			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
-			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			var validationErr *a.ValidationError
+			if errors.As(err, &validationErr) {
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, validationErr.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, repository.ErrDuplicateSlug) {
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, repository.ErrDuplicateSlug.Error(), http.StatusConflict)
+			} else if errors.Is(err, repository.ErrReadOnly) {
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, repository.ErrReadOnly.Error(), http.StatusForbidden)
+			} else {
+				slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			}
 			return
 		}
 
 		w.WriteHeader(http.StatusCreated)
-		err = json.NewEncoder(w).Encode(createdArticle)
+		err = json.NewEncoder(w).Encode(savedArticle{Article: createdArticle, Warnings: warnings})
 		if err != nil {
 			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
 			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
@@ -84,9 +106,47 @@ func (h *Handler) CreateArticle() http.Handler {
 func (h *Handler) GetAllArticles() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sortOption := a.GetSortOption(r)
+		from, to, err := parseDateRange(r)
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+
+		content, err := withContent(r)
+		if err != nil {
+			slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, a.ErrUnknownField.Error(), http.StatusBadRequest)
+			return
+		}
 
-		slog.Debug("Fetching all articles", "requestID", middleware.ReqIDFromCtx(r.Context()), "sortOption", sortOption)
-		articles, err := h.service.GetAll(r.Context(), &sortOption)
+		// Lists leave out the content unless asked for it
+		var articles any
+		if from.IsZero() && to.IsZero() {
+			slog.Debug("Fetching all articles",
+				"requestID", middleware.ReqIDFromCtx(r.Context()),
+				"sortOption", sortOption,
+				"content", content,
+			)
+			if content {
+				articles, err = h.service.GetAll(r.Context(), &sortOption)
+			} else {
+				articles, err = h.service.GetAllSummaries(r.Context(), &sortOption)
+			}
+		} else {
+			slog.Debug("Fetching articles by publication date",
+				"requestID", middleware.ReqIDFromCtx(r.Context()),
+				"from", from,
+				"to", to,
+				"sortOption", sortOption,
+				"content", content,
+			)
+			if content {
+				articles, err = h.service.GetByPublicationDate(r.Context(), from, to, &sortOption)
+			} else {
+				articles, err = h.service.GetSummariesByPublicationDate(r.Context(), from, to, &sortOption)
+			}
+		}
 		if err != nil {
 			if errors.Is(err, a.ErrArticlesNotFound) {
 				slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
@@ -170,12 +230,24 @@ func (h *Handler) GetArticlesByTags() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tags := r.URL.Query()["tag"]
 		sortOption := a.GetSortOption(r)
+		content, err := withContent(r)
+		if err != nil {
+			slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, a.ErrUnknownField.Error(), http.StatusBadRequest)
+			return
+		}
 
 		slog.Debug("Fetching articles by tags: "+strings.Join(tags, ", "),
 			"requestID", middleware.ReqIDFromCtx(r.Context()),
 			"sortOption", sortOption,
+			"content", content,
 		)
-		articles, err := h.service.GetByTags(r.Context(), tags, &sortOption)
+		var articles any
+		if content {
+			articles, err = h.service.GetByTags(r.Context(), tags, &sortOption)
+		} else {
+			articles, err = h.service.GetSummariesByTags(r.Context(), tags, &sortOption)
+		}
 		if err != nil {
 			if errors.Is(err, a.ErrArticlesNotFound) {
 				slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
@@ -221,11 +293,21 @@ func (h *Handler) UpdateArticleByTitle(slugPathParam string) http.Handler {
 			return
 		}
 
-		updatedArticle, err := h.service.UpdateBySlug(r.Context(), slug, unmarshaledArticle)
+		updatedArticle, warnings, err := h.service.UpdateBySlug(r.Context(), slug, unmarshaledArticle)
 		if err != nil {
+			var validationErr *a.ValidationError
 			if errors.Is(err, a.ErrArticleNotFound) {
 				slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
 				http.Error(w, a.ErrArticleNotFound.Error(), http.StatusNotFound)
+			} else if errors.As(err, &validationErr) {
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, validationErr.Error(), http.StatusBadRequest)
+			} else if errors.Is(err, repository.ErrDuplicateSlug) {
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, repository.ErrDuplicateSlug.Error(), http.StatusConflict)
+			} else if errors.Is(err, repository.ErrReadOnly) {
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, repository.ErrReadOnly.Error(), http.StatusForbidden)
 			} else {
 				slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
 				http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
@@ -233,7 +315,7 @@ func (h *Handler) UpdateArticleByTitle(slugPathParam string) http.Handler {
 			return
 		}
 
-		err = json.NewEncoder(w).Encode(updatedArticle)
+		err = json.NewEncoder(w).Encode(savedArticle{Article: updatedArticle, Warnings: warnings})
 		if err != nil {
 			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
 			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
@@ -252,6 +334,9 @@ func (h *Handler) DeleteArticleByTitle(slugPathParam string) http.Handler {
 			if errors.Is(err, a.ErrArticleNotFound) {
 				slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
 				http.Error(w, a.ErrArticleNotFound.Error(), http.StatusNotFound)
+			} else if errors.Is(err, repository.ErrReadOnly) {
+				slog.Info(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+				http.Error(w, repository.ErrReadOnly.Error(), http.StatusForbidden)
 			} else {
 				slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
 				http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
@@ -281,3 +366,41 @@ func (h *Handler) GetAllTags() http.Handler {
 		}
 	})
 }
+
+// parseDateRange reads the from and to query parameters, both dates and both optional. The range includes the
+// day of to, so it ends at the start of the next day.
+func parseDateRange(r *http.Request) (from, to time.Time, err error) {
+	if s := r.URL.Query().Get("from"); s != "" {
+		from, err = time.Parse(time.DateOnly, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		to, err = time.Parse(time.DateOnly, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
+
+// withContent reads the fields query parameter of list endpoints, a comma separated list of what to return
+// besides the summaries of the articles. The only field is content: the markdown, the rendered HTML and the
+// references.
+func withContent(r *http.Request) (bool, error) {
+	content := false
+	for _, param := range r.URL.Query()["fields"] {
+		for _, field := range strings.Split(param, ",") {
+			switch strings.TrimSpace(field) {
+			case "content":
+				content = true
+			case "":
+			default:
+				return false, errors.Join(a.ErrUnknownField, fmt.Errorf("field %q", field))
+			}
+		}
+	}
+	return content, nil
+}
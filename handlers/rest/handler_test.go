@@ -5,19 +5,25 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
 	"github.com/jannawro/blog/handlers/rest"
 	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository/fs"
 	"github.com/jannawro/blog/repository/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func setupTest() (*rest.Handler, *mock.Repository) {
 	mockRepo := mock.NewRepository()
-	service := article.NewService(mockRepo)
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, components.DefaultShortcodes(), mockRepo)
+	service := article.NewService(mockRepo, renderer)
 	handler := rest.NewHandler(service)
 	return handler, mockRepo
 }
@@ -81,6 +87,46 @@ func TestCreateArticle(t *testing.T) {
 		assert.Equal(t, http.StatusConflict, rr.Code)
 		assert.Contains(t, rr.Body.String(), "An article with this title already exists")
 	})
+
+	t.Run("Reject article with an unknown shortcode", func(t *testing.T) {
+		articleData := []byte(`{
+			"article": "title:Shortcodes\npublicationDate:2023-05-15\ntags:test\n===\n{{< youtub dQw4w9WgXcQ >}}"
+		}`)
+
+		req, err := http.NewRequest("POST", "/articles", bytes.NewBuffer(articleData))
+		req = middleware.SetReqID(req)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		handler.CreateArticle().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), `unknown shortcode "youtub"`)
+	})
+
+	t.Run("Warn about an article linking to a missing article", func(t *testing.T) {
+		articleData := []byte(`{
+			"article": "title:Links\npublicationDate:2023-05-15\ntags:test\n===\nSee [[no-such-article]]."
+		}`)
+
+		req, err := http.NewRequest("POST", "/articles", bytes.NewBuffer(articleData))
+		req = middleware.SetReqID(req)
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		handler.CreateArticle().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		var response struct {
+			Slug     string   `json:"slug"`
+			Warnings []string `json:"warnings"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, "links", response.Slug)
+		assert.Equal(t, []string{`[[no-such-article]]: no article with slug "no-such-article"`}, response.Warnings)
+	})
 }
 
 func TestGetAllArticles(t *testing.T) {
@@ -109,6 +155,49 @@ func TestGetAllArticles(t *testing.T) {
 	assert.Equal(t, "Article 2", response[1].Title)
 }
 
+func TestGetAllArticlesByPublicationDate(t *testing.T) {
+	handler, mockRepo := setupTest()
+	mockRepo.SetArticles([]article.Article{
+		{ID: 1, Title: "Article 1", Slug: "article-1", PublicationDate: time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Title: "Article 2", Slug: "article-2", PublicationDate: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 3, Title: "Article 3", Slug: "article-3", PublicationDate: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+	})
+
+	tests := []struct {
+		name   string
+		query  string
+		code   int
+		titles []string
+	}{
+		{"Both bounds include their day", "?from=2024-01-31&to=2024-02-01", http.StatusOK, []string{"Article 1", "Article 2"}},
+		{"Only from", "?from=2024-02-01", http.StatusOK, []string{"Article 2", "Article 3"}},
+		{"Only to", "?to=2024-01-31", http.StatusOK, []string{"Article 1"}},
+		{"Nothing in range", "?from=2025-01-01", http.StatusOK, []string{}},
+		{"Invalid date", "?from=2024-13-01", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/articles"+tt.query, nil)
+			req = middleware.SetReqID(req)
+			rr := httptest.NewRecorder()
+			handler.GetAllArticles().ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var response article.Articles
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			titles := []string{}
+			for _, a := range response {
+				titles = append(titles, a.Title)
+			}
+			assert.Equal(t, tt.titles, titles)
+		})
+	}
+}
+
 func TestGetArticleByTitle(t *testing.T) {
 	handler, mockRepo := setupTest()
 
@@ -165,7 +254,7 @@ func TestGetArticlesByTags(t *testing.T) {
 	}
 	mockRepo.SetArticles(articles)
 
-	req := httptest.NewRequest("GET", "/articles?tag=tag1&tag=tag2", nil)
+	req := httptest.NewRequest("GET", "/articles?tag=tag2", nil)
 	req = middleware.SetReqID(req)
 
 	rr := httptest.NewRecorder()
@@ -176,8 +265,55 @@ func TestGetArticlesByTags(t *testing.T) {
 	var response article.Articles
 	err := json.NewDecoder(rr.Body).Decode(&response)
 	assert.NoError(t, err)
-	assert.Len(t, response, 1)
-	assert.Equal(t, "Article 1", response[0].Title)
+	assert.Len(t, response, 2)
+	assert.ElementsMatch(t, []string{"Article 1", "Article 2"}, []string{response[0].Title, response[1].Title})
+}
+
+func TestListFields(t *testing.T) {
+	handler, mockRepo := setupTest()
+	mockRepo.SetArticles([]article.Article{
+		{ID: 1, Title: "Article 1", Slug: "article-1", Content: "Markdown", ContentHTML: "<p>Markdown</p>", Tags: []string{"tag1"}},
+	})
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		query   string
+		code    int
+		content bool
+	}{
+		{"All articles without content", handler.GetAllArticles(), "", http.StatusOK, false},
+		{"All articles with content", handler.GetAllArticles(), "?fields=content", http.StatusOK, true},
+		{"Articles by date with content", handler.GetAllArticles(), "?to=2030-01-01&fields=content", http.StatusOK, true},
+		{"Articles by tags without content", handler.GetArticlesByTags(), "?tag=tag1", http.StatusOK, false},
+		{"Articles by tags with content", handler.GetArticlesByTags(), "?tag=tag1&fields=content", http.StatusOK, true},
+		{"Unknown field", handler.GetAllArticles(), "?fields=content,views", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/articles"+tt.query, nil)
+			req = middleware.SetReqID(req)
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.code, rr.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var response []map[string]any
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			assert.Len(t, response, 1)
+			assert.Equal(t, "Article 1", response[0]["title"])
+			if tt.content {
+				assert.Equal(t, "Markdown", response[0]["content"])
+				assert.Equal(t, "<p>Markdown</p>", response[0]["content_html"])
+			} else {
+				assert.NotContains(t, response[0], "content")
+				assert.NotContains(t, response[0], "content_html")
+			}
+		})
+	}
 }
 
 func TestUpdateArticleByTitle(t *testing.T) {
@@ -231,6 +367,53 @@ func TestDeleteArticleByTitle(t *testing.T) {
 	assert.Len(t, articles, 0)
 }
 
+func TestRepositoryErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, a := range []article.Article{
+		{Title: "First", Slug: "first", Content: "First content.", PublicationDate: time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{Title: "Second", Slug: "second", Content: "Second content.", PublicationDate: time.Date(2023, 5, 11, 0, 0, 0, 0, time.UTC)},
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, a.Slug+".md"), article.MarshalArticle(a), 0o644))
+	}
+	newHandler := func(writable bool) *rest.Handler {
+		repo, err := fs.NewRepository(dir, fs.Options{Writable: writable, PollInterval: time.Hour})
+		require.NoError(t, err)
+		t.Cleanup(func() { repo.Close() })
+		renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, nil, repo)
+		return rest.NewHandler(article.NewService(repo, renderer))
+	}
+	serve := func(handler http.Handler, method, slug, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/articles/"+slug, bytes.NewBufferString(body))
+		req = middleware.SetReqID(req)
+		req.SetPathValue("title", slug)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Read-only repository", func(t *testing.T) {
+		handler := newHandler(false)
+
+		rr := serve(handler.CreateArticle(), "POST", "", `{"article": "title:Third\npublicationDate:2023-05-12\ntags:test\n===\nThird content."}`)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		rr = serve(handler.UpdateArticleByTitle("title"), "PUT", "first", `{"article": "title:First\npublicationDate:2023-05-10\ntags:test\n===\nChanged."}`)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		rr = serve(handler.DeleteArticleByTitle("title"), "DELETE", "first", "")
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "read-only")
+	})
+
+	t.Run("Duplicate slug", func(t *testing.T) {
+		handler := newHandler(true)
+
+		rr := serve(handler.UpdateArticleByTitle("title"), "PUT", "second", `{"article": "title:First\npublicationDate:2023-05-11\ntags:test\n===\nRenamed."}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Contains(t, rr.Body.String(), "same slug")
+	})
+}
+
 func TestGetAllTags(t *testing.T) {
 	handler, mockRepo := setupTest()
 
@@ -0,0 +1,106 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	m "github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/middleware"
+)
+
+// multipartOverhead leaves room for the multipart headers around the uploaded file
+const multipartOverhead = 1 << 20
+
+type MediaHandler struct {
+	service *m.Service
+}
+
+func NewMediaHandler(service *m.Service) *MediaHandler {
+	return &MediaHandler{
+		service: service,
+	}
+}
+
+type mediaResponse struct {
+	m.Media
+	URL string `json:"url"`
+}
+
+// UploadMedia accepts a multipart form with the file in the "file" field. The file name can be
+// overridden with the "name" field.
+func (h *MediaHandler) UploadMedia() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, m.MaxUploadSize+multipartOverhead)
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, m.ErrMediaTooLarge.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "A file is required in the 'file' form field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		name := header.Filename
+		if n := r.FormValue("name"); n != "" {
+			name = n
+		}
+
+		slog.Debug("Uploading media", "requestID", middleware.ReqIDFromCtx(r.Context()), "name", name)
+		uploaded, err := h.service.Upload(r.Context(), name, file)
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			switch {
+			case errors.Is(err, m.ErrMediaTooLarge):
+				http.Error(w, m.ErrMediaTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			case errors.Is(err, m.ErrImageTooLarge):
+				http.Error(w, m.ErrImageTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			case errors.Is(err, m.ErrUnsupportedMediaType):
+				http.Error(w, m.ErrUnsupportedMediaType.Error(), http.StatusUnsupportedMediaType)
+			case errors.Is(err, m.ErrInvalidName):
+				http.Error(w, m.ErrInvalidName.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(mediaResponse{Media: *uploaded, URL: uploaded.URL()})
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+func (h *MediaHandler) GetAllMedia() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.Debug("Fetching all media", "requestID", middleware.ReqIDFromCtx(r.Context()))
+		all, err := h.service.GetAll(r.Context())
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]mediaResponse, len(all))
+		for i, media := range all {
+			response[i] = mediaResponse{Media: media, URL: media.URL()}
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package rest_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jannawro/blog/handlers/rest"
+	"github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pixelPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\b\x00\x00\x00\x00:~\x9bU" +
+	"\x00\x00\x00\x0fIDATx\x9c\x00\x02\x00\xfd\xff\x02\x00\x03\x00\x00\x06\x00\x03!\xfc\xac\x06\x00\x00\x00\x00IEND\xaeB`\x82")
+
+// pngHeader returns the start of a grayscale PNG of the given size, enough to read its dimensions
+func pngHeader(width, height uint32) []byte {
+	ihdr := binary.BigEndian.AppendUint32([]byte("IHDR"), width)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, height)
+	ihdr = append(ihdr, 8, 0, 0, 0, 0)
+
+	header := []byte("\x89PNG\r\n\x1a\n")
+	header = binary.BigEndian.AppendUint32(header, uint32(len(ihdr)-4))
+	header = append(header, ihdr...)
+	return binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(ihdr))
+}
+
+func setupMediaTest(t *testing.T) *rest.MediaHandler {
+	storage, err := media.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	return rest.NewMediaHandler(media.NewService(mock.NewMediaRepository(), storage, nil))
+}
+
+func newUploadRequest(t *testing.T, fileName string, content []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", fileName)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/api/media", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return middleware.SetReqID(req)
+}
+
+func TestUploadMedia(t *testing.T) {
+	handler := setupMediaTest(t)
+
+	t.Run("Upload an image", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.UploadMedia().ServeHTTP(rr, newUploadRequest(t, "photo.png", pixelPNG))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		var response struct {
+			Hash        string `json:"hash"`
+			Name        string `json:"name"`
+			ContentType string `json:"content_type"`
+			URL         string `json:"url"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.Equal(t, "photo.png", response.Name)
+		assert.Equal(t, "image/png", response.ContentType)
+		assert.Equal(t, "/media/"+response.Hash+"/photo.png", response.URL)
+	})
+
+	t.Run("Reject unsupported content", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.UploadMedia().ServeHTTP(rr, newUploadRequest(t, "page.html", []byte("<html></html>")))
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
+	t.Run("Reject an image with too many pixels", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.UploadMedia().ServeHTTP(rr, newUploadRequest(t, "huge.png", pngHeader(10_000, 10_000)))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Contains(t, rr.Body.String(), media.ErrImageTooLarge.Error())
+	})
+
+	t.Run("Reject request without a file", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/media", nil)
+		req = middleware.SetReqID(req)
+		rr := httptest.NewRecorder()
+		handler.UploadMedia().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,49 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/jannawro/blog/gitsync"
+	"github.com/jannawro/blog/middleware"
+)
+
+type SyncHandler struct {
+	syncer *gitsync.Syncer
+}
+
+func NewSyncHandler(syncer *gitsync.Syncer) *SyncHandler {
+	return &SyncHandler{
+		syncer: syncer,
+	}
+}
+
+// Sync pulls the content repository and applies it to the articles, meant to be called by a push webhook.
+// Responds with the synced commit and what happened to every article.
+func (h *SyncHandler) Sync() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.Debug("Syncing articles", "requestID", middleware.ReqIDFromCtx(r.Context()))
+		// Webhook callers give up quickly, the sync is finished anyway
+		report, err := h.syncer.Sync(context.WithoutCancel(r.Context()))
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			if errors.Is(err, gitsync.ErrGitFailed) {
+				http.Error(w, "Pulling the content repository failed", http.StatusBadGateway)
+				return
+			}
+			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(report)
+		if err != nil {
+			slog.Error(err.Error(), "requestID", middleware.ReqIDFromCtx(r.Context()))
+			http.Error(w, internalServerErrorMsg, http.StatusInternalServerError)
+			return
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package rest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/components"
+	"github.com/jannawro/blog/gitsync"
+	"github.com/jannawro/blog/handlers/rest"
+	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSyncTest(t *testing.T, url string) (*rest.SyncHandler, *mock.Repository) {
+	mockRepo := mock.NewRepository()
+	renderer := components.NewRenderer(components.DefaultRendererOptions(), components.NewSanitizer(components.DefaultSanitizerConfig()), nil, components.DefaultShortcodes(), mockRepo)
+	service := article.NewService(mockRepo, renderer)
+	syncer := gitsync.NewSyncer(service, gitsync.Options{URL: url, Dir: filepath.Join(t.TempDir(), "checkout")})
+	return rest.NewSyncHandler(syncer), mockRepo
+}
+
+func TestSync(t *testing.T) {
+	t.Run("Sync the repository", func(t *testing.T) {
+		content := t.TempDir()
+		data := "title: Synced\npublicationDate: 2024-05-10\ntags: go\n===\nFrom git."
+		require.NoError(t, os.WriteFile(filepath.Join(content, "synced.md"), []byte(data), 0o644))
+		for _, args := range [][]string{
+			{"init", "--quiet"},
+			{"add", "--all"},
+			{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "--quiet", "--message", "Add"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = content
+			output, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(output))
+		}
+		handler, mockRepo := setupSyncTest(t, content)
+
+		req := middleware.SetReqID(httptest.NewRequest("POST", "/api/sync", nil))
+		rr := httptest.NewRecorder()
+		handler.Sync().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var response gitsync.Report
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.NotEmpty(t, response.Commit)
+		assert.Equal(t, []article.ImportResult{
+			{File: "synced.md", Slug: "synced", Status: article.ImportCreated},
+		}, response.Results)
+		_, err := mockRepo.GetBySlug(req.Context(), "synced")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unreachable repository", func(t *testing.T) {
+		handler, _ := setupSyncTest(t, filepath.Join(t.TempDir(), "missing"))
+
+		req := middleware.SetReqID(httptest.NewRequest("POST", "/api/sync", nil))
+		rr := httptest.NewRecorder()
+		handler.Sync().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadGateway, rr.Code)
+	})
+}
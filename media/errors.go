@@ -0,0 +1,16 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMediaNotFound        = errors.New("media not found")
+	ErrMediaUploadFailed    = errors.New("media upload failed")
+	ErrMediaTooLarge        = fmt.Errorf("media larger than %d bytes", MaxUploadSize)
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrInvalidName          = errors.New("invalid media name")
+	ErrImageTooLarge        = fmt.Errorf("image larger than %d pixels", MaxImagePixels)
+	ErrVariantsFailed       = errors.New("generating image variants failed")
+)
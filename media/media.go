@@ -0,0 +1,70 @@
+package media
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// PathPrefix is the public path media files are served under
+const PathPrefix = "/media/"
+
+// Media is an uploaded file. Files are stored under the hex encoded SHA-256 hash of their content,
+// so uploading the same file twice yields the same Media.
+type Media struct {
+	ID          int64     `json:"id"`
+	Hash        string    `json:"hash"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// URL returns the public path of the file, e.g. /media/<hash>/photo.jpg
+func (m Media) URL() string {
+	return PathPrefix + m.Hash + "/" + m.Name
+}
+
+// Variant is a resized copy of an uploaded image. Like the original it is stored and served
+// under the hash of its own content.
+type Variant struct {
+	ID          int64     `json:"id"`
+	MediaID     int64     `json:"media_id"`
+	Hash        string    `json:"hash"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// URL returns the public path of the variant, e.g. /media/<hash>/photo-640w.webp
+func (v Variant) URL() string {
+	return PathPrefix + v.Hash + "/" + v.Name
+}
+
+// Image is an uploaded image together with its variants, ordered by width.
+type Image struct {
+	Media
+	Variants []Variant
+}
+
+type MediaRepository interface {
+	Create(ctx context.Context, media Media) (*Media, error)
+	// GetByHash returns ErrMediaNotFound if there is no media with hash
+	GetByHash(ctx context.Context, hash string) (*Media, error)
+	GetAll(ctx context.Context) ([]Media, error)
+	UpdateDimensions(ctx context.Context, id int64, width, height int) error
+	CreateVariant(ctx context.Context, variant Variant) (*Variant, error)
+	GetVariantByHash(ctx context.Context, hash string) (*Variant, error)
+	GetVariants(ctx context.Context, mediaID int64) ([]Variant, error)
+}
+
+// Storage keeps the content of media files. Files are addressed by their hash.
+type Storage interface {
+	Put(ctx context.Context, hash string, content io.Reader) error
+	Open(ctx context.Context, hash string) (io.ReadSeekCloser, error)
+}
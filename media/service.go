@@ -0,0 +1,203 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// MaxUploadSize is the largest file that can be uploaded, in bytes
+const MaxUploadSize = 32 << 20
+
+// allowedContentTypes lists what can be uploaded. SVG and HTML are left out on purpose,
+// browsers would execute scripts embedded in them.
+var allowedContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"video/mp4":       true,
+	"video/webm":      true,
+	"audio/mpeg":      true,
+	"audio/ogg":       true,
+	"application/pdf": true,
+}
+
+type Service struct {
+	repo          MediaRepository
+	storage       Storage
+	variantWidths []int
+}
+
+// NewService returns a Service generating variants of uploaded images at variantWidths.
+// No variants are generated when variantWidths is empty.
+func NewService(repo MediaRepository, storage Storage, variantWidths []int) *Service {
+	return &Service{
+		repo:          repo,
+		storage:       storage,
+		variantWidths: normalizeWidths(variantWidths),
+	}
+}
+
+// Upload stores a file and its metadata. The content type is sniffed from the content rather than
+// trusted from the client. Uploading a file that already exists returns the existing Media.
+// Variants of images are generated right away.
+func (s *Service) Upload(ctx context.Context, name string, content io.Reader) (*Media, error) {
+	name = SanitizeName(name)
+	if name == "" {
+		return nil, ErrInvalidName
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, MaxUploadSize+1))
+	if err != nil {
+		return nil, errors.Join(ErrMediaUploadFailed, err)
+	}
+	if len(data) > MaxUploadSize {
+		return nil, ErrMediaTooLarge
+	}
+
+	contentType := detectContentType(data)
+	if !allowedContentTypes[contentType] {
+		return nil, errors.Join(ErrUnsupportedMediaType, errors.New(contentType))
+	}
+
+	var width, height int
+	if isImage(contentType) {
+		width, height, err = imageDimensions(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hash := hashOf(data)
+
+	existing, err := s.repo.GetByHash(ctx, hash)
+	if err == nil {
+		if _, err := s.ensureVariants(ctx, existing); err != nil {
+			return nil, errors.Join(ErrMediaUploadFailed, err)
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, ErrMediaNotFound) {
+		return nil, errors.Join(ErrMediaUploadFailed, err)
+	}
+
+	if err := s.storage.Put(ctx, hash, bytes.NewReader(data)); err != nil {
+		return nil, errors.Join(ErrMediaUploadFailed, err)
+	}
+
+	m, err := s.repo.Create(ctx, Media{
+		Hash:        hash,
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Width:       width,
+		Height:      height,
+	})
+	if err != nil {
+		return nil, errors.Join(ErrMediaUploadFailed, err)
+	}
+
+	if _, err := s.ensureVariants(ctx, m); err != nil {
+		return nil, errors.Join(ErrMediaUploadFailed, err)
+	}
+	return m, nil
+}
+
+// Open returns the metadata and content of a file or of an image variant. The name has to match
+// the stored one.
+func (s *Service) Open(ctx context.Context, hash, name string) (*Media, io.ReadSeekCloser, error) {
+	m, err := s.repo.GetByHash(ctx, hash)
+	if err != nil {
+		v, variantErr := s.repo.GetVariantByHash(ctx, hash)
+		if variantErr != nil {
+			return nil, nil, errors.Join(ErrMediaNotFound, err, variantErr)
+		}
+		m = &Media{
+			Hash:        v.Hash,
+			Name:        v.Name,
+			ContentType: v.ContentType,
+			Size:        v.Size,
+			Width:       v.Width,
+			Height:      v.Height,
+			CreatedAt:   v.CreatedAt,
+		}
+	}
+	if m.Name != name {
+		return nil, nil, ErrMediaNotFound
+	}
+
+	content, err := s.storage.Open(ctx, hash)
+	if err != nil {
+		return nil, nil, errors.Join(ErrMediaNotFound, err)
+	}
+	return m, content, nil
+}
+
+// Image looks up the image served under src, e.g. /media/<hash>/photo.jpg, together with its variants.
+// Variants missing because the image was uploaded before the configured widths changed are generated
+// on the way. ErrMediaNotFound is returned when src doesn't point at an uploaded image.
+func (s *Service) Image(ctx context.Context, src string) (*Image, error) {
+	hash, name, ok := strings.Cut(strings.TrimPrefix(src, PathPrefix), "/")
+	if !ok || !strings.HasPrefix(src, PathPrefix) {
+		return nil, ErrMediaNotFound
+	}
+
+	m, err := s.repo.GetByHash(ctx, hash)
+	if err != nil {
+		return nil, errors.Join(ErrMediaNotFound, err)
+	}
+	if m.Name != name || !isImage(m.ContentType) {
+		return nil, ErrMediaNotFound
+	}
+
+	variants, err := s.ensureVariants(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	return &Image{Media: *m, Variants: variants}, nil
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]Media, error) {
+	all, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, errors.Join(ErrMediaNotFound, err)
+	}
+	return all, nil
+}
+
+// SanitizeName reduces a file name to its base name made of letters, digits, dots, dashes and underscores,
+// so it can be safely used in an URL path.
+func SanitizeName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)), r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('-')
+		}
+	}
+
+	return strings.Trim(b.String(), ".-")
+}
+
+func hashOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func detectContentType(data []byte) string {
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	return contentType
+}
@@ -0,0 +1,234 @@
+package media_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/repository/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pixelPNG is a 1x1 pixel PNG image
+var pixelPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\b\x00\x00\x00\x00:~\x9bU" +
+	"\x00\x00\x00\x0fIDATx\x9c\x00\x02\x00\xfd\xff\x02\x00\x03\x00\x00\x06\x00\x03!\xfc\xac\x06\x00\x00\x00\x00IEND\xaeB`\x82")
+
+func setupTestService(t *testing.T) *media.Service {
+	storage, err := media.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	return media.NewService(mock.NewMediaRepository(), storage, media.DefaultVariantWidths)
+}
+
+func TestUploadAndOpen(t *testing.T) {
+	service := setupTestService(t)
+	ctx := context.Background()
+
+	uploaded, err := service.Upload(ctx, "My Photo.png", bytes.NewReader(pixelPNG))
+	require.NoError(t, err)
+
+	assert.Equal(t, "My-Photo.png", uploaded.Name)
+	assert.Equal(t, "image/png", uploaded.ContentType)
+	assert.Equal(t, int64(len(pixelPNG)), uploaded.Size)
+	assert.Len(t, uploaded.Hash, 64)
+	assert.Equal(t, "/media/"+uploaded.Hash+"/My-Photo.png", uploaded.URL())
+
+	opened, content, err := service.Open(ctx, uploaded.Hash, "My-Photo.png")
+	require.NoError(t, err)
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, pixelPNG, data)
+	assert.Equal(t, uploaded.ID, opened.ID)
+}
+
+func TestUploadDeduplicatesContent(t *testing.T) {
+	service := setupTestService(t)
+	ctx := context.Background()
+
+	first, err := service.Upload(ctx, "first.png", bytes.NewReader(pixelPNG))
+	require.NoError(t, err)
+	second, err := service.Upload(ctx, "second.png", bytes.NewReader(pixelPNG))
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+}
+
+func TestUploadRejectsInvalidFiles(t *testing.T) {
+	service := setupTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		fileName    string
+		content     io.Reader
+		expectedErr error
+	}{
+		{"HTML", "page.html", strings.NewReader("<html><script>alert(1)</script></html>"), media.ErrUnsupportedMediaType},
+		{"SVG", "image.svg", strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), media.ErrUnsupportedMediaType},
+		{"Too large", "big.png", io.MultiReader(bytes.NewReader(pixelPNG), bytes.NewReader(make([]byte, media.MaxUploadSize))), media.ErrMediaTooLarge},
+		{"No name", "...", bytes.NewReader(pixelPNG), media.ErrInvalidName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Upload(ctx, tt.fileName, tt.content)
+			assert.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+// failingMediaRepository fails to look up media
+type failingMediaRepository struct {
+	*mock.MediaRepository
+}
+
+func (failingMediaRepository) GetByHash(context.Context, string) (*media.Media, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestUploadFailsWhenLookupFails(t *testing.T) {
+	storage, err := media.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	repo := failingMediaRepository{mock.NewMediaRepository()}
+	service := media.NewService(repo, storage, media.DefaultVariantWidths)
+
+	_, err = service.Upload(context.Background(), "photo.png", bytes.NewReader(pixelPNG))
+	assert.ErrorIs(t, err, media.ErrMediaUploadFailed)
+	all, err := repo.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestOpenRequiresMatchingName(t *testing.T) {
+	service := setupTestService(t)
+	ctx := context.Background()
+
+	uploaded, err := service.Upload(ctx, "photo.png", bytes.NewReader(pixelPNG))
+	require.NoError(t, err)
+
+	_, _, err = service.Open(ctx, uploaded.Hash, "other.png")
+	assert.ErrorIs(t, err, media.ErrMediaNotFound)
+
+	_, _, err = service.Open(ctx, "../../etc/passwd", "photo.png")
+	assert.ErrorIs(t, err, media.ErrMediaNotFound)
+}
+
+func TestUploadGeneratesVariants(t *testing.T) {
+	service := setupTestService(t)
+	ctx := context.Background()
+
+	uploaded, err := service.Upload(ctx, "photo.jpg", bytes.NewReader(encodeJPEG(t, 1000, 500)))
+	require.NoError(t, err)
+	assert.Equal(t, 1000, uploaded.Width)
+	assert.Equal(t, 500, uploaded.Height)
+
+	img, err := service.Image(ctx, uploaded.URL())
+	require.NoError(t, err)
+
+	var jpegWidths []int
+	for _, v := range img.Variants {
+		assert.Equal(t, uploaded.ID, v.MediaID)
+		assert.Equal(t, v.Width/2, v.Height)
+		if v.ContentType == "image/jpeg" {
+			jpegWidths = append(jpegWidths, v.Width)
+		}
+	}
+	assert.Equal(t, []int{320, 640, 960}, jpegWidths)
+
+	variant := img.Variants[0]
+	opened, content, err := service.Open(ctx, variant.Hash, variant.Name)
+	require.NoError(t, err)
+	defer content.Close()
+	assert.Equal(t, variant.ContentType, opened.ContentType)
+
+	decoded, _, err := image.Decode(content)
+	require.NoError(t, err)
+	assert.Equal(t, 320, decoded.Bounds().Dx())
+}
+
+func TestImageGeneratesMissingVariants(t *testing.T) {
+	repo := mock.NewMediaRepository()
+	storage, err := media.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	uploaded, err := media.NewService(repo, storage, nil).Upload(ctx, "photo.jpg", bytes.NewReader(encodeJPEG(t, 800, 600)))
+	require.NoError(t, err)
+	// Uploaded before dimensions were recorded
+	require.NoError(t, repo.UpdateDimensions(ctx, uploaded.ID, 0, 0))
+
+	img, err := media.NewService(repo, storage, []int{400}).Image(ctx, uploaded.URL())
+	require.NoError(t, err)
+
+	assert.Equal(t, 800, img.Width)
+	assert.Equal(t, 600, img.Height)
+	require.NotEmpty(t, img.Variants)
+	assert.Equal(t, 400, img.Variants[0].Width)
+	assert.Equal(t, 300, img.Variants[0].Height)
+	assert.Equal(t, "photo-400w.jpg", img.Variants[0].Name)
+}
+
+func TestImageNotFound(t *testing.T) {
+	service := setupTestService(t)
+	ctx := context.Background()
+
+	uploaded, err := service.Upload(ctx, "photo.png", bytes.NewReader(pixelPNG))
+	require.NoError(t, err)
+
+	for _, src := range []string{
+		"https://example.com/photo.png",
+		"/media/" + uploaded.Hash + "/other.png",
+		"/media/" + uploaded.Hash,
+		"/assets/photo.png",
+	} {
+		_, err := service.Image(ctx, src)
+		assert.ErrorIs(t, err, media.ErrMediaNotFound, src)
+	}
+
+	img, err := service.Image(ctx, uploaded.URL())
+	require.NoError(t, err)
+	assert.Empty(t, img.Variants)
+}
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"photo.jpg", "photo.jpg"},
+		{"My Holiday Photo.JPG", "My-Holiday-Photo.JPG"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\cat.png`, "cat.png"},
+		{"<script>.png", "script.png"},
+		{"zdjęcie.png", "zdjcie.png"},
+		{".hidden", "hidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, media.SanitizeName(tt.name))
+		})
+	}
+}
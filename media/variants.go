@@ -0,0 +1,220 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	_ "image/gif"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+)
+
+// DefaultVariantWidths are the widths, in pixels, variants of uploaded images are generated at
+var DefaultVariantWidths = []int{320, 640, 960, 1280, 1920}
+
+// MaxImagePixels limits the size of uploaded images, so a small file can't make the server
+// decode an enormous image.
+const MaxImagePixels = 50_000_000
+
+const jpegQuality = 82
+
+// resizableContentTypes lists the images variants are generated for. GIFs are served as uploaded,
+// resizing them would drop their animation.
+var resizableContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+var variantExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// ensureVariants returns the variants of m, generating the ones that are missing. Every variant is
+// generated in the content type of the original and additionally as WebP. The WebP encoder is lossless,
+// so a WebP variant is only kept when it turns out smaller than its JPEG or PNG counterpart.
+func (s *Service) ensureVariants(ctx context.Context, m *Media) ([]Variant, error) {
+	if !resizableContentTypes[m.ContentType] || len(s.variantWidths) == 0 {
+		return nil, nil
+	}
+
+	variants, err := s.repo.GetVariants(ctx, m.ID)
+	if err != nil {
+		return nil, errors.Join(ErrVariantsFailed, err)
+	}
+
+	generated := make(map[int]bool)
+	for _, v := range variants {
+		if v.ContentType == m.ContentType {
+			generated[v.Width] = true
+		}
+	}
+
+	var original image.Image
+	for _, width := range s.variantWidths {
+		if generated[width] || (m.Width != 0 && width >= m.Width) {
+			continue
+		}
+
+		if original == nil {
+			original, err = s.decode(ctx, m)
+			if err != nil {
+				return nil, errors.Join(ErrVariantsFailed, err)
+			}
+			if m.Width == 0 {
+				// Uploaded before dimensions were recorded
+				m.Width, m.Height = original.Bounds().Dx(), original.Bounds().Dy()
+				if err := s.repo.UpdateDimensions(ctx, m.ID, m.Width, m.Height); err != nil {
+					return nil, errors.Join(ErrVariantsFailed, err)
+				}
+				if width >= m.Width {
+					continue
+				}
+			}
+		}
+
+		created, err := s.generateVariants(ctx, m, resize(original, width))
+		if err != nil {
+			return nil, errors.Join(ErrVariantsFailed, err)
+		}
+		variants = append(variants, created...)
+	}
+
+	slices.SortFunc(variants, func(a, b Variant) int {
+		if a.Width != b.Width {
+			return a.Width - b.Width
+		}
+		return strings.Compare(a.ContentType, b.ContentType)
+	})
+	return variants, nil
+}
+
+func (s *Service) decode(ctx context.Context, m *Media) (image.Image, error) {
+	content, err := s.storage.Open(ctx, m.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	img, _, err := image.Decode(content)
+	return img, err
+}
+
+func (s *Service) generateVariants(ctx context.Context, m *Media, img image.Image) ([]Variant, error) {
+	data, err := encode(img, m.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.storeVariant(ctx, m, img, m.ContentType, data)
+	if err != nil {
+		return nil, err
+	}
+	variants := []Variant{*v}
+
+	if m.ContentType == "image/webp" {
+		return variants, nil
+	}
+
+	webp, err := encode(img, "image/webp")
+	if err != nil {
+		return nil, err
+	}
+	if len(webp) < len(data) {
+		v, err := s.storeVariant(ctx, m, img, "image/webp", webp)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, *v)
+	}
+	return variants, nil
+}
+
+func (s *Service) storeVariant(ctx context.Context, m *Media, img image.Image, contentType string, data []byte) (*Variant, error) {
+	hash := hashOf(data)
+	if err := s.storage.Put(ctx, hash, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	v, err := s.repo.CreateVariant(ctx, Variant{
+		MediaID:     m.ID,
+		Hash:        hash,
+		Name:        variantName(m.Name, img.Bounds().Dx(), contentType),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	})
+	if err != nil {
+		// The same variant may have just been generated by a concurrent request
+		if existing, getErr := s.repo.GetVariantByHash(ctx, hash); getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// resize scales img down to width, keeping its aspect ratio
+func resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Src, nil)
+	return resized
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	case "image/webp":
+		err = nativewebp.Encode(&buf, img, nil)
+	default:
+		err = errors.Join(ErrUnsupportedMediaType, errors.New(contentType))
+	}
+	return buf.Bytes(), err
+}
+
+// variantName derives the name of a variant from the original, e.g. photo.jpg becomes photo-640w.webp
+func variantName(name string, width int, contentType string) string {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	return base + "-" + strconv.Itoa(width) + "w" + variantExtensions[contentType]
+}
+
+func imageDimensions(data []byte) (int, int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, errors.Join(ErrUnsupportedMediaType, err)
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return 0, 0, ErrImageTooLarge
+	}
+	return config.Width, config.Height, nil
+}
+
+func isImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+// normalizeWidths returns the positive widths sorted and without duplicates
+func normalizeWidths(widths []int) []int {
+	normalized := slices.DeleteFunc(slices.Clone(widths), func(w int) bool { return w <= 0 })
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type primaryReadsKey struct{}
+
+// PrimaryReads makes repositories with read replicas serve every read of a request from the primary database,
+// so clients see their own writes straight away. The API uses it, pages can live with replication lag.
+func PrimaryReads() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithPrimaryReads(r.Context())))
+		})
+	}
+}
+
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+func PrimaryReadsFromCtx(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryReadsKey{}).(bool)
+	return primary
+}
@@ -0,0 +1,23 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jannawro/blog/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrimaryReads(t *testing.T) {
+	assert.False(t, middleware.PrimaryReadsFromCtx(context.Background()))
+	assert.True(t, middleware.PrimaryReadsFromCtx(middleware.WithPrimaryReads(context.Background())))
+
+	var primary bool
+	handler := middleware.PrimaryReads()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primary = middleware.PrimaryReadsFromCtx(r.Context())
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.True(t, primary)
+}
@@ -0,0 +1,157 @@
+// Package outbox delivers the article events the repositories record in their outbox to sinks outside the
+// repository, e.g. webhooks.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jannawro/blog/article"
+)
+
+// Sink receives article events. An event can be delivered more than once, e.g. when another sink failed to take
+// it, so sinks should tell duplicates apart by the event ID.
+type Sink interface {
+	Deliver(ctx context.Context, event article.Event) error
+}
+
+type Options struct {
+	// PollInterval is how often the outbox is checked for new events
+	PollInterval time.Duration
+	// BatchSize is how many events are claimed at once
+	BatchSize int
+	// Lease is how long claimed events are hidden from other dispatchers, it has to cover delivering a batch
+	Lease time.Duration
+	// MaxAttempts is how often delivering an event is tried before it's dead
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry of an event. It doubles with every retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the longest wait between retries
+	MaxBackoff time.Duration
+	// Retention is how long delivered events are kept, zero keeps them forever
+	Retention time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		PollInterval:   time.Second,
+		BatchSize:      100,
+		Lease:          5 * time.Minute,
+		MaxAttempts:    10,
+		InitialBackoff: 10 * time.Second,
+		MaxBackoff:     time.Hour,
+		Retention:      7 * 24 * time.Hour,
+	}
+}
+
+// Dispatcher delivers the events in the outbox to every sink, at least once. Failed deliveries are retried with
+// a growing backoff and the event is marked dead after Options.MaxAttempts.
+type Dispatcher struct {
+	events  article.EventRepository
+	sinks   []Sink
+	options Options
+}
+
+func NewDispatcher(events article.EventRepository, sinks []Sink, options Options) *Dispatcher {
+	return &Dispatcher{
+		events:  events,
+		sinks:   sinks,
+		options: options,
+	}
+}
+
+// Run delivers events until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	poll := time.NewTicker(d.options.PollInterval)
+	defer poll.Stop()
+	var prune <-chan time.Time
+	if d.options.Retention > 0 {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		prune = ticker.C
+		d.prune(ctx)
+	}
+
+	for {
+		claimed, err := d.Dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Encountered an unexpected error when dispatching article events", "error", err)
+		}
+		// A full batch means more events are waiting
+		if err == nil && claimed == d.options.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		case <-prune:
+			d.prune(ctx)
+		}
+	}
+}
+
+// Dispatch delivers a batch of due events and returns how many were claimed
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	events, err := d.events.ClaimEvents(ctx, d.options.BatchSize, d.options.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for _, event := range events {
+		errs = append(errs, d.dispatch(ctx, event))
+	}
+	return len(events), errors.Join(errs...)
+}
+
+// dispatch delivers event to every sink and records the outcome
+func (d *Dispatcher) dispatch(ctx context.Context, event article.Event) error {
+	var errs []error
+	for _, sink := range d.sinks {
+		errs = append(errs, sink.Deliver(ctx, event))
+	}
+	deliveryErr := errors.Join(errs...)
+	if deliveryErr == nil {
+		return d.events.MarkEventDelivered(ctx, event.ID)
+	}
+
+	attempts := event.Attempts + 1
+	dead := attempts >= d.options.MaxAttempts
+	retryIn := d.backoff(attempts)
+	if dead {
+		slog.Error("Gave up delivering an article event", "event", event.ID, "operation", event.Operation,
+			"slug", event.Article.Slug, "attempts", attempts, "error", deliveryErr)
+	} else {
+		slog.Warn("Delivering an article event failed", "event", event.ID, "operation", event.Operation,
+			"slug", event.Article.Slug, "attempts", attempts, "retryIn", retryIn, "error", deliveryErr)
+	}
+	return d.events.MarkEventFailed(ctx, event.ID, deliveryErr.Error(), retryIn, dead)
+}
+
+// backoff is the wait before the next delivery after the given number of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.options.InitialBackoff
+	for range attempts - 1 {
+		backoff *= 2
+		if backoff >= d.options.MaxBackoff {
+			return d.options.MaxBackoff
+		}
+	}
+	return min(backoff, d.options.MaxBackoff)
+}
+
+// prune deletes the events delivered before the retention
+func (d *Dispatcher) prune(ctx context.Context) {
+	deleted, err := d.events.DeleteDeliveredEvents(ctx, d.options.Retention)
+	if err != nil {
+		slog.Error("Encountered an unexpected error when deleting delivered article events", "error", err)
+		return
+	}
+	if deleted > 0 {
+		slog.Debug("Deleted delivered article events", "deleted", deleted)
+	}
+}
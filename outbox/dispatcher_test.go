@@ -0,0 +1,171 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/outbox"
+)
+
+// failure is a failed delivery recorded by the fake outbox
+type failure struct {
+	id      int64
+	retryIn time.Duration
+	dead    bool
+}
+
+// fakeOutbox hands out its pending events once each
+type fakeOutbox struct {
+	mutex     sync.Mutex
+	pending   []article.Event
+	delivered []int64
+	failures  []failure
+	pruned    []time.Duration
+}
+
+func (o *fakeOutbox) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]article.Event, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	claimed := o.pending[:min(limit, len(o.pending))]
+	o.pending = o.pending[len(claimed):]
+	return claimed, nil
+}
+
+func (o *fakeOutbox) MarkEventDelivered(ctx context.Context, id int64) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.delivered = append(o.delivered, id)
+	return nil
+}
+
+func (o *fakeOutbox) MarkEventFailed(
+	ctx context.Context,
+	id int64,
+	message string,
+	retryIn time.Duration,
+	dead bool,
+) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.failures = append(o.failures, failure{id: id, retryIn: retryIn, dead: dead})
+	return nil
+}
+
+func (o *fakeOutbox) DeleteDeliveredEvents(ctx context.Context, age time.Duration) (int64, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.pruned = append(o.pruned, age)
+	return 0, nil
+}
+
+// sinkFunc adapts a function to outbox.Sink
+type sinkFunc func(event article.Event) error
+
+func (f sinkFunc) Deliver(ctx context.Context, event article.Event) error {
+	return f(event)
+}
+
+func newEvent(id int64, attempts int) article.Event {
+	return article.Event{
+		ID:        id,
+		Operation: article.ChangeCreated,
+		Article:   article.Article{ID: id, Slug: "article"},
+		Attempts:  attempts,
+	}
+}
+
+func testOptions() outbox.Options {
+	options := outbox.DefaultOptions()
+	options.BatchSize = 2
+	options.MaxAttempts = 3
+	options.InitialBackoff = time.Second
+	options.MaxBackoff = 3 * time.Second
+	return options
+}
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Every sink gets every event", func(t *testing.T) {
+		events := &fakeOutbox{pending: []article.Event{newEvent(1, 0), newEvent(2, 0), newEvent(3, 0)}}
+		var first, second []int64
+		dispatcher := outbox.NewDispatcher(events, []outbox.Sink{
+			sinkFunc(func(event article.Event) error { first = append(first, event.ID); return nil }),
+			sinkFunc(func(event article.Event) error { second = append(second, event.ID); return nil }),
+		}, testOptions())
+
+		claimed, err := dispatcher.Dispatch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, claimed)
+		claimed, err = dispatcher.Dispatch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, claimed)
+
+		assert.Equal(t, []int64{1, 2, 3}, first)
+		assert.Equal(t, []int64{1, 2, 3}, second)
+		assert.Equal(t, []int64{1, 2, 3}, events.delivered)
+		assert.Empty(t, events.failures)
+	})
+
+	t.Run("Failures back off until the event is dead", func(t *testing.T) {
+		events := &fakeOutbox{pending: []article.Event{newEvent(1, 0), newEvent(2, 1), newEvent(3, 2)}}
+		dispatcher := outbox.NewDispatcher(events, []outbox.Sink{
+			sinkFunc(func(article.Event) error { return nil }),
+			sinkFunc(func(article.Event) error { return errors.New("unreachable") }),
+		}, testOptions())
+
+		for range 2 {
+			_, err := dispatcher.Dispatch(ctx)
+			require.NoError(t, err)
+		}
+
+		assert.Empty(t, events.delivered)
+		assert.Equal(t, []failure{
+			{id: 1, retryIn: time.Second},
+			{id: 2, retryIn: 2 * time.Second},
+			{id: 3, retryIn: 3 * time.Second, dead: true},
+		}, events.failures)
+	})
+}
+
+func TestRun(t *testing.T) {
+	events := &fakeOutbox{pending: []article.Event{newEvent(1, 0), newEvent(2, 0), newEvent(3, 0)}}
+	bus := outbox.NewBus()
+	received := make(chan int64, 3)
+	bus.Subscribe(func(event article.Event) { received <- event.ID })
+	options := testOptions()
+	options.PollInterval = time.Hour
+	dispatcher := outbox.NewDispatcher(events, []outbox.Sink{bus}, options)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		dispatcher.Run(ctx)
+	}()
+
+	// A full batch is followed by the next one right away, long before the poll interval
+	for _, id := range []int64{1, 2, 3} {
+		select {
+		case got := <-received:
+			assert.Equal(t, id, got)
+		case <-time.After(5 * time.Second):
+			t.Fatal("event not delivered")
+		}
+	}
+	cancel()
+	<-stopped
+
+	assert.Equal(t, []time.Duration{options.Retention}, events.pruned)
+}
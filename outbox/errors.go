@@ -0,0 +1,5 @@
+package outbox
+
+import "errors"
+
+var ErrDeliveryFailed = errors.New("delivering the article event failed")
@@ -0,0 +1,130 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jannawro/blog/article"
+)
+
+// webhookTimeout bounds a single webhook request
+const webhookTimeout = 10 * time.Second
+
+// WebhookSink posts every event as JSON to a URL. Any response but a 2xx is a failed delivery.
+//
+// The request carries the event ID and operation in the X-Blog-Event-ID and X-Blog-Event headers. With a secret,
+// X-Blog-Signature holds "sha256=" and the hex encoded HMAC-SHA256 of the body, so the receiver can verify it.
+type WebhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (s *WebhookSink) Deliver(ctx context.Context, event article.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Join(ErrDeliveryFailed, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Join(ErrDeliveryFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Blog-Event", string(event.Operation))
+	req.Header.Set("X-Blog-Event-ID", strconv.FormatInt(event.ID, 10))
+	if s.secret != "" {
+		req.Header.Set("X-Blog-Signature", "sha256="+Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Join(ErrDeliveryFailed, err)
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Join(ErrDeliveryFailed, fmt.Errorf("webhook %s responded %s", s.url, resp.Status))
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body, the signature WebhookSink sends
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// LogSink logs every event, e.g. to follow the changes in the logs of the blog
+type LogSink struct{}
+
+func (LogSink) Deliver(ctx context.Context, event article.Event) error {
+	slog.Info("Article event",
+		"event", event.ID,
+		"operation", event.Operation,
+		"id", event.Article.ID,
+		"slug", event.Article.Slug,
+		"occurredAt", event.OccurredAt,
+	)
+	return nil
+}
+
+// Bus hands the events to functions subscribed in the same process. The functions are called one at a time by
+// the dispatcher, so a slow one holds up the delivery of the following events.
+type Bus struct {
+	mutex       sync.RWMutex
+	subscribers map[uint64]func(article.Event)
+	nextID      uint64
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[uint64]func(article.Event)),
+	}
+}
+
+// Subscribe calls f with every delivered event until unsubscribe is called
+func (b *Bus) Subscribe(f func(article.Event)) (unsubscribe func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = f
+	return func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+func (b *Bus) Deliver(ctx context.Context, event article.Event) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for _, f := range b.subscribers {
+		f(event)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package outbox_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/outbox"
+)
+
+func TestWebhookSink(t *testing.T) {
+	ctx := context.Background()
+	event := newEvent(7, 0)
+
+	t.Run("Signed delivery", func(t *testing.T) {
+		var received article.Event
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, "created", r.Header.Get("X-Blog-Event"))
+			assert.Equal(t, "7", r.Header.Get("X-Blog-Event-ID"))
+			assert.Equal(t, "sha256="+outbox.Sign("secret", body), r.Header.Get("X-Blog-Signature"))
+			require.NoError(t, json.Unmarshal(body, &received))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		require.NoError(t, outbox.NewWebhookSink(server.URL, "secret").Deliver(ctx, event))
+		assert.Equal(t, event.ID, received.ID)
+		assert.Equal(t, event.Article.Slug, received.Article.Slug)
+	})
+
+	t.Run("Unsigned without a secret", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Empty(t, r.Header.Get("X-Blog-Signature"))
+		}))
+		defer server.Close()
+
+		assert.NoError(t, outbox.NewWebhookSink(server.URL, "").Deliver(ctx, event))
+	})
+
+	t.Run("Error responses fail the delivery", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		err := outbox.NewWebhookSink(server.URL, "").Deliver(ctx, event)
+		assert.ErrorIs(t, err, outbox.ErrDeliveryFailed)
+		assert.ErrorContains(t, err, "503")
+	})
+}
+
+func TestBus(t *testing.T) {
+	bus := outbox.NewBus()
+	var received []int64
+	unsubscribe := bus.Subscribe(func(event article.Event) { received = append(received, event.ID) })
+
+	require.NoError(t, bus.Deliver(context.Background(), newEvent(1, 0)))
+	unsubscribe()
+	require.NoError(t, bus.Deliver(context.Background(), newEvent(2, 0)))
+
+	assert.Equal(t, []int64{1}, received)
+}
@@ -0,0 +1,261 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/repository"
+)
+
+// idCreator is implemented by article repositories able to create an article under a given ID
+type idCreator interface {
+	CreateWithID(ctx context.Context, article article.Article) (*article.Article, error)
+}
+
+// Count is the number of rows of a kind in the source and the target of Copy
+type Count struct {
+	Source int
+	Target int
+}
+
+func (c Count) Matches() bool {
+	return c.Source == c.Target
+}
+
+// CopyReport is the outcome of Copy. The target is counted after copying, read back from the database.
+type CopyReport struct {
+	Articles Count
+	Tags     Count
+	Links    Count
+	Media    Count
+	Variants Count
+	// PreservedIDs is false if the target can't create articles under given IDs, the articles got new IDs then
+	PreservedIDs bool
+}
+
+// Matches reports whether the target holds as many rows of every kind as the source
+func (r CopyReport) Matches() bool {
+	return r.Articles.Matches() && r.Tags.Matches() && r.Links.Matches() && r.Media.Matches() && r.Variants.Matches()
+}
+
+// CopyOptions configures Copy.
+type CopyOptions struct {
+	// Force copies into a target that isn't empty, e.g. to finish a copy that failed halfway. Copy isn't atomic,
+	// a failure leaves what was copied so far in the target. Articles and media already in the target, matched by
+	// slug and hash, are left as they are and the rest is copied.
+	Force bool
+}
+
+// Copy copies the articles, their links and the media records from src to dst, which has to be empty unless
+// options.Force is set. Articles keep their IDs if dst supports it, slugs and dates are kept as they are. Links
+// to articles that don't exist can't be read from src, so the links are taken from the markdown of the articles
+// with linkTargets instead. The media files themselves are left where they are.
+func Copy(
+	ctx context.Context,
+	dst, src *Backend,
+	linkTargets func(markdown string) []string,
+	options CopyOptions,
+) (CopyReport, error) {
+	var report CopyReport
+	if !options.Force {
+		if err := checkEmpty(ctx, dst); err != nil {
+			return report, err
+		}
+	}
+
+	articles, err := src.Articles.GetAll(ctx)
+	if err != nil {
+		return report, errors.Join(repository.ErrCopyFailed, err)
+	}
+	tags, err := src.Articles.GetAllTags(ctx)
+	if err != nil {
+		return report, errors.Join(repository.ErrCopyFailed, err)
+	}
+	report.Articles.Source = len(articles)
+	report.Tags.Source = len(tags)
+
+	// ids maps the IDs of the articles in src to their IDs in dst, starting with the ones copied already
+	ids := make(map[int64]int64, len(articles))
+	copied, err := dst.Articles.GetAll(ctx)
+	if err != nil {
+		return report, errors.Join(repository.ErrCopyFailed, err)
+	}
+	slugs := make(map[string]int64, len(copied))
+	for _, a := range copied {
+		slugs[a.Slug] = a.ID
+	}
+
+	creator, preserveIDs := dst.Articles.(idCreator)
+	report.PreservedIDs = preserveIDs
+	for _, a := range articles {
+		if id, ok := slugs[a.Slug]; ok {
+			ids[a.ID] = id
+			continue
+		}
+
+		var created *article.Article
+		if preserveIDs {
+			created, err = creator.CreateWithID(ctx, a)
+		} else {
+			created, err = dst.Articles.Create(ctx, a)
+		}
+		if err != nil {
+			return report, errors.Join(repository.ErrCopyFailed, fmt.Errorf("article %q: %w", a.Slug, err))
+		}
+		ids[a.ID] = created.ID
+	}
+	// Links are set once every article exists
+	for _, a := range articles {
+		if err := dst.Articles.SetLinks(ctx, ids[a.ID], linkTargets(a.Content)); err != nil {
+			return report, errors.Join(repository.ErrCopyFailed, fmt.Errorf("links of article %q: %w", a.Slug, err))
+		}
+	}
+	report.Links.Source, err = countLinks(ctx, src.Articles, articles)
+	if err != nil {
+		return report, errors.Join(repository.ErrCopyFailed, err)
+	}
+
+	if err := copyMedia(ctx, dst.Media, src.Media, &report); err != nil {
+		return report, errors.Join(repository.ErrCopyFailed, err)
+	}
+
+	if err := countTarget(ctx, dst, &report); err != nil {
+		return report, errors.Join(repository.ErrCopyFailed, err)
+	}
+	return report, nil
+}
+
+func checkEmpty(ctx context.Context, b *Backend) error {
+	articles, err := b.Articles.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	allMedia, err := b.Media.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	if len(articles) > 0 || len(allMedia) > 0 {
+		return errors.Join(
+			repository.ErrTargetNotEmpty,
+			fmt.Errorf("found %d articles and %d media", len(articles), len(allMedia)),
+		)
+	}
+	return nil
+}
+
+func copyMedia(ctx context.Context, dst, src media.MediaRepository, report *CopyReport) error {
+	allMedia, err := src.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	report.Media.Source = len(allMedia)
+
+	copied, err := copiedMedia(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	// GetAll returns the newest media first, they are created oldest first to keep the order
+	for _, m := range slices.Backward(allMedia) {
+		variants, err := src.GetVariants(ctx, m.ID)
+		if err != nil {
+			return err
+		}
+		report.Variants.Source += len(variants)
+
+		target, ok := copied[m.Hash]
+		if !ok {
+			created, err := dst.Create(ctx, m)
+			if err != nil {
+				return fmt.Errorf("media %s: %w", m.Hash, err)
+			}
+			target = copiedMediaItem{id: created.ID}
+		}
+		for _, v := range variants {
+			if target.variants[v.Hash] {
+				continue
+			}
+			v.MediaID = target.id
+			if _, err := dst.CreateVariant(ctx, v); err != nil {
+				return fmt.Errorf("variant %s of media %s: %w", v.Hash, m.Hash, err)
+			}
+		}
+	}
+	return nil
+}
+
+// copiedMediaItem is a media record found in the target of Copy with the hashes of its variants
+type copiedMediaItem struct {
+	id       int64
+	variants map[string]bool
+}
+
+// copiedMedia returns the media records in repo by hash, they were copied by an earlier Copy
+func copiedMedia(ctx context.Context, repo media.MediaRepository) (map[string]copiedMediaItem, error) {
+	allMedia, err := repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	copied := make(map[string]copiedMediaItem, len(allMedia))
+	for _, m := range allMedia {
+		variants, err := repo.GetVariants(ctx, m.ID)
+		if err != nil {
+			return nil, err
+		}
+		item := copiedMediaItem{id: m.ID, variants: make(map[string]bool, len(variants))}
+		for _, v := range variants {
+			item.variants[v.Hash] = true
+		}
+		copied[m.Hash] = item
+	}
+	return copied, nil
+}
+
+func countTarget(ctx context.Context, b *Backend, report *CopyReport) error {
+	articles, err := b.Articles.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	tags, err := b.Articles.GetAllTags(ctx)
+	if err != nil {
+		return err
+	}
+	report.Articles.Target = len(articles)
+	report.Tags.Target = len(tags)
+
+	report.Links.Target, err = countLinks(ctx, b.Articles, articles)
+	if err != nil {
+		return err
+	}
+
+	allMedia, err := b.Media.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	report.Media.Target = len(allMedia)
+	for _, m := range allMedia {
+		variants, err := b.Media.GetVariants(ctx, m.ID)
+		if err != nil {
+			return err
+		}
+		report.Variants.Target += len(variants)
+	}
+	return nil
+}
+
+// countLinks counts the links between articles. Links to articles that don't exist can't be read back.
+func countLinks(ctx context.Context, repo article.ArticleRepository, articles article.Articles) (int, error) {
+	count := 0
+	for _, a := range articles {
+		backlinks, err := repo.GetBacklinks(ctx, a.Slug)
+		if err != nil {
+			return 0, err
+		}
+		count += len(backlinks)
+	}
+	return count, nil
+}
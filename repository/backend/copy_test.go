@@ -0,0 +1,138 @@
+package backend_test
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/repository"
+	"github.com/jannawro/blog/repository/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var wikiLink = regexp.MustCompile(`\[\[([a-z0-9-]+)\]\]`)
+
+func linkTargets(markdown string) []string {
+	var targets []string
+	for _, match := range wikiLink.FindAllStringSubmatch(markdown, -1) {
+		targets = append(targets, match[1])
+	}
+	return targets
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+
+	src, err := backend.Open(ctx, "memory://", backend.Options{})
+	require.NoError(t, err)
+	defer src.Close()
+
+	date := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	for _, a := range []article.Article{
+		{ID: 3, Title: "First", Slug: "first", Content: "See [[second]] and [[missing]].", Tags: []string{"go"}, PublicationDate: date},
+		{ID: 7, Title: "Second", Slug: "second", Content: "Back to [[first]].", Tags: []string{"go", "db"}, PublicationDate: date.Add(time.Hour)},
+	} {
+		_, err := src.Articles.(interface {
+			CreateWithID(ctx context.Context, article article.Article) (*article.Article, error)
+		}).CreateWithID(ctx, a)
+		require.NoError(t, err)
+		require.NoError(t, src.Articles.SetLinks(ctx, a.ID, linkTargets(a.Content)))
+	}
+	image, err := src.Media.Create(ctx, media.Media{Hash: "hash", Name: "photo.jpg", ContentType: "image/jpeg", Size: 100, Width: 800, Height: 600})
+	require.NoError(t, err)
+	_, err = src.Media.CreateVariant(ctx, media.Variant{MediaID: image.ID, Hash: "variant", Name: "photo-640w.webp", ContentType: "image/webp", Size: 50, Width: 640, Height: 480})
+	require.NoError(t, err)
+
+	dst, err := backend.Open(ctx, "sqlite://"+filepath.Join(t.TempDir(), "blog.db"), backend.Options{})
+	require.NoError(t, err)
+	defer dst.Close()
+
+	report, err := backend.Copy(ctx, dst, src, linkTargets, backend.CopyOptions{})
+	require.NoError(t, err)
+	assert.True(t, report.Matches(), "%+v", report)
+	assert.True(t, report.PreservedIDs)
+	assert.Equal(t, backend.Count{Source: 2, Target: 2}, report.Articles)
+	assert.Equal(t, backend.Count{Source: 2, Target: 2}, report.Tags)
+	assert.Equal(t, backend.Count{Source: 2, Target: 2}, report.Links)
+	assert.Equal(t, backend.Count{Source: 1, Target: 1}, report.Media)
+	assert.Equal(t, backend.Count{Source: 1, Target: 1}, report.Variants)
+
+	second, err := dst.Articles.GetByID(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "second", second.Slug)
+	assert.Equal(t, date.Add(time.Hour), second.PublicationDate)
+
+	// The link to the missing article is kept for when it gets created
+	_, err = dst.Articles.Create(ctx, article.Article{Title: "Missing", Slug: "missing", PublicationDate: date})
+	require.NoError(t, err)
+	backlinks, err := dst.Articles.GetBacklinks(ctx, "missing")
+	require.NoError(t, err)
+	require.Len(t, backlinks, 1)
+	assert.Equal(t, int64(3), backlinks[0].ID)
+
+	copiedImage, err := dst.Media.GetByHash(ctx, "hash")
+	require.NoError(t, err)
+	assert.Equal(t, 800, copiedImage.Width)
+	variants, err := dst.Media.GetVariants(ctx, copiedImage.ID)
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	assert.Equal(t, "variant", variants[0].Hash)
+
+	t.Run("Target not empty", func(t *testing.T) {
+		_, err := backend.Copy(ctx, dst, src, linkTargets, backend.CopyOptions{})
+		assert.ErrorIs(t, err, repository.ErrTargetNotEmpty)
+	})
+}
+
+func TestCopyForce(t *testing.T) {
+	ctx := context.Background()
+
+	src, err := backend.Open(ctx, "memory://", backend.Options{})
+	require.NoError(t, err)
+	defer src.Close()
+	dst, err := backend.Open(ctx, "memory://", backend.Options{})
+	require.NoError(t, err)
+	defer dst.Close()
+
+	date := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	for _, a := range []article.Article{
+		{Title: "First", Slug: "first", Content: "See [[second]].", PublicationDate: date},
+		{Title: "Second", Slug: "second", Content: "Back to [[first]].", PublicationDate: date.Add(time.Hour)},
+	} {
+		created, err := src.Articles.Create(ctx, a)
+		require.NoError(t, err)
+		require.NoError(t, src.Articles.SetLinks(ctx, created.ID, linkTargets(a.Content)))
+	}
+	image, err := src.Media.Create(ctx, media.Media{Hash: "hash", Name: "photo.jpg", ContentType: "image/jpeg"})
+	require.NoError(t, err)
+	for _, hash := range []string{"small", "large"} {
+		_, err = src.Media.CreateVariant(ctx, media.Variant{MediaID: image.ID, Hash: hash, Name: hash + ".webp"})
+		require.NoError(t, err)
+	}
+
+	// A copy that failed halfway, after the first article and a variant
+	first, err := src.Articles.GetBySlug(ctx, "first")
+	require.NoError(t, err)
+	_, err = dst.Articles.Create(ctx, *first)
+	require.NoError(t, err)
+	copiedImage, err := dst.Media.Create(ctx, *image)
+	require.NoError(t, err)
+	_, err = dst.Media.CreateVariant(ctx, media.Variant{MediaID: copiedImage.ID, Hash: "small", Name: "small.webp"})
+	require.NoError(t, err)
+
+	_, err = backend.Copy(ctx, dst, src, linkTargets, backend.CopyOptions{})
+	require.ErrorIs(t, err, repository.ErrTargetNotEmpty)
+
+	report, err := backend.Copy(ctx, dst, src, linkTargets, backend.CopyOptions{Force: true})
+	require.NoError(t, err)
+	assert.True(t, report.Matches(), "%+v", report)
+	assert.Equal(t, backend.Count{Source: 2, Target: 2}, report.Articles)
+	assert.Equal(t, backend.Count{Source: 2, Target: 2}, report.Links)
+	assert.Equal(t, backend.Count{Source: 1, Target: 1}, report.Media)
+	assert.Equal(t, backend.Count{Source: 2, Target: 2}, report.Variants)
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/jannawro/blog/repository"
+)
+
+// RetryOptions configures retrying to open a backend. The zero value makes a single attempt.
+type RetryOptions struct {
+	// Timeout bounds all the attempts together. Zero disables retrying.
+	Timeout time.Duration
+	// InitialBackoff is the wait before the second attempt. It doubles with every attempt up to MaxBackoff,
+	// the actual waits are randomized between half and all of it so instances starting together spread out.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// retry calls attempt until it succeeds, fails with an error retrying can't fix or the timeout runs out.
+func retry(ctx context.Context, options RetryOptions, attempt func(ctx context.Context) error) error {
+	if options.Timeout <= 0 {
+		return attempt(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	backoff := max(options.InitialBackoff, time.Millisecond)
+	for attempts := 1; ; attempts++ {
+		err := attempt(ctx)
+		if err == nil {
+			if attempts > 1 {
+				slog.Info("Opened the database", "attempts", attempts)
+			}
+			return nil
+		}
+		if permanent(err) {
+			return err
+		}
+
+		wait := backoff/2 + rand.N(backoff/2+1)
+		if time.Now().Add(wait).After(deadline) {
+			return errors.Join(repository.ErrOpenDeadlineExceeded, err)
+		}
+		slog.Warn("Opening the database failed, retrying", "attempt", attempts, "retryIn", wait.String(), "error", err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return errors.Join(repository.ErrOpenDeadlineExceeded, err)
+		}
+		if options.MaxBackoff > 0 {
+			backoff = min(backoff*2, options.MaxBackoff)
+		} else {
+			backoff *= 2
+		}
+	}
+}
+
+// permanent reports whether err comes from the configuration or the schema rather than the database being
+// unavailable, so retrying can't fix it. A migration whose SQL failed or left the database dirty needs fixing by
+// hand, see the migrate command. Other migration errors, like another instance holding the migration lock, are
+// retried.
+func permanent(err error) bool {
+	var dirty migrate.ErrDirty
+	return errors.Is(err, repository.ErrUnknownBackend) ||
+		errors.Is(err, repository.ErrInvalidDatabaseURL) ||
+		errors.Is(err, repository.ErrReplicasUnsupported) ||
+		errors.As(err, &dirty) ||
+		failedMigration(err)
+}
+
+// failedMigration reports whether err is the SQL of a migration failing. The database is left dirty, the next
+// attempt would fail with migrate.ErrDirty anyway.
+func failedMigration(err error) bool {
+	var dbErr database.Error
+	return errors.As(err, &dbErr) && strings.HasPrefix(dbErr.Err, "migration failed")
+}
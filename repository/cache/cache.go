@@ -0,0 +1,463 @@
+// Package cache keeps the results of article reads in memory. Articles change a few times a month while
+// every page view reads them, so most reads can skip the database.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/middleware"
+)
+
+type Options struct {
+	// Size is the maximum number of cached results. The least recently used result is dropped first.
+	Size int
+	// TTL is how long a result is served from the cache. Zero keeps results until they are dropped.
+	TTL time.Duration
+	// ReplicaLag is how long read replicas may serve the data from before a write. Reads not made from the primary,
+	// see middleware.PrimaryReads, aren't cached for that long after a write. Zero caches them right away.
+	ReplicaLag time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Size: 1024,
+		TTL:  5 * time.Minute,
+	}
+}
+
+// DefaultReplicaLag is the Options.ReplicaLag of a repository reading from read replicas
+const DefaultReplicaLag = 10 * time.Second
+
+// Stats counts how the cache has been doing since it was created
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+type kind int
+
+const (
+	kindID kind = iota
+	kindSlug
+	kindAll
+	kindTags
+	kindAllTags
+	kindBacklinks
+	kindPublicationDate
+	kindArchive
+)
+
+// entry is a cached result together with what it depends on, so writes drop only the results they change
+type entry struct {
+	key     string
+	kind    kind
+	slug    string
+	tags    []string
+	ids     []int64
+	value   any
+	expires time.Time
+}
+
+// Repository is an article.ArticleRepository serving reads of the wrapped repository from an LRU cache.
+// Writes go to the wrapped repository and drop the cached results they change. Cached articles and summaries
+// are shared between callers, they must not modify the tags or references of a returned one.
+type Repository struct {
+	repo    article.ArticleRepository
+	options Options
+	now     func() time.Time
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// generation changes with every write, results loaded across a write aren't cached
+	generation uint64
+	lastWrite  time.Time
+
+	loads     singleflight.Group
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+func NewRepository(repo article.ArticleRepository, options Options) *Repository {
+	return &Repository{
+		repo:    repo,
+		options: options,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (r *Repository) Stats() Stats {
+	r.mutex.Lock()
+	entries := r.lru.Len()
+	r.mutex.Unlock()
+
+	return Stats{
+		Hits:      r.hits.Load(),
+		Misses:    r.misses.Load(),
+		Evictions: r.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, a article.Article) (*article.Article, error) {
+	created, err := r.repo.Create(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(nil, created)
+	return created, nil
+}
+
+func (r *Repository) GetAll(ctx context.Context) (article.Articles, error) {
+	return getArticles(ctx, r, entry{key: "all", kind: kindAll}, func(ctx context.Context) (article.Articles, error) {
+		return r.repo.GetAll(ctx)
+	})
+}
+
+func (r *Repository) GetByID(ctx context.Context, id int64) (*article.Article, error) {
+	key := entry{key: "id:" + strconv.FormatInt(id, 10), kind: kindID, ids: []int64{id}}
+	return getArticle(ctx, r, key, func(ctx context.Context) (*article.Article, error) {
+		return r.repo.GetByID(ctx, id)
+	})
+}
+
+func (r *Repository) GetBySlug(ctx context.Context, slug string) (*article.Article, error) {
+	key := entry{key: "slug:" + slug, kind: kindSlug, slug: slug}
+	return getArticle(ctx, r, key, func(ctx context.Context) (*article.Article, error) {
+		return r.repo.GetBySlug(ctx, slug)
+	})
+}
+
+func (r *Repository) GetByTags(ctx context.Context, tags []string) (article.Articles, error) {
+	sorted := slices.Compact(slices.Sorted(slices.Values(tags)))
+	key := entry{key: "tags:" + strings.Join(sorted, ","), kind: kindTags, tags: sorted}
+	return getArticles(ctx, r, key, func(ctx context.Context) (article.Articles, error) {
+		return r.repo.GetByTags(ctx, tags)
+	})
+}
+
+func (r *Repository) GetByPublicationDate(ctx context.Context, from, to time.Time) (article.Articles, error) {
+	key := entry{key: "published:" + rangeKey(from) + "," + rangeKey(to), kind: kindPublicationDate}
+	return getArticles(ctx, r, key, func(ctx context.Context) (article.Articles, error) {
+		return r.repo.GetByPublicationDate(ctx, from, to)
+	})
+}
+
+func (r *Repository) GetArchive(ctx context.Context) ([]article.ArchiveMonth, error) {
+	months, err := get(ctx, r, entry{key: "archive", kind: kindArchive}, r.repo.GetArchive, nil)
+	return slices.Clone(months), err
+}
+
+func (r *Repository) GetAllSummaries(ctx context.Context) (article.ArticleSummaries, error) {
+	return getSummaries(ctx, r, entry{key: "summaries:all", kind: kindAll}, r.repo.GetAllSummaries)
+}
+
+func (r *Repository) GetSummariesByTags(ctx context.Context, tags []string) (article.ArticleSummaries, error) {
+	sorted := slices.Compact(slices.Sorted(slices.Values(tags)))
+	key := entry{key: "summaries:tags:" + strings.Join(sorted, ","), kind: kindTags, tags: sorted}
+	return getSummaries(ctx, r, key, func(ctx context.Context) (article.ArticleSummaries, error) {
+		return r.repo.GetSummariesByTags(ctx, tags)
+	})
+}
+
+func (r *Repository) GetSummariesByPublicationDate(
+	ctx context.Context,
+	from, to time.Time,
+) (article.ArticleSummaries, error) {
+	key := entry{key: "summaries:published:" + rangeKey(from) + "," + rangeKey(to), kind: kindPublicationDate}
+	return getSummaries(ctx, r, key, func(ctx context.Context) (article.ArticleSummaries, error) {
+		return r.repo.GetSummariesByPublicationDate(ctx, from, to)
+	})
+}
+
+func (r *Repository) GetAllTags(ctx context.Context) ([]string, error) {
+	tags, err := get(ctx, r, entry{key: "alltags", kind: kindAllTags}, r.repo.GetAllTags, nil)
+	return slices.Clone(tags), err
+}
+
+func (r *Repository) Update(ctx context.Context, id int64, updated article.Article) (*article.Article, error) {
+	// The previous version decides which results change as well, e.g. those listing its old tags
+	previous, err := r.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	a, err := r.repo.Update(ctx, id, updated)
+	r.invalidate(previous, a)
+	return a, err
+}
+
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	previous, err := r.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = r.repo.Delete(ctx, id)
+	r.invalidate(previous, nil)
+	return err
+}
+
+func (r *Repository) SetLinks(ctx context.Context, sourceID int64, targetSlugs []string) error {
+	err := r.repo.SetLinks(ctx, sourceID, targetSlugs)
+	r.invalidateLinks(sourceID, targetSlugs)
+	return err
+}
+
+func (r *Repository) GetBacklinks(ctx context.Context, slug string) (article.Articles, error) {
+	key := entry{key: "backlinks:" + slug, kind: kindBacklinks, slug: slug}
+	return getArticles(ctx, r, key, func(ctx context.Context) (article.Articles, error) {
+		return r.repo.GetBacklinks(ctx, slug)
+	})
+}
+
+// Apply drops the cached results changed by a write published through an article.ChangeFeed, e.g. one made
+// by another instance of the blog
+func (r *Repository) Apply(change article.Change) {
+	previous := &article.Article{ID: change.ID, Slug: change.PreviousSlug, Tags: change.PreviousTags}
+	current := &article.Article{ID: change.ID, Slug: change.Slug, Tags: change.Tags}
+	switch change.Operation {
+	case article.ChangeCreated:
+		r.invalidate(nil, current)
+	case article.ChangeUpdated:
+		r.invalidate(previous, current)
+	case article.ChangeDeleted:
+		r.invalidate(previous, nil)
+	case article.ChangeLinks:
+		r.invalidateLinks(change.ID, []string{change.Slug})
+	default:
+		r.drop(func(*entry) bool { return true })
+	}
+}
+
+func getArticle(
+	ctx context.Context,
+	r *Repository,
+	key entry,
+	load func(ctx context.Context) (*article.Article, error),
+) (*article.Article, error) {
+	a, err := get(ctx, r, key, load, func(a *article.Article) []int64 { return []int64{a.ID} })
+	if err != nil {
+		return nil, err
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func getArticles(
+	ctx context.Context,
+	r *Repository,
+	key entry,
+	load func(ctx context.Context) (article.Articles, error),
+) (article.Articles, error) {
+	articles, err := get(ctx, r, key, load, func(articles article.Articles) []int64 {
+		ids := make([]int64, len(articles))
+		for i, a := range articles {
+			ids[i] = a.ID
+		}
+		return ids
+	})
+	return slices.Clone(articles), err
+}
+
+func getSummaries(
+	ctx context.Context,
+	r *Repository,
+	key entry,
+	load func(ctx context.Context) (article.ArticleSummaries, error),
+) (article.ArticleSummaries, error) {
+	summaries, err := get(ctx, r, key, load, func(summaries article.ArticleSummaries) []int64 {
+		ids := make([]int64, len(summaries))
+		for i, s := range summaries {
+			ids[i] = s.ID
+		}
+		return ids
+	})
+	return slices.Clone(summaries), err
+}
+
+// get returns the cached result for key or loads it. Concurrent misses of the same key share one load, reads from
+// the primary don't share the loads of reads that may be served by a replica. ids returns the IDs of the articles
+// in a loaded result.
+func get[T any](
+	ctx context.Context,
+	r *Repository,
+	key entry,
+	load func(ctx context.Context) (T, error),
+	ids func(T) []int64,
+) (T, error) {
+	if value, ok := r.lookup(key.key); ok {
+		r.hits.Add(1)
+		return value.(T), nil
+	}
+	r.misses.Add(1)
+
+	// Loads started before a write don't serve callers arriving after it. A replica may not have seen a recent
+	// write yet, what it serves then is returned but not cached.
+	primary := middleware.PrimaryReadsFromCtx(ctx)
+	r.mutex.Lock()
+	generation := r.generation
+	cacheable := primary || r.now().Sub(r.lastWrite) >= r.options.ReplicaLag
+	r.mutex.Unlock()
+	flight := key.key + "@" + strconv.FormatUint(generation, 10)
+	if primary {
+		flight += "@primary"
+	}
+
+	value, err, _ := r.loads.Do(flight, func() (any, error) {
+		// The load is shared, a caller going away must not fail it for the others
+		value, err := load(context.WithoutCancel(ctx))
+		if err != nil {
+			return value, err
+		}
+		if !cacheable {
+			return value, nil
+		}
+		if ids != nil {
+			key.ids = append(key.ids, ids(value)...)
+		}
+		r.store(key, value, generation)
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+func (r *Repository) lookup(key string) (any, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	element, ok := r.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if !e.expires.IsZero() && r.now().After(e.expires) {
+		r.remove(element)
+		return nil, false
+	}
+	r.lru.MoveToFront(element)
+	return e.value, true
+}
+
+// store caches value under key unless a write happened since the load started at generation
+func (r *Repository) store(key entry, value any, generation uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if generation != r.generation || r.options.Size <= 0 {
+		return
+	}
+	if element, ok := r.entries[key.key]; ok {
+		r.remove(element)
+	}
+
+	key.value = value
+	if r.options.TTL > 0 {
+		key.expires = r.now().Add(r.options.TTL)
+	}
+	r.entries[key.key] = r.lru.PushFront(&key)
+	for r.lru.Len() > r.options.Size {
+		r.remove(r.lru.Back())
+		r.evictions.Add(1)
+	}
+}
+
+func (r *Repository) remove(element *list.Element) {
+	r.lru.Remove(element)
+	delete(r.entries, element.Value.(*entry).key)
+}
+
+// invalidate drops the results changed by a write turning previous into current. previous is nil for a
+// created article, current for a deleted one.
+func (r *Repository) invalidate(previous, current *article.Article) {
+	var versions []*article.Article
+	for _, a := range []*article.Article{previous, current} {
+		if a != nil {
+			versions = append(versions, a)
+		}
+	}
+	tagsChanged := !slices.Equal(tagsOf(previous), tagsOf(current))
+
+	r.drop(func(e *entry) bool {
+		switch e.kind {
+		case kindAll, kindPublicationDate, kindArchive:
+			// Writes don't tell the publication date of the previous version
+			return true
+		case kindAllTags:
+			return tagsChanged
+		}
+		for _, a := range versions {
+			if slices.Contains(e.ids, a.ID) {
+				return true
+			}
+			switch e.kind {
+			case kindSlug, kindBacklinks:
+				// Backlinks leave out the article under the slug itself
+				if e.slug == a.Slug {
+					return true
+				}
+			case kindTags:
+				if slices.ContainsFunc(a.Tags, func(tag string) bool { return slices.Contains(e.tags, tag) }) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// invalidateLinks drops the backlinks changed by the article with sourceID linking to targetSlugs
+func (r *Repository) invalidateLinks(sourceID int64, targetSlugs []string) {
+	r.drop(func(e *entry) bool {
+		// Backlinks listing the source may lose it, the ones of the targets may gain it
+		return e.kind == kindBacklinks && (slices.Contains(e.ids, sourceID) || slices.Contains(targetSlugs, e.slug))
+	})
+}
+
+// rangeKey identifies a bound of a publication date range, the zero time being an open end
+func rangeKey(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func tagsOf(a *article.Article) []string {
+	if a == nil {
+		return nil
+	}
+	return a.Tags
+}
+
+// drop removes the cached results matching changed
+func (r *Repository) drop(changed func(e *entry) bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.generation++
+	r.lastWrite = r.now()
+	for element := r.lru.Front(); element != nil; {
+		next := element.Next()
+		if changed(element.Value.(*entry)) {
+			r.remove(element)
+		}
+		element = next
+	}
+}
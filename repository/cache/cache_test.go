@@ -0,0 +1,275 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository/cache"
+	"github.com/jannawro/blog/repository/mock"
+	"github.com/jannawro/blog/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) article.ArticleRepository {
+		return cache.NewRepository(mock.NewRepository(), cache.DefaultOptions())
+	})
+}
+
+// countingRepository counts the reads reaching the wrapped repository. GetAll waits for release if it's set.
+type countingRepository struct {
+	*mock.Repository
+	reads   atomic.Int64
+	release chan struct{}
+}
+
+func (r *countingRepository) GetAll(ctx context.Context) (article.Articles, error) {
+	r.reads.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.Repository.GetAll(ctx)
+}
+
+func (r *countingRepository) GetByTags(ctx context.Context, tags []string) (article.Articles, error) {
+	r.reads.Add(1)
+	return r.Repository.GetByTags(ctx, tags)
+}
+
+func (r *countingRepository) GetBySlug(ctx context.Context, slug string) (*article.Article, error) {
+	r.reads.Add(1)
+	return r.Repository.GetBySlug(ctx, slug)
+}
+
+func (r *countingRepository) GetArchive(ctx context.Context) ([]article.ArchiveMonth, error) {
+	r.reads.Add(1)
+	return r.Repository.GetArchive(ctx)
+}
+
+func newArticle(slug string, tags ...string) article.Article {
+	return article.Article{Title: slug, Slug: slug, Tags: tags, PublicationDate: time.Now().UTC()}
+}
+
+func TestHitsAndMisses(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: mock.NewRepository()}
+	cached := cache.NewRepository(repo, cache.DefaultOptions())
+	_, err := cached.Create(ctx, newArticle("first"))
+	require.NoError(t, err)
+
+	for range 3 {
+		a, err := cached.GetBySlug(ctx, "first")
+		require.NoError(t, err)
+		assert.Equal(t, "first", a.Slug)
+	}
+	assert.EqualValues(t, 1, repo.reads.Load())
+	assert.Equal(t, cache.Stats{Hits: 2, Misses: 1, Entries: 1}, cached.Stats())
+
+	// Missing articles aren't cached
+	for range 2 {
+		_, err = cached.GetBySlug(ctx, "missing")
+		assert.ErrorIs(t, err, article.ErrArticleNotFound)
+	}
+	assert.EqualValues(t, 3, repo.reads.Load())
+}
+
+func TestInvalidation(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: mock.NewRepository()}
+	cached := cache.NewRepository(repo, cache.DefaultOptions())
+	goArticle, err := cached.Create(ctx, newArticle("go-article", "go"))
+	require.NoError(t, err)
+	_, err = cached.Create(ctx, newArticle("web-article", "web"))
+	require.NoError(t, err)
+
+	read := func(tag string) article.Articles {
+		articles, err := cached.GetByTags(ctx, []string{tag})
+		require.NoError(t, err)
+		return articles
+	}
+	read("go")
+	read("web")
+	require.EqualValues(t, 2, repo.reads.Load())
+
+	changed := *goArticle
+	changed.Title = "Go article"
+	_, err = cached.Update(ctx, goArticle.ID, changed)
+	require.NoError(t, err)
+
+	// Only the results listing the updated article are read again
+	assert.Equal(t, "Go article", read("go")[0].Title)
+	read("web")
+	assert.EqualValues(t, 3, repo.reads.Load())
+
+	changed.Tags = []string{"web"}
+	_, err = cached.Update(ctx, goArticle.ID, changed)
+	require.NoError(t, err)
+	assert.Empty(t, read("go"))
+	assert.Len(t, read("web"), 2)
+	assert.EqualValues(t, 5, repo.reads.Load())
+
+	require.NoError(t, cached.Delete(ctx, goArticle.ID))
+	assert.Len(t, read("web"), 1)
+	_, err = cached.GetBySlug(ctx, "go-article")
+	assert.ErrorIs(t, err, article.ErrArticleNotFound)
+}
+
+func TestArchiveInvalidation(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: mock.NewRepository()}
+	cached := cache.NewRepository(repo, cache.DefaultOptions())
+	a, err := cached.Create(ctx, newArticle("first"))
+	require.NoError(t, err)
+
+	months, err := cached.GetArchive(ctx)
+	require.NoError(t, err)
+	_, err = cached.GetArchive(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, repo.reads.Load())
+	assert.Equal(t, 1, months[0].Count)
+
+	// Moving an article to another month changes the archive although nothing but the date tells
+	moved := *a
+	moved.PublicationDate = a.PublicationDate.AddDate(-1, 0, 0)
+	_, err = cached.Update(ctx, a.ID, moved)
+	require.NoError(t, err)
+
+	months, err = cached.GetArchive(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, repo.reads.Load())
+	assert.Equal(t, moved.PublicationDate.Year(), months[0].Year)
+}
+
+func TestSizeAndTTL(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: mock.NewRepository()}
+	cached := cache.NewRepository(repo, cache.Options{Size: 2, TTL: 50 * time.Millisecond})
+	for _, slug := range []string{"a", "b", "c"} {
+		_, err := cached.Create(ctx, newArticle(slug))
+		require.NoError(t, err)
+		_, err = cached.GetBySlug(ctx, slug)
+		require.NoError(t, err)
+	}
+	stats := cached.Stats()
+	assert.EqualValues(t, 1, stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+
+	// a was evicted, c is still cached
+	_, err := cached.GetBySlug(ctx, "c")
+	require.NoError(t, err)
+	_, err = cached.GetBySlug(ctx, "a")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, repo.reads.Load())
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = cached.GetBySlug(ctx, "a")
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, repo.reads.Load())
+}
+
+func TestConcurrentMissesLoadOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: mock.NewRepository(), release: make(chan struct{})}
+	cached := cache.NewRepository(repo, cache.DefaultOptions())
+
+	const readers = 8
+	var started, done sync.WaitGroup
+	for range readers {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			started.Done()
+			_, err := cached.GetAll(ctx)
+			assert.NoError(t, err)
+		}()
+	}
+	started.Wait()
+	// Give the readers time to join the load before it finishes
+	time.Sleep(20 * time.Millisecond)
+	close(repo.release)
+	done.Wait()
+
+	assert.EqualValues(t, 1, repo.reads.Load())
+	assert.EqualValues(t, readers, cached.Stats().Misses)
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: mock.NewRepository()}
+	cached := cache.NewRepository(repo, cache.DefaultOptions())
+	// Writes made by another instance reach the wrapped repository without passing the cache
+	created, err := repo.Create(ctx, newArticle("first", "go"))
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, newArticle("second", "web"))
+	require.NoError(t, err)
+
+	readAll := func() {
+		for _, tag := range []string{"go", "web"} {
+			_, err := cached.GetByTags(ctx, []string{tag})
+			require.NoError(t, err)
+		}
+	}
+	readAll()
+	require.EqualValues(t, 2, repo.reads.Load())
+
+	changed := *created
+	changed.Slug = "renamed"
+	_, err = repo.Update(ctx, created.ID, changed)
+	require.NoError(t, err)
+	cached.Apply(article.Change{
+		Operation:    article.ChangeUpdated,
+		ID:           created.ID,
+		Slug:         "renamed",
+		Tags:         []string{"go"},
+		PreviousSlug: "first",
+		PreviousTags: []string{"go"},
+	})
+	readAll()
+	assert.EqualValues(t, 3, repo.reads.Load())
+	found, err := cached.GetByTags(ctx, []string{"go"})
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", found[0].Slug)
+
+	// Missed changes drop everything
+	cached.Apply(article.Change{Operation: article.ChangeUnknown})
+	assert.Zero(t, cached.Stats().Entries)
+}
+
+func TestReplicaLag(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepository{Repository: mock.NewRepository()}
+	cached := cache.NewRepository(repo, cache.Options{Size: 16, ReplicaLag: 50 * time.Millisecond})
+	_, err := cached.Create(ctx, newArticle("first"))
+	require.NoError(t, err)
+
+	// Right after a write a replica may serve the previous version, its reads aren't cached
+	for range 2 {
+		_, err = cached.GetBySlug(ctx, "first")
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 2, repo.reads.Load())
+
+	// Reads from the primary are, and serve the reads after them
+	_, err = cached.GetBySlug(middleware.WithPrimaryReads(ctx), "first")
+	require.NoError(t, err)
+	_, err = cached.GetBySlug(ctx, "first")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, repo.reads.Load())
+
+	// Once the replicas caught up their reads are cached again
+	_, err = cached.Create(ctx, newArticle("second"))
+	require.NoError(t, err)
+	time.Sleep(60 * time.Millisecond)
+	for range 2 {
+		_, err = cached.GetBySlug(ctx, "second")
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 4, repo.reads.Load())
+}
@@ -11,4 +11,16 @@ var (
 	ErrMigrationInstanceFailed  = errors.New("failed to create a migration instance")
 	ErrMigrationRunFailed       = errors.New("migrations failed during run")
 	ErrTxRollbackFailed         = errors.New("failed to roll back transaction")
+	ErrUnknownBackend           = errors.New("unknown database backend")
+	ErrInvalidDatabaseURL       = errors.New("invalid database URL")
+	ErrMigrationsUnsupported    = errors.New("the database backend doesn't support migrations")
+	ErrReplicasUnsupported      = errors.New("the database backend doesn't support read replicas")
+	ErrOpenDeadlineExceeded     = errors.New("gave up opening the database")
+	ErrTargetNotEmpty           = errors.New("the target database isn't empty")
+	ErrCopyFailed               = errors.New("copying the database failed")
+	ErrListenFailed             = errors.New("failed to listen for database notifications")
+	ErrReadOnly                 = errors.New("the repository is read-only")
+	ErrDuplicateSlug            = errors.New("another article has the same slug")
+	ErrInvalidEvent             = errors.New("invalid article event in the outbox")
+	ErrInvalidArticle           = errors.New("invalid article stored in the database")
 )
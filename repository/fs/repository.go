@@ -0,0 +1,337 @@
+// Package fs stores articles as markdown files with headers in a directory, the format article.UnmarshalToArticle
+// reads, so the content can live in git and the blog can run without a database.
+//
+// The files hold the markdown only, the slug of an article follows from its title when the file is read again. The
+// rendered HTML and the links between articles are kept in memory and have to be rendered again after loading, see
+// article.Service.Apply.
+package fs
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/repository"
+)
+
+type Options struct {
+	// Writable lets Create, Update and Delete change the files. Otherwise only the rendered HTML can be updated.
+	Writable bool
+	// PollInterval makes the repository scan the directory for changes at this interval instead of being notified
+	// by the operating system, e.g. for network filesystems. Zero means notifications.
+	PollInterval time.Duration
+}
+
+// file is an article loaded from path. data is the content of the file as last read or written.
+type file struct {
+	article article.Article
+	path    string
+	data    []byte
+}
+
+// Repository is an article.ArticleRepository keeping the articles in a directory, one markdown file per article.
+// Files are found in subdirectories too, hidden ones are skipped. Changes made to the files by hand are picked up
+// and published to the subscribers, the repository's own writes aren't.
+//
+// Articles get their IDs when they are loaded, in the order of their publication dates, so IDs aren't stable
+// across restarts.
+type Repository struct {
+	dir     string
+	options Options
+
+	mutex  sync.RWMutex
+	files  map[int64]*file
+	slugs  map[string]int64
+	paths  map[string]int64
+	links  map[int64][]string
+	nextID int64
+
+	subscribersMutex sync.RWMutex
+	subscribers      []subscriber
+	nextSubscriberID uint64
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+type subscriber struct {
+	id uint64
+	f  func(article.Change)
+}
+
+// NewRepository loads the articles in dir and starts watching it for changes. Files that can't be read as
+// articles are skipped with a warning.
+func NewRepository(dir string, options Options) (*Repository, error) {
+	r := &Repository{
+		dir:     dir,
+		options: options,
+		files:   make(map[int64]*file),
+		slugs:   make(map[string]int64),
+		paths:   make(map[string]int64),
+		links:   make(map[int64][]string),
+		nextID:  1,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := r.load(); err != nil {
+		return nil, errors.Join(repository.ErrDatabaseConnectionFailed, err)
+	}
+
+	if options.PollInterval <= 0 {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, errors.Join(repository.ErrDatabaseConnectionFailed, err)
+		}
+		r.watcher = watcher
+		if err := r.watchDirs(); err != nil {
+			return nil, errors.Join(repository.ErrDatabaseConnectionFailed, err, watcher.Close())
+		}
+	}
+	go r.watch()
+	return r, nil
+}
+
+// Close stops watching the directory
+func (r *Repository) Close() error {
+	close(r.done)
+	<-r.stopped
+	if r.watcher != nil {
+		return r.watcher.Close()
+	}
+	return nil
+}
+
+func (r *Repository) Create(ctx context.Context, a article.Article) (*article.Article, error) {
+	if !r.options.Writable {
+		return nil, repository.ErrReadOnly
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.slugs[a.Slug]; ok {
+		return nil, errors.Join(repository.ErrDuplicateSlug, fmt.Errorf("slug %q", a.Slug))
+	}
+	path := filepath.Join(r.dir, url.PathEscape(a.Slug)+".md")
+	data := article.MarshalArticle(a)
+	if err := writeNewFile(path, data); err != nil {
+		return nil, err
+	}
+
+	a.ID = r.nextID
+	r.nextID++
+	r.add(&file{article: a, path: path, data: data})
+	return &a, nil
+}
+
+func (r *Repository) GetAll(ctx context.Context) (article.Articles, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.filter(func(article.Article) bool { return true }), nil
+}
+
+func (r *Repository) GetByID(ctx context.Context, id int64) (*article.Article, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	f, ok := r.files[id]
+	if !ok {
+		return nil, errors.Join(article.ErrArticleNotFound, fmt.Errorf("id %d", id))
+	}
+	a := f.article
+	return &a, nil
+}
+
+func (r *Repository) GetBySlug(ctx context.Context, slug string) (*article.Article, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	id, ok := r.slugs[slug]
+	if !ok {
+		return nil, errors.Join(article.ErrArticleNotFound, fmt.Errorf("slug %q", slug))
+	}
+	a := r.files[id].article
+	return &a, nil
+}
+
+func (r *Repository) GetByTags(ctx context.Context, tags []string) (article.Articles, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.filter(func(a article.Article) bool {
+		return slices.ContainsFunc(a.Tags, func(tag string) bool { return slices.Contains(tags, tag) })
+	}), nil
+}
+
+func (r *Repository) GetByPublicationDate(ctx context.Context, from, to time.Time) (article.Articles, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	articles := r.filter(func(a article.Article) bool { return a.PublishedIn(from, to) })
+	slices.SortStableFunc(articles, func(a, b article.Article) int {
+		return a.PublicationDate.Compare(b.PublicationDate)
+	})
+	return articles, nil
+}
+
+func (r *Repository) GetArchive(ctx context.Context) ([]article.ArchiveMonth, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.filter(func(article.Article) bool { return true }).Archive(), nil
+}
+
+func (r *Repository) GetAllSummaries(ctx context.Context) (article.ArticleSummaries, error) {
+	articles, err := r.GetAll(ctx)
+	return articles.Summaries(), err
+}
+
+func (r *Repository) GetSummariesByTags(ctx context.Context, tags []string) (article.ArticleSummaries, error) {
+	articles, err := r.GetByTags(ctx, tags)
+	return articles.Summaries(), err
+}
+
+func (r *Repository) GetSummariesByPublicationDate(
+	ctx context.Context,
+	from, to time.Time,
+) (article.ArticleSummaries, error) {
+	articles, err := r.GetByPublicationDate(ctx, from, to)
+	return articles.Summaries(), err
+}
+
+func (r *Repository) GetAllTags(ctx context.Context) ([]string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tags := []string{}
+	for _, f := range r.files {
+		tags = append(tags, f.article.Tags...)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags), nil
+}
+
+// Update replaces the article with id. An update changing only the rendered HTML is kept in memory and allowed
+// even if the repository isn't writable, any other change is written to the file of the article.
+func (r *Repository) Update(ctx context.Context, id int64, updated article.Article) (*article.Article, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	f, ok := r.files[id]
+	if !ok {
+		return nil, errors.Join(article.ErrArticleNotFound, fmt.Errorf("id %d", id))
+	}
+	updated.ID = id
+
+	if !f.article.SameMarkdown(updated) {
+		if !r.options.Writable {
+			return nil, repository.ErrReadOnly
+		}
+		if other, ok := r.slugs[updated.Slug]; ok && other != id {
+			return nil, errors.Join(repository.ErrDuplicateSlug, fmt.Errorf("slug %q", updated.Slug))
+		}
+		data := article.MarshalArticle(updated)
+		if err := os.WriteFile(f.path, data, 0o644); err != nil {
+			return nil, err
+		}
+		f.data = data
+	}
+
+	delete(r.slugs, f.article.Slug)
+	f.article = updated
+	r.slugs[updated.Slug] = id
+	return &updated, nil
+}
+
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	if !r.options.Writable {
+		return repository.ErrReadOnly
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	f, ok := r.files[id]
+	if !ok {
+		return errors.Join(article.ErrArticleNotFound, fmt.Errorf("id %d", id))
+	}
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	r.remove(f)
+	return nil
+}
+
+func (r *Repository) SetLinks(ctx context.Context, sourceID int64, targetSlugs []string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.files[sourceID]; !ok {
+		return nil
+	}
+	targets := slices.Clone(targetSlugs)
+	slices.Sort(targets)
+	r.links[sourceID] = slices.Compact(targets)
+	return nil
+}
+
+func (r *Repository) GetBacklinks(ctx context.Context, slug string) (article.Articles, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	backlinks := r.filter(func(a article.Article) bool {
+		return a.Slug != slug && slices.Contains(r.links[a.ID], slug)
+	})
+	slices.SortStableFunc(backlinks, func(a, b article.Article) int {
+		return b.PublicationDate.Compare(a.PublicationDate)
+	})
+	return backlinks, nil
+}
+
+// filter returns the articles matching keep in the order of their IDs. The caller holds the mutex.
+func (r *Repository) filter(keep func(article.Article) bool) article.Articles {
+	result := article.Articles{}
+	for _, f := range r.files {
+		if keep(f.article) {
+			result = append(result, f.article)
+		}
+	}
+	slices.SortFunc(result, func(a, b article.Article) int { return cmp.Compare(a.ID, b.ID) })
+	return result
+}
+
+// add indexes f. The caller holds the mutex.
+func (r *Repository) add(f *file) {
+	r.files[f.article.ID] = f
+	r.slugs[f.article.Slug] = f.article.ID
+	r.paths[f.path] = f.article.ID
+}
+
+// remove drops f from the index. The caller holds the mutex.
+func (r *Repository) remove(f *file) {
+	delete(r.files, f.article.ID)
+	delete(r.slugs, f.article.Slug)
+	delete(r.paths, f.path)
+	delete(r.links, f.article.ID)
+}
+
+// writeNewFile writes data to path, failing if the file exists already
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return errors.Join(err, f.Close())
+}
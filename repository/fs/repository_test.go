@@ -0,0 +1,155 @@
+package fs_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/repository"
+	"github.com/jannawro/blog/repository/fs"
+	"github.com/jannawro/blog/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepository(t *testing.T, dir string, options fs.Options) *fs.Repository {
+	repo, err := fs.NewRepository(dir, options)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, repo.Close())
+	})
+	return repo
+}
+
+// writeArticle writes an article file by hand, its slug follows from the title
+func writeArticle(t *testing.T, path, title, content string) {
+	t.Helper()
+	data := "title: " + title + "\n" +
+		"publicationDate: 2024-05-10\n" +
+		"tags: go\n" +
+		"===\n" + content
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+}
+
+func TestRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) article.ArticleRepository {
+		return newRepository(t, t.TempDir(), fs.Options{Writable: true})
+	})
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "2024"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o755))
+	writeArticle(t, filepath.Join(dir, "2024", "nested.md"), "Nested", "In a subdirectory.")
+	writeArticle(t, filepath.Join(dir, ".git", "hidden.md"), "Hidden", "Skipped.")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.md"), []byte("no headers"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not markdown"), 0o644))
+
+	repo := newRepository(t, dir, fs.Options{})
+
+	all, err := repo.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, "nested", all[0].Slug)
+	assert.Equal(t, "In a subdirectory.", all[0].Content)
+	assert.Equal(t, []string{"go"}, all[0].Tags)
+}
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "first.md")
+	writeArticle(t, path, "First", "Some *markdown*.")
+	repo := newRepository(t, dir, fs.Options{})
+
+	_, err := repo.Create(ctx, article.Article{Title: "New", Slug: "new"})
+	assert.ErrorIs(t, err, repository.ErrReadOnly)
+
+	stored, err := repo.GetBySlug(ctx, "first")
+	require.NoError(t, err)
+	assert.ErrorIs(t, repo.Delete(ctx, stored.ID), repository.ErrReadOnly)
+
+	changed := *stored
+	changed.Content = "Changed."
+	_, err = repo.Update(ctx, stored.ID, changed)
+	assert.ErrorIs(t, err, repository.ErrReadOnly)
+
+	// Rendering doesn't touch the file
+	rendered := *stored
+	rendered.ContentHTML = "<p>Some <em>markdown</em>.</p>"
+	_, err = repo.Update(ctx, stored.ID, rendered)
+	require.NoError(t, err)
+	fetched, err := repo.GetByID(ctx, stored.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rendered.ContentHTML, fetched.ContentHTML)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Some *markdown*.")
+}
+
+func TestWatch(t *testing.T) {
+	for name, options := range map[string]fs.Options{
+		"Notifications": {Writable: true},
+		"Polling":       {Writable: true, PollInterval: 10 * time.Millisecond},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			path := filepath.Join(dir, "first.md")
+			writeArticle(t, path, "First", "Before.")
+			repo := newRepository(t, dir, options)
+
+			changes := make(chan article.Change, 10)
+			unsubscribe := repo.Subscribe(func(change article.Change) { changes <- change })
+			defer unsubscribe()
+
+			// The repository's own writes aren't published
+			_, err := repo.Create(ctx, article.Article{Title: "Own", Slug: "own", Tags: []string{"go"}})
+			require.NoError(t, err)
+
+			writeArticle(t, path, "Renamed", "After.")
+			change := receive(t, changes)
+			assert.Equal(t, article.ChangeUpdated, change.Operation)
+			assert.Equal(t, "renamed", change.Slug)
+			assert.Equal(t, "first", change.PreviousSlug)
+			renamed, err := repo.GetBySlug(ctx, "renamed")
+			require.NoError(t, err)
+			assert.Equal(t, "After.", renamed.Content)
+			assert.Equal(t, change.ID, renamed.ID)
+
+			writeArticle(t, filepath.Join(dir, "second.md"), "Second", "New.")
+			change = receive(t, changes)
+			assert.Equal(t, article.ChangeCreated, change.Operation)
+			assert.Equal(t, "second", change.Slug)
+
+			require.NoError(t, os.Remove(path))
+			change = receive(t, changes)
+			assert.Equal(t, article.ChangeDeleted, change.Operation)
+			assert.Equal(t, "renamed", change.PreviousSlug)
+			_, err = repo.GetBySlug(ctx, "renamed")
+			assert.ErrorIs(t, err, article.ErrArticleNotFound)
+
+			select {
+			case change := <-changes:
+				t.Errorf("unexpected change %+v", change)
+			case <-time.After(200 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func receive(t *testing.T, changes <-chan article.Change) article.Change {
+	t.Helper()
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change published")
+		return article.Change{}
+	}
+}
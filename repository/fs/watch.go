@@ -0,0 +1,275 @@
+package fs
+
+import (
+	"bytes"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jannawro/blog/article"
+)
+
+// settleDelay is how long changes to the directory have to stop before it's scanned, editors tend to write a
+// file in several steps
+const settleDelay = 100 * time.Millisecond
+
+// Subscribe calls f with every change made to the files by hand, in order. Changes made through the
+// repository aren't published.
+func (r *Repository) Subscribe(f func(article.Change)) func() {
+	r.subscribersMutex.Lock()
+	defer r.subscribersMutex.Unlock()
+
+	id := r.nextSubscriberID
+	r.nextSubscriberID++
+	r.subscribers = append(r.subscribers, subscriber{id: id, f: f})
+	return func() {
+		r.subscribersMutex.Lock()
+		defer r.subscribersMutex.Unlock()
+		r.subscribers = slices.DeleteFunc(r.subscribers, func(s subscriber) bool { return s.id == id })
+	}
+}
+
+func (r *Repository) publish(change article.Change) {
+	r.subscribersMutex.RLock()
+	defer r.subscribersMutex.RUnlock()
+
+	for _, s := range r.subscribers {
+		s.f(change)
+	}
+}
+
+// load indexes the articles in the directory, numbered in the order of their publication dates
+func (r *Repository) load() error {
+	contents, err := readFiles(r.dir)
+	if err != nil {
+		return err
+	}
+
+	var loaded []*file
+	for _, path := range slices.Sorted(maps.Keys(contents)) {
+		a, err := parse(contents[path])
+		if err != nil {
+			slog.Warn("Skipping a file that isn't an article", "path", path, "error", err)
+			continue
+		}
+		loaded = append(loaded, &file{article: a, path: path, data: contents[path]})
+	}
+	slices.SortStableFunc(loaded, func(a, b *file) int {
+		return a.article.PublicationDate.Compare(b.article.PublicationDate)
+	})
+
+	for _, f := range loaded {
+		if _, ok := r.slugs[f.article.Slug]; ok {
+			slog.Warn("Skipping an article with a duplicate slug", "path", f.path, "slug", f.article.Slug)
+			continue
+		}
+		f.article.ID = r.nextID
+		r.nextID++
+		r.add(f)
+	}
+	return nil
+}
+
+// watch scans the directory whenever it changes, or at the poll interval, until the repository is closed
+func (r *Repository) watch() {
+	defer close(r.stopped)
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	var poll <-chan time.Time
+	if r.watcher != nil {
+		events, errs = r.watcher.Events, r.watcher.Errors
+	} else {
+		ticker := time.NewTicker(r.options.PollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-r.done:
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			if settled == nil {
+				settled = time.After(settleDelay)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+			slog.Warn("Watching the articles directory failed", "dir", r.dir, "error", err)
+		case <-settled:
+			settled = nil
+			// New subdirectories have to be watched as well
+			if err := r.watchDirs(); err != nil {
+				slog.Warn("Watching the articles directory failed", "dir", r.dir, "error", err)
+			}
+			r.rescan()
+		case <-poll:
+			r.rescan()
+		}
+	}
+}
+
+// rescan brings the index up to date with the directory and publishes what changed
+func (r *Repository) rescan() {
+	contents, err := readFiles(r.dir)
+	if err != nil {
+		slog.Warn("Reading the articles directory failed", "dir", r.dir, "error", err)
+		return
+	}
+
+	r.mutex.Lock()
+	changes := r.apply(contents)
+	r.mutex.Unlock()
+
+	for _, change := range changes {
+		r.publish(change)
+	}
+}
+
+// apply updates the index to the files in contents, keyed by their paths, and returns the changes. The caller
+// holds the mutex.
+func (r *Repository) apply(contents map[string][]byte) []article.Change {
+	var changes []article.Change
+
+	// Removed files go first, a renamed file comes back under its new path with the same slug
+	for _, path := range slices.Sorted(maps.Keys(r.paths)) {
+		if _, ok := contents[path]; ok {
+			continue
+		}
+		f := r.files[r.paths[path]]
+		r.remove(f)
+		changes = append(changes, article.Change{
+			Operation:    article.ChangeDeleted,
+			ID:           f.article.ID,
+			PreviousSlug: f.article.Slug,
+			PreviousTags: f.article.Tags,
+		})
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(contents)) {
+		data := contents[path]
+		id, known := r.paths[path]
+		if known && bytes.Equal(r.files[id].data, data) {
+			continue
+		}
+
+		a, err := parse(data)
+		if err != nil {
+			// The file may be half written, the next change to it will tell
+			slog.Warn("Skipping a file that isn't an article", "path", path, "error", err)
+			continue
+		}
+		if other, ok := r.slugs[a.Slug]; ok && other != id {
+			slog.Warn("Skipping an article with a duplicate slug", "path", path, "slug", a.Slug)
+			continue
+		}
+
+		if !known {
+			a.ID = r.nextID
+			r.nextID++
+			r.add(&file{article: a, path: path, data: data})
+			changes = append(changes, article.Change{
+				Operation: article.ChangeCreated,
+				ID:        a.ID,
+				Slug:      a.Slug,
+				Tags:      a.Tags,
+			})
+			continue
+		}
+
+		f := r.files[id]
+		f.data = data
+		if f.article.SameMarkdown(a) {
+			continue
+		}
+		previous := f.article
+		a.ID = id
+		// The previous HTML is served until the article is rendered again
+		a.ContentHTML, a.ThumbnailHTML = previous.ContentHTML, previous.ThumbnailHTML
+		delete(r.slugs, previous.Slug)
+		f.article = a
+		r.slugs[a.Slug] = id
+		changes = append(changes, article.Change{
+			Operation:    article.ChangeUpdated,
+			ID:           id,
+			Slug:         a.Slug,
+			Tags:         a.Tags,
+			PreviousSlug: previous.Slug,
+			PreviousTags: previous.Tags,
+		})
+	}
+	return changes
+}
+
+// watchDirs watches the directory and its subdirectories
+func (r *Repository) watchDirs() error {
+	return filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if hidden(r.dir, path, d) {
+			return filepath.SkipDir
+		}
+		return r.watcher.Add(path)
+	})
+}
+
+// readFiles returns the contents of the markdown files in dir and its subdirectories, keyed by their paths
+func readFiles(dir string) (map[string][]byte, error) {
+	contents := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if hidden(dir, path, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		contents[path] = data
+		return nil
+	})
+	return contents, err
+}
+
+// hidden reports whether path below dir is hidden, like .git
+func hidden(dir, path string, d fs.DirEntry) bool {
+	return path != dir && strings.HasPrefix(d.Name(), ".")
+}
+
+func parse(data []byte) (article.Article, error) {
+	var a article.Article
+	if err := article.UnmarshalToArticle(data, &a); err != nil {
+		return a, err
+	}
+	// An empty tags header reads as a single empty tag
+	a.Tags = slices.DeleteFunc(a.Tags, func(tag string) bool { return tag == "" })
+	if len(a.Tags) == 0 {
+		a.Tags = nil
+	}
+	return a, nil
+}
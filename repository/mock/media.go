@@ -0,0 +1,122 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jannawro/blog/media"
+)
+
+type MediaRepository struct {
+	media    map[string]media.Media
+	variants map[string]media.Variant
+	mutex    sync.RWMutex
+	nextID   int64
+}
+
+func NewMediaRepository() *MediaRepository {
+	return &MediaRepository{
+		media:    make(map[string]media.Media),
+		variants: make(map[string]media.Variant),
+		nextID:   1,
+	}
+}
+
+func (r *MediaRepository) Create(ctx context.Context, m media.Media) (*media.Media, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.media[m.Hash]; ok {
+		return nil, errors.New("media already exists")
+	}
+
+	m.ID = r.nextID
+	m.CreatedAt = time.Now().UTC()
+	r.media[m.Hash] = m
+	r.nextID++
+
+	return &m, nil
+}
+
+func (r *MediaRepository) GetByHash(ctx context.Context, hash string) (*media.Media, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if m, ok := r.media[hash]; ok {
+		return &m, nil
+	}
+	return nil, media.ErrMediaNotFound
+}
+
+func (r *MediaRepository) GetAll(ctx context.Context) ([]media.Media, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]media.Media, 0, len(r.media))
+	for _, m := range r.media {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+	return result, nil
+}
+
+func (r *MediaRepository) UpdateDimensions(ctx context.Context, id int64, width, height int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for hash, m := range r.media {
+		if m.ID == id {
+			m.Width, m.Height = width, height
+			r.media[hash] = m
+			return nil
+		}
+	}
+	return errors.New("media not found")
+}
+
+func (r *MediaRepository) CreateVariant(ctx context.Context, v media.Variant) (*media.Variant, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.variants[v.Hash]; ok {
+		return nil, errors.New("variant already exists")
+	}
+
+	v.ID = r.nextID
+	v.CreatedAt = time.Now().UTC()
+	r.variants[v.Hash] = v
+	r.nextID++
+
+	return &v, nil
+}
+
+func (r *MediaRepository) GetVariantByHash(ctx context.Context, hash string) (*media.Variant, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if v, ok := r.variants[hash]; ok {
+		return &v, nil
+	}
+	return nil, errors.Join(media.ErrMediaNotFound, errors.New("variant not found"))
+}
+
+func (r *MediaRepository) GetVariants(ctx context.Context, mediaID int64) ([]media.Variant, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := []media.Variant{}
+	for _, v := range r.variants {
+		if v.MediaID == mediaID {
+			result = append(result, v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Width < result[j].Width
+	})
+	return result, nil
+}
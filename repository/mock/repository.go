@@ -1,15 +1,22 @@
 package mock
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/repository"
 )
 
 type Repository struct {
 	articles map[int64]article.Article
+	links    map[int64][]string
 	mutex    sync.RWMutex
 	nextID   int64
 }
@@ -17,6 +24,7 @@ type Repository struct {
 func NewRepository() *Repository {
 	return &Repository{
 		articles: make(map[int64]article.Article),
+		links:    make(map[int64][]string),
 		nextID:   1,
 	}
 }
@@ -25,6 +33,9 @@ func (r *Repository) Create(ctx context.Context, article article.Article) (*arti
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	if err := r.checkSlug(article.Slug, 0); err != nil {
+		return nil, err
+	}
 	article.ID = r.nextID
 	r.articles[article.ID] = article
 	r.nextID++
@@ -32,6 +43,23 @@ func (r *Repository) Create(ctx context.Context, article article.Article) (*arti
 	return &article, nil
 }
 
+// CreateWithID creates article under article.ID instead of the next free ID
+func (r *Repository) CreateWithID(ctx context.Context, article article.Article) (*article.Article, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.articles[article.ID]; ok {
+		return nil, fmt.Errorf("article with ID %d already exists", article.ID)
+	}
+	if err := r.checkSlug(article.Slug, 0); err != nil {
+		return nil, err
+	}
+	r.articles[article.ID] = article
+	r.nextID = max(r.nextID, article.ID+1)
+
+	return &article, nil
+}
+
 func (r *Repository) GetAll(ctx context.Context) (article.Articles, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
@@ -40,6 +68,7 @@ func (r *Repository) GetAll(ctx context.Context) (article.Articles, error) {
 	for _, article := range r.articles {
 		result = append(result, article)
 	}
+	sortByID(result)
 	return result, nil
 }
 
@@ -50,7 +79,7 @@ func (r *Repository) GetByID(ctx context.Context, id int64) (*article.Article, e
 	if article, ok := r.articles[id]; ok {
 		return &article, nil
 	}
-	return nil, errors.New("article not found")
+	return nil, article.ErrArticleNotFound
 }
 
 func (r *Repository) GetBySlug(ctx context.Context, slug string) (*article.Article, error) {
@@ -62,7 +91,7 @@ func (r *Repository) GetBySlug(ctx context.Context, slug string) (*article.Artic
 			return &article, nil
 		}
 	}
-	return nil, errors.New("article not found")
+	return nil, article.ErrArticleNotFound
 }
 
 func (r *Repository) GetByTags(ctx context.Context, tags []string) (article.Articles, error) {
@@ -71,19 +100,62 @@ func (r *Repository) GetByTags(ctx context.Context, tags []string) (article.Arti
 
 	result := make(article.Articles, 0)
 	for _, article := range r.articles {
-		if containsAllTags(article.Tags, tags) {
+		if containsAnyTag(article.Tags, tags) {
+			result = append(result, article)
+		}
+	}
+	sortByID(result)
+	return result, nil
+}
+
+func (r *Repository) GetByPublicationDate(ctx context.Context, from, to time.Time) (article.Articles, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make(article.Articles, 0)
+	for _, article := range r.articles {
+		if article.PublishedIn(from, to) {
 			result = append(result, article)
 		}
 	}
+	sortByPublicationDate(result)
 	return result, nil
 }
 
+func (r *Repository) GetArchive(ctx context.Context) ([]article.ArchiveMonth, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return article.Articles(slices.Collect(maps.Values(r.articles))).Archive(), nil
+}
+
+func (r *Repository) GetAllSummaries(ctx context.Context) (article.ArticleSummaries, error) {
+	articles, err := r.GetAll(ctx)
+	return articles.Summaries(), err
+}
+
+func (r *Repository) GetSummariesByTags(ctx context.Context, tags []string) (article.ArticleSummaries, error) {
+	articles, err := r.GetByTags(ctx, tags)
+	return articles.Summaries(), err
+}
+
+func (r *Repository) GetSummariesByPublicationDate(
+	ctx context.Context,
+	from, to time.Time,
+) (article.ArticleSummaries, error) {
+	articles, err := r.GetByPublicationDate(ctx, from, to)
+	return articles.Summaries(), err
+}
+
 func (r *Repository) Update(ctx context.Context, id int64, updated article.Article) (*article.Article, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if _, ok := r.articles[id]; !ok {
-		return nil, errors.New("article not found")
+		return nil, article.ErrArticleNotFound
+	}
+	if err := r.checkSlug(updated.Slug, id); err != nil {
+		return nil, err
 	}
 
 	updated.ID = id
@@ -96,10 +168,51 @@ func (r *Repository) Delete(ctx context.Context, id int64) error {
 	defer r.mutex.Unlock()
 
 	if _, ok := r.articles[id]; !ok {
-		return errors.New("article not found")
+		return article.ErrArticleNotFound
 	}
 
 	delete(r.articles, id)
+	delete(r.links, id)
+	return nil
+}
+
+func (r *Repository) SetLinks(ctx context.Context, sourceID int64, targetSlugs []string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.articles[sourceID]; !ok {
+		return article.ErrArticleNotFound
+	}
+
+	r.links[sourceID] = slices.Compact(slices.Sorted(slices.Values(targetSlugs)))
+	return nil
+}
+
+func (r *Repository) GetBacklinks(ctx context.Context, slug string) (article.Articles, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make(article.Articles, 0)
+	for id, targets := range r.links {
+		source := r.articles[id]
+		if source.Slug != slug && slices.Contains(targets, slug) {
+			result = append(result, source)
+		}
+	}
+	sortByID(result)
+	slices.SortStableFunc(result, func(a, b article.Article) int {
+		return b.PublicationDate.Compare(a.PublicationDate)
+	})
+	return result, nil
+}
+
+// checkSlug returns repository.ErrDuplicateSlug if an article other than the one with id has slug
+func (r *Repository) checkSlug(slug string, id int64) error {
+	for _, a := range r.articles {
+		if a.Slug == slug && a.ID != id {
+			return errors.Join(repository.ErrDuplicateSlug, fmt.Errorf("slug %q", slug))
+		}
+	}
 	return nil
 }
 
@@ -108,6 +221,7 @@ func (r *Repository) SetArticles(setArticles []article.Article) {
 	defer r.mutex.Unlock()
 
 	r.articles = make(map[int64]article.Article)
+	r.links = make(map[int64][]string)
 	for _, article := range setArticles {
 		r.articles[article.ID] = article
 		if article.ID >= r.nextID {
@@ -121,6 +235,7 @@ func (r *Repository) Reset() {
 	defer r.mutex.Unlock()
 
 	r.articles = make(map[int64]article.Article)
+	r.links = make(map[int64][]string)
 	r.nextID = 1
 }
 
@@ -139,19 +254,27 @@ func (r *Repository) GetAllTags(ctx context.Context) ([]string, error) {
 	for tag := range tagSet {
 		tags = append(tags, tag)
 	}
+	slices.Sort(tags)
 	return tags, nil
 }
 
-func containsAllTags(articleTags, searchTags []string) bool {
-	tagSet := make(map[string]struct{})
-	for _, tag := range articleTags {
-		tagSet[tag] = struct{}{}
-	}
-
+func containsAnyTag(articleTags, searchTags []string) bool {
 	for _, tag := range searchTags {
-		if _, ok := tagSet[tag]; !ok {
-			return false
+		if slices.Contains(articleTags, tag) {
+			return true
 		}
 	}
-	return true
+	return false
+}
+
+func sortByID(articles article.Articles) {
+	slices.SortFunc(articles, func(a, b article.Article) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+func sortByPublicationDate(articles article.Articles) {
+	slices.SortFunc(articles, func(a, b article.Article) int {
+		return cmp.Or(a.PublicationDate.Compare(b.PublicationDate), cmp.Compare(a.ID, b.ID))
+	})
 }
@@ -0,0 +1,15 @@
+package mock_test
+
+import (
+	"testing"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/repository/mock"
+	"github.com/jannawro/blog/repository/repotest"
+)
+
+func TestRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) article.ArticleRepository {
+		return mock.NewRepository()
+	})
+}
@@ -0,0 +1,165 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/repository"
+)
+
+// MediaRepository stores media metadata. The media table is created by the migrations run in NewRepository.
+type MediaRepository struct {
+	q            *Queries
+	queryTimeout time.Duration
+}
+
+// NewMediaRepository creates a media repository, only the QueryTimeout of options applies to it.
+func NewMediaRepository(db *sql.DB, options repository.Options) *MediaRepository {
+	return &MediaRepository{q: New(db), queryTimeout: options.QueryTimeout}
+}
+
+func (r *MediaRepository) Create(ctx context.Context, m media.Media) (*media.Media, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.q.CreateMedia(ctx, CreateMediaParams{
+		Hash:        m.Hash,
+		Name:        m.Name,
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		Width:       int32(m.Width),
+		Height:      int32(m.Height),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByHash(ctx, m.Hash)
+}
+
+func (r *MediaRepository) GetByHash(ctx context.Context, hash string) (*media.Media, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbMedia, err := r.q.GetMediaByHash(ctx, hash)
+	if err != nil {
+		return nil, mediaNotFound(err)
+	}
+
+	return toMedia(dbMedia), nil
+}
+
+func (r *MediaRepository) GetAll(ctx context.Context) ([]media.Media, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbMedia, err := r.q.GetAllMedia(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaSlice := make([]media.Media, len(dbMedia))
+	for i, m := range dbMedia {
+		mediaSlice[i] = *toMedia(m)
+	}
+
+	return mediaSlice, nil
+}
+
+func (r *MediaRepository) UpdateDimensions(ctx context.Context, id int64, width, height int) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.q.UpdateMediaDimensions(ctx, UpdateMediaDimensionsParams{
+		Width:  int32(width),
+		Height: int32(height),
+		ID:     id,
+	})
+}
+
+func (r *MediaRepository) CreateVariant(ctx context.Context, v media.Variant) (*media.Variant, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.q.CreateMediaVariant(ctx, CreateMediaVariantParams{
+		MediaID:     v.MediaID,
+		Hash:        v.Hash,
+		Name:        v.Name,
+		ContentType: v.ContentType,
+		Size:        v.Size,
+		Width:       int32(v.Width),
+		Height:      int32(v.Height),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetVariantByHash(ctx, v.Hash)
+}
+
+func (r *MediaRepository) GetVariantByHash(ctx context.Context, hash string) (*media.Variant, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbVariant, err := r.q.GetMediaVariantByHash(ctx, hash)
+	if err != nil {
+		return nil, mediaNotFound(err)
+	}
+
+	return toVariant(dbVariant), nil
+}
+
+func (r *MediaRepository) GetVariants(ctx context.Context, mediaID int64) ([]media.Variant, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbVariants, err := r.q.GetMediaVariantsByMediaID(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make([]media.Variant, len(dbVariants))
+	for i, v := range dbVariants {
+		variants[i] = *toVariant(v)
+	}
+
+	return variants, nil
+}
+
+func toMedia(m Medium) *media.Media {
+	return &media.Media{
+		ID:          m.ID,
+		Hash:        m.Hash,
+		Name:        m.Name,
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		Width:       int(m.Width),
+		Height:      int(m.Height),
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+func toVariant(v MediaVariant) *media.Variant {
+	return &media.Variant{
+		ID:          v.ID,
+		MediaID:     v.MediaID,
+		Hash:        v.Hash,
+		Name:        v.Name,
+		ContentType: v.ContentType,
+		Size:        v.Size,
+		Width:       int(v.Width),
+		Height:      int(v.Height),
+		CreatedAt:   v.CreatedAt,
+	}
+}
+
+// mediaNotFound marks a missing row as missing media so callers can tell it apart from other failures.
+func mediaNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.Join(media.ErrMediaNotFound, err)
+	}
+	return err
+}
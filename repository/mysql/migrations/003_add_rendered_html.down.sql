@@ -0,0 +1,2 @@
+ALTER TABLE articles DROP COLUMN thumbnail_html;
+ALTER TABLE articles DROP COLUMN content_html;
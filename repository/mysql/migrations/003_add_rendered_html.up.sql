@@ -0,0 +1,2 @@
+ALTER TABLE articles ADD COLUMN content_html MEDIUMTEXT NOT NULL;
+ALTER TABLE articles ADD COLUMN thumbnail_html TEXT NOT NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE media (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    hash CHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
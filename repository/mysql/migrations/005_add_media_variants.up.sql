@@ -0,0 +1,15 @@
+ALTER TABLE media ADD COLUMN width INT NOT NULL DEFAULT 0;
+ALTER TABLE media ADD COLUMN height INT NOT NULL DEFAULT 0;
+
+CREATE TABLE media_variants (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    media_id BIGINT UNSIGNED NOT NULL,
+    hash CHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (media_id) REFERENCES media (id) ON DELETE CASCADE
+);
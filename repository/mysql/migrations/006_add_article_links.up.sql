@@ -0,0 +1,8 @@
+CREATE TABLE article_links (
+    source_id BIGINT UNSIGNED NOT NULL,
+    target_slug VARCHAR(255) NOT NULL,
+    PRIMARY KEY (source_id, target_slug),
+    FOREIGN KEY (source_id) REFERENCES articles (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_article_links_target_slug ON article_links (target_slug);
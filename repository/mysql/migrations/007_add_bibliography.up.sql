@@ -0,0 +1 @@
+ALTER TABLE articles ADD COLUMN bibliography JSON NOT NULL DEFAULT (JSON_ARRAY());
@@ -0,0 +1,15 @@
+-- The outbox of article changes, written in the same transaction as the change and delivered by outbox.Dispatcher
+CREATE TABLE article_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    operation VARCHAR(32) NOT NULL,
+    article_id BIGINT UNSIGNED NOT NULL,
+    payload JSON NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT (''),
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_article_events_status ON article_events (status, next_attempt_at);
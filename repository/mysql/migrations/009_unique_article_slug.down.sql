@@ -0,0 +1 @@
+DROP INDEX idx_articles_slug ON articles;
@@ -14,10 +14,49 @@ type Article struct {
 	ID              int64
 	Title           string
 	Thumbnail       string
+	ThumbnailHtml   string
 	Slug            string
 	Content         string
+	ContentHtml     string
 	Tags            json.RawMessage
+	Bibliography    json.RawMessage
 	PublicationDate time.Time
 	CreatedAt       sql.NullTime
 	UpdatedAt       sql.NullTime
 }
+
+type ArticleEvent struct {
+	ID            int64
+	Operation     string
+	ArticleID     int64
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int32
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	DeliveredAt   sql.NullTime
+}
+
+type MediaVariant struct {
+	ID          int64
+	MediaID     int64
+	Hash        string
+	Name        string
+	ContentType string
+	Size        int64
+	Width       int32
+	Height      int32
+	CreatedAt   time.Time
+}
+
+type Medium struct {
+	ID          int64
+	Hash        string
+	Name        string
+	ContentType string
+	Size        int64
+	Width       int32
+	Height      int32
+	CreatedAt   time.Time
+}
@@ -13,16 +13,19 @@ import (
 )
 
 const createArticle = `-- name: CreateArticle :execresult
-INSERT INTO articles (title, thumbnail, slug, content, tags, publication_date)
-VALUES (?, ?, ?, ?, ?, ?)
+INSERT INTO articles (title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 type CreateArticleParams struct {
 	Title           string
 	Thumbnail       string
+	ThumbnailHtml   string
 	Slug            string
 	Content         string
+	ContentHtml     string
 	Tags            json.RawMessage
+	Bibliography    json.RawMessage
 	PublicationDate time.Time
 }
 
@@ -30,11 +33,131 @@ func (q *Queries) CreateArticle(ctx context.Context, arg CreateArticleParams) (s
 	return q.db.ExecContext(ctx, createArticle,
 		arg.Title,
 		arg.Thumbnail,
+		arg.ThumbnailHtml,
+		arg.Slug,
+		arg.Content,
+		arg.ContentHtml,
+		arg.Tags,
+		arg.Bibliography,
+		arg.PublicationDate,
+	)
+}
+
+const createArticleEvent = `-- name: CreateArticleEvent :exec
+INSERT INTO article_events (operation, article_id, payload)
+VALUES (?, ?, ?)
+`
+
+type CreateArticleEventParams struct {
+	Operation string
+	ArticleID int64
+	Payload   json.RawMessage
+}
+
+func (q *Queries) CreateArticleEvent(ctx context.Context, arg CreateArticleEventParams) error {
+	_, err := q.db.ExecContext(ctx, createArticleEvent, arg.Operation, arg.ArticleID, arg.Payload)
+	return err
+}
+
+const createArticleLink = `-- name: CreateArticleLink :exec
+INSERT INTO article_links (source_id, target_slug)
+VALUES (?, ?)
+`
+
+type CreateArticleLinkParams struct {
+	SourceID   int64
+	TargetSlug string
+}
+
+func (q *Queries) CreateArticleLink(ctx context.Context, arg CreateArticleLinkParams) error {
+	_, err := q.db.ExecContext(ctx, createArticleLink, arg.SourceID, arg.TargetSlug)
+	return err
+}
+
+const createArticleWithID = `-- name: CreateArticleWithID :exec
+INSERT INTO articles (id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateArticleWithIDParams struct {
+	ID              int64
+	Title           string
+	Thumbnail       string
+	ThumbnailHtml   string
+	Slug            string
+	Content         string
+	ContentHtml     string
+	Tags            json.RawMessage
+	Bibliography    json.RawMessage
+	PublicationDate time.Time
+}
+
+func (q *Queries) CreateArticleWithID(ctx context.Context, arg CreateArticleWithIDParams) error {
+	_, err := q.db.ExecContext(ctx, createArticleWithID,
+		arg.ID,
+		arg.Title,
+		arg.Thumbnail,
+		arg.ThumbnailHtml,
 		arg.Slug,
 		arg.Content,
+		arg.ContentHtml,
 		arg.Tags,
+		arg.Bibliography,
 		arg.PublicationDate,
 	)
+	return err
+}
+
+const createMedia = `-- name: CreateMedia :execresult
+INSERT INTO media (hash, name, content_type, size, width, height)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type CreateMediaParams struct {
+	Hash        string
+	Name        string
+	ContentType string
+	Size        int64
+	Width       int32
+	Height      int32
+}
+
+func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, createMedia,
+		arg.Hash,
+		arg.Name,
+		arg.ContentType,
+		arg.Size,
+		arg.Width,
+		arg.Height,
+	)
+}
+
+const createMediaVariant = `-- name: CreateMediaVariant :execresult
+INSERT INTO media_variants (media_id, hash, name, content_type, size, width, height)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateMediaVariantParams struct {
+	MediaID     int64
+	Hash        string
+	Name        string
+	ContentType string
+	Size        int64
+	Width       int32
+	Height      int32
+}
+
+func (q *Queries) CreateMediaVariant(ctx context.Context, arg CreateMediaVariantParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, createMediaVariant,
+		arg.MediaID,
+		arg.Hash,
+		arg.Name,
+		arg.ContentType,
+		arg.Size,
+		arg.Width,
+		arg.Height,
+	)
 }
 
 const deleteArticleByID = `-- name: DeleteArticleByID :execrows
@@ -50,8 +173,79 @@ func (q *Queries) DeleteArticleByID(ctx context.Context, id int64) (int64, error
 	return result.RowsAffected()
 }
 
+const deleteArticleLinks = `-- name: DeleteArticleLinks :exec
+DELETE FROM article_links
+WHERE source_id = ?
+`
+
+func (q *Queries) DeleteArticleLinks(ctx context.Context, sourceID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticleLinks, sourceID)
+	return err
+}
+
+const deleteDeliveredArticleEvents = `-- name: DeleteDeliveredArticleEvents :execrows
+DELETE FROM article_events
+WHERE status = 'delivered'
+  AND delivered_at < CURRENT_TIMESTAMP - INTERVAL ? SECOND
+`
+
+func (q *Queries) DeleteDeliveredArticleEvents(ctx context.Context, ageSeconds interface{}) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDeliveredArticleEvents, ageSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllArticleSummaries = `-- name: GetAllArticleSummaries :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+ORDER BY id ASC
+`
+
+type GetAllArticleSummariesRow struct {
+	ID              int64
+	Title           string
+	Thumbnail       string
+	ThumbnailHtml   string
+	Slug            string
+	Tags            json.RawMessage
+	PublicationDate time.Time
+}
+
+func (q *Queries) GetAllArticleSummaries(ctx context.Context) ([]GetAllArticleSummariesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllArticleSummaries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllArticleSummariesRow
+	for rows.Next() {
+		var i GetAllArticleSummariesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			&i.Tags,
+			&i.PublicationDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllArticles = `-- name: GetAllArticles :many
-SELECT id, title, thumbnail, slug, content, tags, publication_date, created_at, updated_at FROM articles
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
+ORDER BY id ASC
 `
 
 func (q *Queries) GetAllArticles(ctx context.Context) ([]Article, error) {
@@ -67,9 +261,12 @@ func (q *Queries) GetAllArticles(ctx context.Context) ([]Article, error) {
 			&i.ID,
 			&i.Title,
 			&i.Thumbnail,
+			&i.ThumbnailHtml,
 			&i.Slug,
 			&i.Content,
+			&i.ContentHtml,
 			&i.Tags,
+			&i.Bibliography,
 			&i.PublicationDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
@@ -87,14 +284,47 @@ func (q *Queries) GetAllArticles(ctx context.Context) ([]Article, error) {
 	return items, nil
 }
 
+const getAllMedia = `-- name: GetAllMedia :many
+SELECT id, hash, name, content_type, size, width, height, created_at FROM media
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetAllMedia(ctx context.Context) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, getAllMedia)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Hash,
+			&i.Name,
+			&i.ContentType,
+			&i.Size,
+			&i.Width,
+			&i.Height,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllTags = `-- name: GetAllTags :many
-SELECT DISTINCT SUBSTRING_INDEX(SUBSTRING_INDEX(tags, ',', numbers.n), ',', -1) AS unique_tag
-FROM articles
-CROSS JOIN (
-    SELECT 1 AS n UNION ALL SELECT 2 UNION ALL SELECT 3 UNION ALL SELECT 4
-) numbers
-WHERE tags IS NOT NULL
-  AND CHAR_LENGTH(tags) - CHAR_LENGTH(REPLACE(tags, ',', '')) >= numbers.n - 1
+SELECT DISTINCT tag.value AS unique_tag
+FROM articles,
+    JSON_TABLE(articles.tags, '$[*]' COLUMNS (value VARCHAR(255) PATH '$')) AS tag
 ORDER BY unique_tag ASC
 `
 
@@ -121,8 +351,90 @@ func (q *Queries) GetAllTags(ctx context.Context) ([]string, error) {
 	return items, nil
 }
 
+const getArticleArchive = `-- name: GetArticleArchive :many
+SELECT CAST(YEAR(publication_date) AS SIGNED) AS year,
+       CAST(MONTH(publication_date) AS SIGNED) AS month,
+       COUNT(*) AS article_count
+FROM articles
+GROUP BY year, month
+ORDER BY year DESC, month DESC
+`
+
+type GetArticleArchiveRow struct {
+	Year         int64
+	Month        int64
+	ArticleCount int64
+}
+
+func (q *Queries) GetArticleArchive(ctx context.Context) ([]GetArticleArchiveRow, error) {
+	rows, err := q.db.QueryContext(ctx, getArticleArchive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetArticleArchiveRow
+	for rows.Next() {
+		var i GetArticleArchiveRow
+		if err := rows.Scan(&i.Year, &i.Month, &i.ArticleCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArticleBacklinks = `-- name: GetArticleBacklinks :many
+SELECT articles.id, articles.title, articles.thumbnail, articles.thumbnail_html, articles.slug, articles.content, articles.content_html, articles.tags, articles.bibliography, articles.publication_date, articles.created_at, articles.updated_at FROM articles
+JOIN article_links ON article_links.source_id = articles.id
+WHERE article_links.target_slug = ?
+  AND articles.slug != article_links.target_slug
+ORDER BY articles.publication_date DESC, articles.id
+`
+
+func (q *Queries) GetArticleBacklinks(ctx context.Context, targetSlug string) ([]Article, error) {
+	rows, err := q.db.QueryContext(ctx, getArticleBacklinks, targetSlug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Article
+	for rows.Next() {
+		var i Article
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			&i.Content,
+			&i.ContentHtml,
+			&i.Tags,
+			&i.Bibliography,
+			&i.PublicationDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getArticleByID = `-- name: GetArticleByID :one
-SELECT id, title, thumbnail, slug, content, tags, publication_date, created_at, updated_at FROM articles
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
 WHERE id = ? LIMIT 1
 `
 
@@ -133,9 +445,12 @@ func (q *Queries) GetArticleByID(ctx context.Context, id int64) (Article, error)
 		&i.ID,
 		&i.Title,
 		&i.Thumbnail,
+		&i.ThumbnailHtml,
 		&i.Slug,
 		&i.Content,
+		&i.ContentHtml,
 		&i.Tags,
+		&i.Bibliography,
 		&i.PublicationDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
@@ -144,7 +459,7 @@ func (q *Queries) GetArticleByID(ctx context.Context, id int64) (Article, error)
 }
 
 const getArticleBySlug = `-- name: GetArticleBySlug :one
-SELECT id, title, thumbnail, slug, content, tags, publication_date, created_at, updated_at FROM articles
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
 WHERE slug = ? LIMIT 1
 `
 
@@ -155,9 +470,12 @@ func (q *Queries) GetArticleBySlug(ctx context.Context, slug string) (Article, e
 		&i.ID,
 		&i.Title,
 		&i.Thumbnail,
+		&i.ThumbnailHtml,
 		&i.Slug,
 		&i.Content,
+		&i.ContentHtml,
 		&i.Tags,
+		&i.Bibliography,
 		&i.PublicationDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
@@ -165,9 +483,168 @@ func (q *Queries) GetArticleBySlug(ctx context.Context, slug string) (Article, e
 	return i, err
 }
 
+const getArticleSummariesByPublicationDate = `-- name: GetArticleSummariesByPublicationDate :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE (publication_date >= ? OR ? IS NULL)
+  AND (publication_date < ? OR ? IS NULL)
+ORDER BY publication_date ASC, id ASC
+`
+
+type GetArticleSummariesByPublicationDateParams struct {
+	FromDate sql.NullTime
+	ToDate   sql.NullTime
+}
+
+type GetArticleSummariesByPublicationDateRow struct {
+	ID              int64
+	Title           string
+	Thumbnail       string
+	ThumbnailHtml   string
+	Slug            string
+	Tags            json.RawMessage
+	PublicationDate time.Time
+}
+
+func (q *Queries) GetArticleSummariesByPublicationDate(ctx context.Context, arg GetArticleSummariesByPublicationDateParams) ([]GetArticleSummariesByPublicationDateRow, error) {
+	rows, err := q.db.QueryContext(ctx, getArticleSummariesByPublicationDate,
+		arg.FromDate,
+		arg.FromDate,
+		arg.ToDate,
+		arg.ToDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetArticleSummariesByPublicationDateRow
+	for rows.Next() {
+		var i GetArticleSummariesByPublicationDateRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			&i.Tags,
+			&i.PublicationDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArticleSummariesByTags = `-- name: GetArticleSummariesByTags :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE JSON_OVERLAPS(tags, CAST(? AS JSON))
+ORDER BY id ASC
+`
+
+type GetArticleSummariesByTagsRow struct {
+	ID              int64
+	Title           string
+	Thumbnail       string
+	ThumbnailHtml   string
+	Slug            string
+	Tags            json.RawMessage
+	PublicationDate time.Time
+}
+
+func (q *Queries) GetArticleSummariesByTags(ctx context.Context, tags json.RawMessage) ([]GetArticleSummariesByTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getArticleSummariesByTags, tags)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetArticleSummariesByTagsRow
+	for rows.Next() {
+		var i GetArticleSummariesByTagsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			&i.Tags,
+			&i.PublicationDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArticlesByPublicationDate = `-- name: GetArticlesByPublicationDate :many
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
+WHERE (publication_date >= ? OR ? IS NULL)
+  AND (publication_date < ? OR ? IS NULL)
+ORDER BY publication_date ASC, id ASC
+`
+
+type GetArticlesByPublicationDateParams struct {
+	FromDate sql.NullTime
+	ToDate   sql.NullTime
+}
+
+func (q *Queries) GetArticlesByPublicationDate(ctx context.Context, arg GetArticlesByPublicationDateParams) ([]Article, error) {
+	rows, err := q.db.QueryContext(ctx, getArticlesByPublicationDate,
+		arg.FromDate,
+		arg.FromDate,
+		arg.ToDate,
+		arg.ToDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Article
+	for rows.Next() {
+		var i Article
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			&i.Content,
+			&i.ContentHtml,
+			&i.Tags,
+			&i.Bibliography,
+			&i.PublicationDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getArticlesByTags = `-- name: GetArticlesByTags :many
-SELECT id, title, thumbnail, slug, content, tags, publication_date, created_at, updated_at FROM articles
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
 WHERE JSON_OVERLAPS(tags, CAST(? AS JSON))
+ORDER BY id ASC
 `
 
 func (q *Queries) GetArticlesByTags(ctx context.Context, tags json.RawMessage) ([]Article, error) {
@@ -183,9 +660,12 @@ func (q *Queries) GetArticlesByTags(ctx context.Context, tags json.RawMessage) (
 			&i.ID,
 			&i.Title,
 			&i.Thumbnail,
+			&i.ThumbnailHtml,
 			&i.Slug,
 			&i.Content,
+			&i.ContentHtml,
 			&i.Tags,
+			&i.Bibliography,
 			&i.PublicationDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
@@ -203,13 +683,194 @@ func (q *Queries) GetArticlesByTags(ctx context.Context, tags json.RawMessage) (
 	return items, nil
 }
 
+const getDueArticleEvents = `-- name: GetDueArticleEvents :many
+SELECT id, operation, article_id, payload, status, attempts, last_error, next_attempt_at, created_at, delivered_at FROM article_events
+WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+ORDER BY id ASC
+LIMIT ?
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) GetDueArticleEvents(ctx context.Context, limit int32) ([]ArticleEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getDueArticleEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ArticleEvent
+	for rows.Next() {
+		var i ArticleEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Operation,
+			&i.ArticleID,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaByHash = `-- name: GetMediaByHash :one
+SELECT id, hash, name, content_type, size, width, height, created_at FROM media
+WHERE hash = ? LIMIT 1
+`
+
+func (q *Queries) GetMediaByHash(ctx context.Context, hash string) (Medium, error) {
+	row := q.db.QueryRowContext(ctx, getMediaByHash, hash)
+	var i Medium
+	err := row.Scan(
+		&i.ID,
+		&i.Hash,
+		&i.Name,
+		&i.ContentType,
+		&i.Size,
+		&i.Width,
+		&i.Height,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getMediaVariantByHash = `-- name: GetMediaVariantByHash :one
+SELECT id, media_id, hash, name, content_type, size, width, height, created_at FROM media_variants
+WHERE hash = ? LIMIT 1
+`
+
+func (q *Queries) GetMediaVariantByHash(ctx context.Context, hash string) (MediaVariant, error) {
+	row := q.db.QueryRowContext(ctx, getMediaVariantByHash, hash)
+	var i MediaVariant
+	err := row.Scan(
+		&i.ID,
+		&i.MediaID,
+		&i.Hash,
+		&i.Name,
+		&i.ContentType,
+		&i.Size,
+		&i.Width,
+		&i.Height,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getMediaVariantsByMediaID = `-- name: GetMediaVariantsByMediaID :many
+SELECT id, media_id, hash, name, content_type, size, width, height, created_at FROM media_variants
+WHERE media_id = ?
+ORDER BY width ASC
+`
+
+func (q *Queries) GetMediaVariantsByMediaID(ctx context.Context, mediaID int64) ([]MediaVariant, error) {
+	rows, err := q.db.QueryContext(ctx, getMediaVariantsByMediaID, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaVariant
+	for rows.Next() {
+		var i MediaVariant
+		if err := rows.Scan(
+			&i.ID,
+			&i.MediaID,
+			&i.Hash,
+			&i.Name,
+			&i.ContentType,
+			&i.Size,
+			&i.Width,
+			&i.Height,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markArticleEventDelivered = `-- name: MarkArticleEventDelivered :exec
+UPDATE article_events
+SET status = 'delivered',
+    delivered_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+func (q *Queries) MarkArticleEventDelivered(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markArticleEventDelivered, id)
+	return err
+}
+
+const markArticleEventFailed = `-- name: MarkArticleEventFailed :exec
+UPDATE article_events
+SET status = ?,
+    attempts = attempts + 1,
+    last_error = ?,
+    next_attempt_at = CURRENT_TIMESTAMP + INTERVAL ? SECOND
+WHERE id = ?
+`
+
+type MarkArticleEventFailedParams struct {
+	Status       string
+	LastError    string
+	RetrySeconds interface{}
+	ID           int64
+}
+
+func (q *Queries) MarkArticleEventFailed(ctx context.Context, arg MarkArticleEventFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markArticleEventFailed,
+		arg.Status,
+		arg.LastError,
+		arg.RetrySeconds,
+		arg.ID,
+	)
+	return err
+}
+
+const postponeArticleEvent = `-- name: PostponeArticleEvent :exec
+UPDATE article_events
+SET next_attempt_at = CURRENT_TIMESTAMP + INTERVAL ? SECOND
+WHERE id = ?
+`
+
+type PostponeArticleEventParams struct {
+	LeaseSeconds interface{}
+	ID           int64
+}
+
+func (q *Queries) PostponeArticleEvent(ctx context.Context, arg PostponeArticleEventParams) error {
+	_, err := q.db.ExecContext(ctx, postponeArticleEvent, arg.LeaseSeconds, arg.ID)
+	return err
+}
+
 const updateArticleByID = `-- name: UpdateArticleByID :execrows
 UPDATE articles
 SET title = ?,
     thumbnail = ?,
+    thumbnail_html = ?,
     slug = ?,
     content = ?,
+    content_html = ?,
     tags = ?,
+    bibliography = ?,
     publication_date = ?
 WHERE id = ?
 `
@@ -217,9 +878,12 @@ WHERE id = ?
 type UpdateArticleByIDParams struct {
 	Title           string
 	Thumbnail       string
+	ThumbnailHtml   string
 	Slug            string
 	Content         string
+	ContentHtml     string
 	Tags            json.RawMessage
+	Bibliography    json.RawMessage
 	PublicationDate time.Time
 	ID              int64
 }
@@ -228,9 +892,12 @@ func (q *Queries) UpdateArticleByID(ctx context.Context, arg UpdateArticleByIDPa
 	result, err := q.db.ExecContext(ctx, updateArticleByID,
 		arg.Title,
 		arg.Thumbnail,
+		arg.ThumbnailHtml,
 		arg.Slug,
 		arg.Content,
+		arg.ContentHtml,
 		arg.Tags,
+		arg.Bibliography,
 		arg.PublicationDate,
 		arg.ID,
 	)
@@ -239,3 +906,21 @@ func (q *Queries) UpdateArticleByID(ctx context.Context, arg UpdateArticleByIDPa
 	}
 	return result.RowsAffected()
 }
+
+const updateMediaDimensions = `-- name: UpdateMediaDimensions :exec
+UPDATE media
+SET width = ?,
+    height = ?
+WHERE id = ?
+`
+
+type UpdateMediaDimensionsParams struct {
+	Width  int32
+	Height int32
+	ID     int64
+}
+
+func (q *Queries) UpdateMediaDimensions(ctx context.Context, arg UpdateMediaDimensionsParams) error {
+	_, err := q.db.ExecContext(ctx, updateMediaDimensions, arg.Width, arg.Height, arg.ID)
+	return err
+}
@@ -5,11 +5,13 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/fs"
 	"log/slog"
 	"strings"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
@@ -23,15 +25,17 @@ const DBDriver = "mysql"
 type Repository struct {
 	db *sql.DB
 	q  *Queries
+	// queryTimeout bounds every call, see repository.Options
+	queryTimeout time.Duration
 }
 
-func NewDatabase(connString string) (*sql.DB, error) {
+func NewDatabase(ctx context.Context, connString string) (*sql.DB, error) {
 	db, err := sql.Open(DBDriver, connString)
 	if err != nil {
 		return nil, errors.Join(repository.ErrDatabaseConnectionFailed, err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		closeErr := db.Close()
 		if closeErr != nil {
 			return nil, errors.Join(repository.ErrPingFailed, err, closeErr)
@@ -42,38 +46,51 @@ func NewDatabase(connString string) (*sql.DB, error) {
 	return db, nil
 }
 
-func NewRepository(db *sql.DB, migrationFiles fs.FS) (*Repository, error) {
-	// Run migration
-	if err := runMigration(db, migrationFiles); err != nil {
-		closeErr := db.Close()
-		if closeErr != nil {
-			return nil, errors.Join(repository.ErrMigrationFailed, err, closeErr)
+func NewRepository(db *sql.DB, migrationFiles fs.FS, options repository.Options) (*Repository, error) {
+	if !options.SkipMigrations {
+		if err := runMigration(db, migrationFiles); err != nil {
+			closeErr := db.Close()
+			if closeErr != nil {
+				return nil, errors.Join(repository.ErrMigrationFailed, err, closeErr)
+			}
+			return nil, errors.Join(repository.ErrMigrationFailed, err)
 		}
-		return nil, errors.Join(repository.ErrMigrationFailed, err)
 	}
 	return &Repository{
-		db: db,
-		q:  New(db),
+		db:           db,
+		q:            New(db),
+		queryTimeout: options.QueryTimeout,
 	}, nil
 }
 
-func runMigration(db *sql.DB, migrationFiles fs.FS) error {
+// NewMigration prepares the migrations in migrationFiles for running against db. Closing the returned instance
+// closes db as well.
+func NewMigration(db *sql.DB, migrationFiles fs.FS) (*migrate.Migrate, error) {
 	driver, err := mysql.WithInstance(db, &mysql.Config{})
 	if err != nil {
-		return errors.Join(repository.ErrDriverCreationFailed, err)
+		return nil, errors.Join(repository.ErrDriverCreationFailed, err)
 	}
 
 	// Create an embed source for the migration
 	embedSource, err := iofs.New(migrationFiles, ".")
 	if err != nil {
-		return errors.Join(repository.ErrEmbedFailed, err)
+		return nil, errors.Join(repository.ErrEmbedFailed, err)
 	}
 
 	m, err := migrate.NewWithInstance(
 		"iofs", embedSource,
 		DBDriver, driver)
 	if err != nil {
-		return errors.Join(repository.ErrMigrationInstanceFailed, err)
+		return nil, errors.Join(repository.ErrMigrationInstanceFailed, err)
+	}
+
+	return m, nil
+}
+
+func runMigration(db *sql.DB, migrationFiles fs.FS) error {
+	m, err := NewMigration(db, migrationFiles)
+	if err != nil {
+		return err
 	}
 
 	// Run the migration
@@ -84,7 +101,10 @@ func runMigration(db *sql.DB, migrationFiles fs.FS) error {
 	return nil
 }
 
-func (r *Repository) Create(ctx context.Context, article article.Article) (*article.Article, error) {
+func (r *Repository) Create(ctx context.Context, a article.Article) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -99,106 +119,258 @@ func (r *Repository) Create(ctx context.Context, article article.Article) (*arti
 
 	qtx := r.q.WithTx(tx)
 	result, err := qtx.CreateArticle(ctx, CreateArticleParams{
-		Title:           article.Title,
-		Thumbnail:       article.Thumbnail,
-		Slug:            article.Slug,
-		Content:         article.Content,
-		Tags:            tagsToJSON(article.Tags),
-		PublicationDate: article.PublicationDate,
+		Title:           a.Title,
+		Thumbnail:       a.Thumbnail,
+		ThumbnailHtml:   a.ThumbnailHTML,
+		Slug:            a.Slug,
+		Content:         a.Content,
+		ContentHtml:     a.ContentHTML,
+		Tags:            tagsToJSON(a.Tags),
+		Bibliography:    referencesToJSON(a.References),
+		PublicationDate: a.PublicationDate,
 	})
+	if err != nil {
+		return nil, duplicateSlug(err)
+	}
+	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
+
+	a.ID = id
+	if err := recordEvent(ctx, qtx, article.ChangeCreated, a); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
+	return &a, nil
+}
+
+// CreateWithID creates article under article.ID instead of the next free ID. It's meant for copying articles
+// between databases.
+func (r *Repository) CreateWithID(ctx context.Context, article article.Article) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err != sql.ErrTxDone {
+				slog.Error(errors.Join(repository.ErrTxRollbackFailed, err).Error(), "requestID", middleware.ReqIDFromCtx(ctx))
+			}
+		}
+	}()
 
-	article.ID = id
+	qtx := r.q.WithTx(tx)
+	err = qtx.CreateArticleWithID(ctx, CreateArticleWithIDParams{
+		ID:              article.ID,
+		Title:           article.Title,
+		Thumbnail:       article.Thumbnail,
+		ThumbnailHtml:   article.ThumbnailHTML,
+		Slug:            article.Slug,
+		Content:         article.Content,
+		ContentHtml:     article.ContentHTML,
+		Tags:            tagsToJSON(article.Tags),
+		Bibliography:    referencesToJSON(article.References),
+		PublicationDate: article.PublicationDate,
+	})
+	if err != nil {
+		return nil, duplicateSlug(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
 	return &article, nil
 }
 
 func (r *Repository) GetAll(ctx context.Context) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	dbArticles, err := r.q.GetAllArticles(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	articlesSlice := make(article.Articles, len(dbArticles))
-	for i, a := range dbArticles {
-		articlesSlice[i] = article.Article{
-			ID:              a.ID,
-			Title:           a.Title,
-			Thumbnail:       a.Thumbnail,
-			Slug:            a.Slug,
-			Content:         a.Content,
-			Tags:            jsonToTags(a.Tags),
-			PublicationDate: a.PublicationDate,
-		}
-	}
-
-	return articlesSlice, nil
+	return toArticles(dbArticles)
 }
 
 func (r *Repository) GetByID(ctx context.Context, id int64) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	dbArticle, err := r.q.GetArticleByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
-	return &article.Article{
-		ID:              dbArticle.ID,
-		Title:           dbArticle.Title,
-		Thumbnail:       dbArticle.Thumbnail,
-		Slug:            dbArticle.Slug,
-		Content:         dbArticle.Content,
-		Tags:            jsonToTags(dbArticle.Tags),
-		PublicationDate: dbArticle.PublicationDate,
-	}, nil
+	a, err := toArticle(dbArticle)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
 }
 
 func (r *Repository) GetBySlug(ctx context.Context, slug string) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	dbArticle, err := r.q.GetArticleBySlug(ctx, slug)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
-	return &article.Article{
-		ID:              dbArticle.ID,
-		Title:           dbArticle.Title,
-		Thumbnail:       dbArticle.Thumbnail,
-		Slug:            dbArticle.Slug,
-		Content:         dbArticle.Content,
-		Tags:            jsonToTags(dbArticle.Tags),
-		PublicationDate: dbArticle.PublicationDate,
-	}, nil
+	a, err := toArticle(dbArticle)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
 }
 
 func (r *Repository) GetByTags(ctx context.Context, tags []string) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	dbArticles, err := r.q.GetArticlesByTags(ctx, tagsToJSON(tags))
 	if err != nil {
 		return nil, err
 	}
 
-	articlesSlice := make(article.Articles, len(dbArticles))
-	for i, a := range dbArticles {
-		articlesSlice[i] = article.Article{
-			ID:              a.ID,
-			Title:           a.Title,
-			Thumbnail:       a.Thumbnail,
-			Slug:            a.Slug,
-			Content:         a.Content,
-			Tags:            jsonToTags(a.Tags),
-			PublicationDate: a.PublicationDate,
+	return toArticles(dbArticles)
+}
+
+func (r *Repository) GetByPublicationDate(ctx context.Context, from, to time.Time) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbArticles, err := r.q.GetArticlesByPublicationDate(ctx, GetArticlesByPublicationDateParams{
+		FromDate: nullTime(from),
+		ToDate:   nullTime(to),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toArticles(dbArticles)
+}
+
+func (r *Repository) GetAllSummaries(ctx context.Context) (article.ArticleSummaries, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.q.GetAllArticleSummaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(article.ArticleSummaries, len(rows))
+	for i, s := range rows {
+		summaryTags, err := jsonToTags(s.Tags)
+		if err != nil {
+			return nil, err
+		}
+		summaries[i] = article.ArticleSummary{
+			ID:              s.ID,
+			Title:           s.Title,
+			Thumbnail:       s.Thumbnail,
+			ThumbnailHTML:   s.ThumbnailHtml,
+			Slug:            s.Slug,
+			Tags:            summaryTags,
+			PublicationDate: s.PublicationDate,
+		}
+	}
+
+	return summaries, nil
+}
+
+func (r *Repository) GetSummariesByTags(ctx context.Context, tags []string) (article.ArticleSummaries, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.q.GetArticleSummariesByTags(ctx, tagsToJSON(tags))
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(article.ArticleSummaries, len(rows))
+	for i, s := range rows {
+		summaryTags, err := jsonToTags(s.Tags)
+		if err != nil {
+			return nil, err
+		}
+		summaries[i] = article.ArticleSummary{
+			ID:              s.ID,
+			Title:           s.Title,
+			Thumbnail:       s.Thumbnail,
+			ThumbnailHTML:   s.ThumbnailHtml,
+			Slug:            s.Slug,
+			Tags:            summaryTags,
+			PublicationDate: s.PublicationDate,
+		}
+	}
+
+	return summaries, nil
+}
+
+func (r *Repository) GetSummariesByPublicationDate(
+	ctx context.Context,
+	from, to time.Time,
+) (article.ArticleSummaries, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.q.GetArticleSummariesByPublicationDate(ctx, GetArticleSummariesByPublicationDateParams{
+		FromDate: nullTime(from),
+		ToDate:   nullTime(to),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(article.ArticleSummaries, len(rows))
+	for i, s := range rows {
+		summaryTags, err := jsonToTags(s.Tags)
+		if err != nil {
+			return nil, err
 		}
+		summaries[i] = article.ArticleSummary{
+			ID:              s.ID,
+			Title:           s.Title,
+			Thumbnail:       s.Thumbnail,
+			ThumbnailHTML:   s.ThumbnailHtml,
+			Slug:            s.Slug,
+			Tags:            summaryTags,
+			PublicationDate: s.PublicationDate,
+		}
+	}
+
+	return summaries, nil
+}
+
+func (r *Repository) GetArchive(ctx context.Context) ([]article.ArchiveMonth, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.q.GetArticleArchive(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return articlesSlice, nil
+	months := make([]article.ArchiveMonth, len(rows))
+	for i, row := range rows {
+		months[i] = article.ArchiveMonth{
+			Year:  int(row.Year),
+			Month: time.Month(row.Month),
+			Count: int(row.ArticleCount),
+		}
+	}
+	return months, nil
 }
 
 func (r *Repository) Update(
@@ -206,6 +378,9 @@ func (r *Repository) Update(
 	id int64,
 	updated article.Article,
 ) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -219,40 +394,58 @@ func (r *Repository) Update(
 	}()
 
 	qtx := r.q.WithTx(tx)
-	id, err = qtx.UpdateArticleByID(ctx, UpdateArticleByIDParams{
+	previous, err := qtx.GetArticleByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	_, err = qtx.UpdateArticleByID(ctx, UpdateArticleByIDParams{
 		ID:              id,
 		Title:           updated.Title,
 		Thumbnail:       updated.Thumbnail,
+		ThumbnailHtml:   updated.ThumbnailHTML,
 		Slug:            updated.Slug,
 		Content:         updated.Content,
+		ContentHtml:     updated.ContentHTML,
 		Tags:            tagsToJSON(updated.Tags),
+		Bibliography:    referencesToJSON(updated.References),
 		PublicationDate: updated.PublicationDate,
 	})
 	if err != nil {
-		return nil, err
+		return nil, duplicateSlug(err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, err
+	// The affected rows can't tell a missing article apart from an update that changed nothing,
+	// so read the article back instead.
+	dbArticle, err := qtx.GetArticleByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
 	}
 
-	a, err := r.q.GetArticleByID(ctx, id)
+	previousArticle, err := toArticle(previous)
 	if err != nil {
 		return nil, err
 	}
+	a, err := toArticle(dbArticle)
+	if err != nil {
+		return nil, err
+	}
+	// Rendering an article again isn't recorded, the outbox only carries the markdown
+	if !a.SameMarkdown(previousArticle) {
+		if err := recordEvent(ctx, qtx, article.ChangeUpdated, a); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
-	return &article.Article{
-		ID:              a.ID,
-		Title:           a.Title,
-		Thumbnail:       a.Thumbnail,
-		Slug:            a.Slug,
-		Content:         a.Content,
-		Tags:            jsonToTags(a.Tags),
-		PublicationDate: a.PublicationDate,
-	}, nil
+	return &a, nil
 }
 
 func (r *Repository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -266,36 +459,141 @@ func (r *Repository) Delete(ctx context.Context, id int64) error {
 	}()
 
 	qtx := r.q.WithTx(tx)
-	_, err = qtx.DeleteArticleByID(ctx, id)
+	// The event describes the article as it was before the deletion
+	dbArticle, err := qtx.GetArticleByID(ctx, id)
+	if err != nil {
+		return notFound(err)
+	}
+	deleted, err := qtx.DeleteArticleByID(ctx, id)
 	if err != nil {
 		return err
 	}
+	if deleted == 0 {
+		return article.ErrArticleNotFound
+	}
 
+	a, err := toArticle(dbArticle)
+	if err != nil {
+		return err
+	}
+	if err := recordEvent(ctx, qtx, article.ChangeDeleted, a); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
-func (r *Repository) GetAllTags(ctx context.Context) ([]string, error) {
-	tags, err := r.q.GetAllTags(ctx)
+func (r *Repository) SetLinks(ctx context.Context, sourceID int64, targetSlugs []string) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err != sql.ErrTxDone {
+				slog.Error(errors.Join(repository.ErrTxRollbackFailed, err).Error(), "requestID", middleware.ReqIDFromCtx(ctx))
+			}
+		}
+	}()
+
+	qtx := r.q.WithTx(tx)
+	if err := qtx.DeleteArticleLinks(ctx, sourceID); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, slug := range targetSlugs {
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		if err := qtx.CreateArticleLink(ctx, CreateArticleLinkParams{
+			SourceID:   sourceID,
+			TargetSlug: slug,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) GetBacklinks(ctx context.Context, slug string) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbArticles, err := r.q.GetArticleBacklinks(ctx, slug)
 	if err != nil {
 		return nil, err
 	}
 
-	// Clean up the tags
-	var cleanTags []string
-	for _, tag := range tags {
-		// Remove any leading or trailing whitespace, quotes, and square brackets
-		cleanTag := strings.Trim(tag, " \"\n[]")
-		// Split the tag in case it contains multiple tags
-		splitTags := strings.Split(cleanTag, ",")
-		for _, t := range splitTags {
-			t = strings.TrimSpace(t)
-			if t != "" {
-				cleanTags = append(cleanTags, t)
-			}
+	return toArticles(dbArticles)
+}
+
+func (r *Repository) GetAllTags(ctx context.Context) ([]string, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.q.GetAllTags(ctx)
+}
+
+// toArticle converts an article row, failing if a column holding JSON can't be decoded
+func toArticle(a Article) (article.Article, error) {
+	tags, err := jsonToTags(a.Tags)
+	if err != nil {
+		return article.Article{}, err
+	}
+	references, err := jsonToReferences(a.Bibliography)
+	if err != nil {
+		return article.Article{}, err
+	}
+	return article.Article{
+		ID:              a.ID,
+		Title:           a.Title,
+		Thumbnail:       a.Thumbnail,
+		ThumbnailHTML:   a.ThumbnailHtml,
+		Slug:            a.Slug,
+		Content:         a.Content,
+		ContentHTML:     a.ContentHtml,
+		Tags:            tags,
+		References:      references,
+		PublicationDate: a.PublicationDate,
+	}, nil
+}
+
+func toArticles(rows []Article) (article.Articles, error) {
+	articles := make(article.Articles, len(rows))
+	for i, row := range rows {
+		a, err := toArticle(row)
+		if err != nil {
+			return nil, err
 		}
+		articles[i] = a
+	}
+	return articles, nil
+}
+
+// notFound marks a missing row as a missing article so callers can tell it apart from other failures.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.Join(article.ErrArticleNotFound, err)
 	}
+	return err
+}
 
-	return cleanTags, nil
+// duplicateSlug turns the duplicate entry error of the slug index into repository.ErrDuplicateSlug
+func duplicateSlug(err error) error {
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 && strings.Contains(mysqlErr.Message, "idx_articles_slug") {
+		return errors.Join(repository.ErrDuplicateSlug, err)
+	}
+	return err
+}
+
+// nullTime turns the zero time into NULL, an open end of a range
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
 }
 
 func tagsToJSON(tags []string) json.RawMessage {
@@ -306,11 +604,32 @@ func tagsToJSON(tags []string) json.RawMessage {
 	return jsonTags
 }
 
-func jsonToTags(j json.RawMessage) []string {
+func jsonToTags(j json.RawMessage) ([]string, error) {
 	var tags []string
-	err := json.Unmarshal(j, &tags)
+	if err := json.Unmarshal(j, &tags); err != nil {
+		return nil, errors.Join(repository.ErrInvalidArticle, fmt.Errorf("tags: %w", err))
+	}
+	return tags, nil
+}
+
+func referencesToJSON(references []article.Reference) json.RawMessage {
+	if references == nil {
+		references = []article.Reference{}
+	}
+	jsonReferences, err := json.Marshal(references)
 	if err != nil {
 		panic(err)
 	}
-	return tags
+	return jsonReferences
+}
+
+func jsonToReferences(j json.RawMessage) ([]article.Reference, error) {
+	var references []article.Reference
+	if err := json.Unmarshal(j, &references); err != nil {
+		return nil, errors.Join(repository.ErrInvalidArticle, fmt.Errorf("bibliography: %w", err))
+	}
+	if len(references) == 0 {
+		return nil, nil
+	}
+	return references, nil
 }
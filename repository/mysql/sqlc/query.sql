@@ -1,9 +1,18 @@
 -- name: CreateArticle :execresult
-INSERT INTO articles (title, thumbnail, slug, content, tags, publication_date)
-VALUES (?, ?, ?, ?, ?, ?);
+INSERT INTO articles (title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: CreateArticleWithID :exec
+INSERT INTO articles (id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
 
 -- name: GetAllArticles :many
-SELECT * FROM articles;
+SELECT * FROM articles
+ORDER BY id ASC;
+
+-- name: GetAllArticleSummaries :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+ORDER BY id ASC;
 
 -- name: GetArticleByID :one
 SELECT * FROM articles
@@ -15,25 +24,50 @@ WHERE slug = ? LIMIT 1;
 
 -- name: GetArticlesByTags :many
 SELECT * FROM articles
-WHERE JSON_OVERLAPS(tags, CAST(sqlc.arg(tags) AS JSON));
+WHERE JSON_OVERLAPS(tags, CAST(sqlc.arg(tags) AS JSON))
+ORDER BY id ASC;
 
--- name: GetAllTags :many
-SELECT DISTINCT SUBSTRING_INDEX(SUBSTRING_INDEX(tags, ',', numbers.n), ',', -1) AS unique_tag
+-- name: GetArticleSummariesByTags :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE JSON_OVERLAPS(tags, CAST(sqlc.arg(tags) AS JSON))
+ORDER BY id ASC;
+
+-- name: GetArticlesByPublicationDate :many
+SELECT * FROM articles
+WHERE (publication_date >= sqlc.narg(from_date) OR sqlc.narg(from_date) IS NULL)
+  AND (publication_date < sqlc.narg(to_date) OR sqlc.narg(to_date) IS NULL)
+ORDER BY publication_date ASC, id ASC;
+
+-- name: GetArticleSummariesByPublicationDate :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE (publication_date >= sqlc.narg(from_date) OR sqlc.narg(from_date) IS NULL)
+  AND (publication_date < sqlc.narg(to_date) OR sqlc.narg(to_date) IS NULL)
+ORDER BY publication_date ASC, id ASC;
+
+-- name: GetArticleArchive :many
+SELECT CAST(YEAR(publication_date) AS SIGNED) AS year,
+       CAST(MONTH(publication_date) AS SIGNED) AS month,
+       COUNT(*) AS article_count
 FROM articles
-CROSS JOIN (
-    SELECT 1 AS n UNION ALL SELECT 2 UNION ALL SELECT 3 UNION ALL SELECT 4
-) numbers
-WHERE tags IS NOT NULL
-  AND CHAR_LENGTH(tags) - CHAR_LENGTH(REPLACE(tags, ',', '')) >= numbers.n - 1
+GROUP BY year, month
+ORDER BY year DESC, month DESC;
+
+-- name: GetAllTags :many
+SELECT DISTINCT tag.value AS unique_tag
+FROM articles,
+    JSON_TABLE(articles.tags, '$[*]' COLUMNS (value VARCHAR(255) PATH '$')) AS tag
 ORDER BY unique_tag ASC;
 
 -- name: UpdateArticleByID :execrows
 UPDATE articles
 SET title = ?,
     thumbnail = ?,
+    thumbnail_html = ?,
     slug = ?,
     content = ?,
+    content_html = ?,
     tags = ?,
+    bibliography = ?,
     publication_date = ?
 WHERE id = ?;
 
@@ -45,3 +79,84 @@ SELECT id, title, thumbnail, slug, content, tags, publication_date
 FROM articles
 WHERE id = ?;
 
+
+-- name: CreateMedia :execresult
+INSERT INTO media (hash, name, content_type, size, width, height)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: GetMediaByHash :one
+SELECT * FROM media
+WHERE hash = ? LIMIT 1;
+
+-- name: GetAllMedia :many
+SELECT * FROM media
+ORDER BY created_at DESC;
+
+-- name: UpdateMediaDimensions :exec
+UPDATE media
+SET width = ?,
+    height = ?
+WHERE id = ?;
+
+-- name: CreateMediaVariant :execresult
+INSERT INTO media_variants (media_id, hash, name, content_type, size, width, height)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetMediaVariantByHash :one
+SELECT * FROM media_variants
+WHERE hash = ? LIMIT 1;
+
+-- name: GetMediaVariantsByMediaID :many
+SELECT * FROM media_variants
+WHERE media_id = ?
+ORDER BY width ASC;
+
+-- name: CreateArticleLink :exec
+INSERT INTO article_links (source_id, target_slug)
+VALUES (?, ?);
+
+-- name: DeleteArticleLinks :exec
+DELETE FROM article_links
+WHERE source_id = ?;
+
+-- name: GetArticleBacklinks :many
+SELECT articles.* FROM articles
+JOIN article_links ON article_links.source_id = articles.id
+WHERE article_links.target_slug = ?
+  AND articles.slug != article_links.target_slug
+ORDER BY articles.publication_date DESC, articles.id;
+
+-- name: CreateArticleEvent :exec
+INSERT INTO article_events (operation, article_id, payload)
+VALUES (?, ?, ?);
+
+-- name: GetDueArticleEvents :many
+SELECT * FROM article_events
+WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+ORDER BY id ASC
+LIMIT ?
+FOR UPDATE SKIP LOCKED;
+
+-- name: PostponeArticleEvent :exec
+UPDATE article_events
+SET next_attempt_at = CURRENT_TIMESTAMP + INTERVAL sqlc.arg(lease_seconds) SECOND
+WHERE id = sqlc.arg(id);
+
+-- name: MarkArticleEventDelivered :exec
+UPDATE article_events
+SET status = 'delivered',
+    delivered_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: MarkArticleEventFailed :exec
+UPDATE article_events
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = CURRENT_TIMESTAMP + INTERVAL sqlc.arg(retry_seconds) SECOND
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteDeliveredArticleEvents :execrows
+DELETE FROM article_events
+WHERE status = 'delivered'
+  AND delivered_at < CURRENT_TIMESTAMP - INTERVAL sqlc.arg(age_seconds) SECOND;
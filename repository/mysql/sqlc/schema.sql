@@ -2,12 +2,64 @@ CREATE TABLE articles (
     id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
     title VARCHAR(255) NOT NULL,
     thumbnail TEXT NOT NULL,
+    thumbnail_html TEXT NOT NULL,
     slug VARCHAR(255) NOT NULL,
     content TEXT NOT NULL,
+    content_html MEDIUMTEXT NOT NULL,
     tags JSON,
+    bibliography JSON NOT NULL DEFAULT (JSON_ARRAY()),
     publication_date DATETIME NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_articles_tags ON articles ((CAST(tags AS CHAR(255))));
+CREATE UNIQUE INDEX idx_articles_slug ON articles (slug);
+
+CREATE TABLE media (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    hash CHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    width INT NOT NULL DEFAULT 0,
+    height INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE media_variants (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    media_id BIGINT UNSIGNED NOT NULL,
+    hash CHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (media_id) REFERENCES media (id) ON DELETE CASCADE
+);
+
+CREATE TABLE article_links (
+    source_id BIGINT UNSIGNED NOT NULL,
+    target_slug VARCHAR(255) NOT NULL,
+    PRIMARY KEY (source_id, target_slug),
+    FOREIGN KEY (source_id) REFERENCES articles (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_article_links_target_slug ON article_links (target_slug);
+
+CREATE TABLE article_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    operation VARCHAR(32) NOT NULL,
+    article_id BIGINT UNSIGNED NOT NULL,
+    payload JSON NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT (''),
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP NULL
+);
+
+CREATE INDEX idx_article_events_status ON article_events (status, next_attempt_at);
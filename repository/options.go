@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Options configures the SQL repositories.
+type Options struct {
+	// SkipMigrations leaves the schema as it is instead of migrating it to the latest version when the repository
+	// is created. Deployments running several instances use it to migrate once, with the migrate command, before
+	// rolling out.
+	SkipMigrations bool
+	// Replicas are read-only copies of the primary database that reads are spread over. Only the postgres
+	// repository supports them.
+	Replicas []*sql.DB
+	// QueryTimeout bounds how long a single repository call may take, zero means no limit.
+	QueryTimeout time.Duration
+}
+
+// WithQueryTimeout bounds ctx by timeout. A zero timeout leaves ctx as it is.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// PoolOptions configures the connection pool of a database. Zero values keep the database/sql defaults.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Apply sets the options on the connection pool of db.
+func (o PoolOptions) Apply(db *sql.DB) {
+	if o.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(o.MaxOpenConns)
+	}
+	if o.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(o.MaxIdleConns)
+	}
+	if o.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(o.ConnMaxLifetime)
+	}
+	if o.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+	}
+}
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/repository"
+)
+
+func (r *Repository) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]article.Event, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbEvents, err := r.q.ClaimArticleEvents(ctx, ClaimArticleEventsParams{
+		LeaseSeconds: lease.Seconds(),
+		MaxEvents:    int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]article.Event, 0, len(dbEvents))
+	for _, e := range dbEvents {
+		event := article.Event{
+			ID:         e.ID,
+			Operation:  article.ChangeOperation(e.Operation),
+			OccurredAt: e.CreatedAt,
+			Attempts:   int(e.Attempts),
+		}
+		if err := json.Unmarshal(e.Payload, &event.Article); err != nil {
+			// The payload won't decode on a later attempt either, the event is dead rather than holding up the rest
+			if err := r.q.MarkArticleEventFailed(ctx, invalidEvent(e.ID, err)); err != nil {
+				return nil, err
+			}
+			slog.Warn("Skipping an outbox event that can't be decoded", "id", e.ID, "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	// RETURNING doesn't keep the order of the claimed rows
+	slices.SortFunc(events, func(a, b article.Event) int { return cmp.Compare(a.ID, b.ID) })
+	return events, nil
+}
+
+func (r *Repository) MarkEventDelivered(ctx context.Context, id int64) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.q.MarkArticleEventDelivered(ctx, id)
+}
+
+func (r *Repository) MarkEventFailed(
+	ctx context.Context,
+	id int64,
+	failure string,
+	retryIn time.Duration,
+	dead bool,
+) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	status := article.EventPending
+	if dead {
+		status = article.EventDead
+	}
+	return r.q.MarkArticleEventFailed(ctx, MarkArticleEventFailedParams{
+		Status:       string(status),
+		LastError:    failure,
+		RetrySeconds: retryIn.Seconds(),
+		ID:           id,
+	})
+}
+
+func (r *Repository) DeleteDeliveredEvents(ctx context.Context, age time.Duration) (int64, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.q.DeleteDeliveredArticleEvents(ctx, age.Seconds())
+}
+
+// invalidEvent marks the event with id dead because its payload can't be decoded
+func invalidEvent(id int64, err error) MarkArticleEventFailedParams {
+	return MarkArticleEventFailedParams{
+		Status:    string(article.EventDead),
+		LastError: errors.Join(repository.ErrInvalidEvent, err).Error(),
+		ID:        id,
+	}
+}
+
+// recordEvent adds the change of a to the outbox, in the transaction of the change
+func recordEvent(ctx context.Context, q *Queries, operation article.ChangeOperation, a article.Article) error {
+	payload, err := eventPayload(a)
+	if err != nil {
+		return err
+	}
+	return q.CreateArticleEvent(ctx, CreateArticleEventParams{
+		Operation: string(operation),
+		ArticleID: a.ID,
+		Payload:   payload,
+	})
+}
+
+// eventPayload is a without its rendered HTML, which is large and only of use to the blog itself
+func eventPayload(a article.Article) (json.RawMessage, error) {
+	a.ContentHTML, a.ThumbnailHTML = "", ""
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return nil, errors.Join(repository.ErrInvalidEvent, err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/repository"
+	"github.com/lib/pq"
+)
+
+// ChangesChannel is the channel the triggers on articles and article_links notify about every change on
+const ChangesChannel = "article_changes"
+
+const (
+	listenerMinReconnectInterval = time.Second
+	listenerMaxReconnectInterval = time.Minute
+	// listenerPingInterval is how often an idle connection is checked, a dead one is only noticed on use
+	listenerPingInterval = 90 * time.Second
+)
+
+// Listener is an article.ChangeFeed publishing the changes made to the articles by any instance of the blog,
+// received through LISTEN on ChangesChannel. It reconnects on its own when the connection is lost and publishes
+// an article.ChangeUnknown afterwards, since changes made in the meantime are lost.
+type Listener struct {
+	listener *pq.Listener
+
+	mutex       sync.RWMutex
+	subscribers []subscriber
+	nextID      uint64
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+type subscriber struct {
+	id uint64
+	f  func(article.Change)
+}
+
+// NewListener connects to the database at connString and starts listening for changes
+func NewListener(connString string) (*Listener, error) {
+	l := &Listener{
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	l.listener = pq.NewListener(connString, listenerMinReconnectInterval, listenerMaxReconnectInterval, l.event)
+	if err := l.listener.Listen(ChangesChannel); err != nil {
+		return nil, errors.Join(repository.ErrListenFailed, err, l.listener.Close())
+	}
+
+	go l.run()
+	return l, nil
+}
+
+// Subscribe calls f with every change, subscribers are called in the order they subscribed in
+func (l *Listener) Subscribe(f func(article.Change)) func() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	id := l.nextID
+	l.nextID++
+	l.subscribers = append(l.subscribers, subscriber{id: id, f: f})
+	return func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		l.subscribers = slices.DeleteFunc(l.subscribers, func(s subscriber) bool { return s.id == id })
+	}
+}
+
+// Close stops listening and closes the connection
+func (l *Listener) Close() error {
+	close(l.done)
+	err := l.listener.Close()
+	<-l.stopped
+	return err
+}
+
+func (l *Listener) run() {
+	defer close(l.stopped)
+
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case notification, ok := <-l.listener.Notify:
+			if !ok {
+				return
+			}
+			// A nil notification follows a reconnect
+			if notification == nil {
+				l.publish(article.Change{Operation: article.ChangeUnknown})
+				continue
+			}
+			l.publish(parseChange(notification.Extra))
+		case <-ping.C:
+			go func() {
+				if err := l.listener.Ping(); err != nil {
+					slog.Warn("Listening for article changes failed a ping", "error", err)
+				}
+			}()
+		}
+	}
+}
+
+func (l *Listener) publish(change article.Change) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	for _, s := range l.subscribers {
+		s.f(change)
+	}
+}
+
+// event logs the state of the connection
+func (l *Listener) event(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		slog.Warn("Lost the connection listening for article changes", "error", err)
+	case pq.ListenerEventConnectionAttemptFailed:
+		slog.Warn("Failed to reconnect listening for article changes", "error", err)
+	case pq.ListenerEventReconnected:
+		slog.Info("Reconnected listening for article changes")
+	}
+}
+
+// parseChange decodes the payload of a notification. Payloads that can't be decoded say that something
+// unknown changed.
+func parseChange(payload string) article.Change {
+	var change article.Change
+	if err := json.Unmarshal([]byte(payload), &change); err != nil || change.Operation == "" {
+		slog.Warn("Received an invalid article change notification", "payload", payload, "error", err)
+		return article.Change{Operation: article.ChangeUnknown}
+	}
+	return change
+}
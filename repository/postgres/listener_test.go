@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/jannawro/blog/article"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChange(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    article.Change
+	}{
+		{
+			name:    "Update",
+			payload: `{"operation":"updated","id":3,"slug":"new","tags":["go"],"previous_slug":"old","previous_tags":null}`,
+			want: article.Change{
+				Operation:    article.ChangeUpdated,
+				ID:           3,
+				Slug:         "new",
+				Tags:         []string{"go"},
+				PreviousSlug: "old",
+			},
+		},
+		{
+			name:    "Links",
+			payload: `{"operation":"links_changed","id":3,"slug":"target"}`,
+			want:    article.Change{Operation: article.ChangeLinks, ID: 3, Slug: "target"},
+		},
+		{
+			name:    "Invalid JSON",
+			payload: `{"operation":`,
+			want:    article.Change{Operation: article.ChangeUnknown},
+		},
+		{
+			name:    "Missing operation",
+			payload: `{"id":3}`,
+			want:    article.Change{Operation: article.ChangeUnknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseChange(tt.payload))
+		})
+	}
+}
+
+func TestPublishInSubscriptionOrder(t *testing.T) {
+	l := &Listener{}
+	var calls []int
+	for i := range 5 {
+		unsubscribe := l.Subscribe(func(article.Change) { calls = append(calls, i) })
+		if i == 2 {
+			unsubscribe()
+		}
+	}
+
+	l.publish(article.Change{Operation: article.ChangeUnknown})
+	assert.Equal(t, []int{0, 1, 3, 4}, calls)
+}
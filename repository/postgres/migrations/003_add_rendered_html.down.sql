@@ -0,0 +1,2 @@
+ALTER TABLE articles DROP COLUMN IF EXISTS thumbnail_html;
+ALTER TABLE articles DROP COLUMN IF EXISTS content_html;
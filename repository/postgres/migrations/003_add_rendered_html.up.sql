@@ -0,0 +1,2 @@
+ALTER TABLE articles ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE articles ADD COLUMN thumbnail_html TEXT NOT NULL DEFAULT '';
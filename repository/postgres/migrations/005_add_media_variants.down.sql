@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS media_variants;
+ALTER TABLE media DROP COLUMN IF EXISTS height;
+ALTER TABLE media DROP COLUMN IF EXISTS width;
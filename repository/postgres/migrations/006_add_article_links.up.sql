@@ -0,0 +1,7 @@
+CREATE TABLE article_links (
+    source_id BIGINT NOT NULL REFERENCES articles (id) ON DELETE CASCADE,
+    target_slug VARCHAR(255) NOT NULL,
+    PRIMARY KEY (source_id, target_slug)
+);
+
+CREATE INDEX idx_article_links_target_slug ON article_links (target_slug);
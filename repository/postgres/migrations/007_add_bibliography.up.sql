@@ -0,0 +1 @@
+ALTER TABLE articles ADD COLUMN bibliography JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS article_links_notify_change ON article_links;
+DROP FUNCTION IF EXISTS notify_article_links_change();
+DROP TRIGGER IF EXISTS articles_notify_change ON articles;
+DROP FUNCTION IF EXISTS notify_article_change();
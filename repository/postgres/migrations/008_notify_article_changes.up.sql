@@ -0,0 +1,41 @@
+-- Instances of the blog listen on article_changes to drop their cached copies of changed articles
+CREATE FUNCTION notify_article_change() RETURNS TRIGGER AS $$
+DECLARE
+    payload JSONB;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        payload = jsonb_build_object('operation', 'created', 'id', NEW.id, 'slug', NEW.slug, 'tags', NEW.tags);
+    ELSIF TG_OP = 'UPDATE' THEN
+        payload = jsonb_build_object('operation', 'updated', 'id', NEW.id, 'slug', NEW.slug, 'tags', NEW.tags,
+            'previous_slug', OLD.slug, 'previous_tags', OLD.tags);
+    ELSE
+        payload = jsonb_build_object('operation', 'deleted', 'id', OLD.id,
+            'previous_slug', OLD.slug, 'previous_tags', OLD.tags);
+    END IF;
+    PERFORM pg_notify('article_changes', payload::TEXT);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER articles_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON articles
+FOR EACH ROW EXECUTE FUNCTION notify_article_change();
+
+CREATE FUNCTION notify_article_links_change() RETURNS TRIGGER AS $$
+DECLARE
+    link article_links;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        link = NEW;
+    ELSE
+        link = OLD;
+    END IF;
+    PERFORM pg_notify('article_changes',
+        jsonb_build_object('operation', 'links_changed', 'id', link.source_id, 'slug', link.target_slug)::TEXT);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER article_links_notify_change
+AFTER INSERT OR DELETE ON article_links
+FOR EACH ROW EXECUTE FUNCTION notify_article_links_change();
@@ -0,0 +1,15 @@
+-- The outbox of article changes, written in the same transaction as the change and delivered by outbox.Dispatcher
+CREATE TABLE article_events (
+    id BIGSERIAL PRIMARY KEY,
+    operation VARCHAR(32) NOT NULL,
+    article_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_article_events_status ON article_events (status, next_attempt_at);
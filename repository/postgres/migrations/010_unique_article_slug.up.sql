@@ -0,0 +1 @@
+CREATE UNIQUE INDEX idx_articles_slug ON articles (slug);
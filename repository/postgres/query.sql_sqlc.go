@@ -7,23 +7,80 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/lib/pq"
 )
 
+const claimArticleEvents = `-- name: ClaimArticleEvents :many
+UPDATE article_events
+SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $1::float8)
+WHERE id IN (
+    SELECT id FROM article_events
+    WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY id ASC
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, operation, article_id, payload, status, attempts, last_error, next_attempt_at, created_at, delivered_at
+`
+
+type ClaimArticleEventsParams struct {
+	LeaseSeconds float64
+	MaxEvents    int32
+}
+
+func (q *Queries) ClaimArticleEvents(ctx context.Context, arg ClaimArticleEventsParams) ([]ArticleEvent, error) {
+	rows, err := q.db.QueryContext(ctx, claimArticleEvents, arg.LeaseSeconds, arg.MaxEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ArticleEvent
+	for rows.Next() {
+		var i ArticleEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Operation,
+			&i.ArticleID,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.CreatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createArticle = `-- name: CreateArticle :one
-INSERT INTO articles (title, thumbnail, slug, content, tags, publication_date)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO articles (title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING id
 `
 
 type CreateArticleParams struct {
 	Title           string
 	Thumbnail       string
+	ThumbnailHtml   string
 	Slug            string
 	Content         string
+	ContentHtml     string
 	Tags            []string
+	Bibliography    json.RawMessage
 	PublicationDate time.Time
 }
 
@@ -31,9 +88,12 @@ func (q *Queries) CreateArticle(ctx context.Context, arg CreateArticleParams) (i
 	row := q.db.QueryRowContext(ctx, createArticle,
 		arg.Title,
 		arg.Thumbnail,
+		arg.ThumbnailHtml,
 		arg.Slug,
 		arg.Content,
+		arg.ContentHtml,
 		pq.Array(arg.Tags),
+		arg.Bibliography,
 		arg.PublicationDate,
 	)
 	var id int64
@@ -41,19 +101,166 @@ func (q *Queries) CreateArticle(ctx context.Context, arg CreateArticleParams) (i
 	return id, err
 }
 
+const createArticleEvent = `-- name: CreateArticleEvent :exec
+INSERT INTO article_events (operation, article_id, payload)
+VALUES ($1, $2, $3)
+`
+
+type CreateArticleEventParams struct {
+	Operation string
+	ArticleID int64
+	Payload   json.RawMessage
+}
+
+func (q *Queries) CreateArticleEvent(ctx context.Context, arg CreateArticleEventParams) error {
+	_, err := q.db.ExecContext(ctx, createArticleEvent, arg.Operation, arg.ArticleID, arg.Payload)
+	return err
+}
+
+const createArticleLink = `-- name: CreateArticleLink :exec
+INSERT INTO article_links (source_id, target_slug)
+VALUES ($1, $2)
+`
+
+type CreateArticleLinkParams struct {
+	SourceID   int64
+	TargetSlug string
+}
+
+func (q *Queries) CreateArticleLink(ctx context.Context, arg CreateArticleLinkParams) error {
+	_, err := q.db.ExecContext(ctx, createArticleLink, arg.SourceID, arg.TargetSlug)
+	return err
+}
+
+const createArticleWithID = `-- name: CreateArticleWithID :exec
+INSERT INTO articles (id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+`
+
+type CreateArticleWithIDParams struct {
+	ID              int64
+	Title           string
+	Thumbnail       string
+	ThumbnailHtml   string
+	Slug            string
+	Content         string
+	ContentHtml     string
+	Tags            []string
+	Bibliography    json.RawMessage
+	PublicationDate time.Time
+}
+
+func (q *Queries) CreateArticleWithID(ctx context.Context, arg CreateArticleWithIDParams) error {
+	_, err := q.db.ExecContext(ctx, createArticleWithID,
+		arg.ID,
+		arg.Title,
+		arg.Thumbnail,
+		arg.ThumbnailHtml,
+		arg.Slug,
+		arg.Content,
+		arg.ContentHtml,
+		pq.Array(arg.Tags),
+		arg.Bibliography,
+		arg.PublicationDate,
+	)
+	return err
+}
+
+const createMedia = `-- name: CreateMedia :one
+INSERT INTO media (hash, name, content_type, size, width, height)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, hash, name, content_type, size, width, height, created_at
+`
+
+type CreateMediaParams struct {
+	Hash        string
+	Name        string
+	ContentType string
+	Size        int64
+	Width       int32
+	Height      int32
+}
+
+func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Medium, error) {
+	row := q.db.QueryRowContext(ctx, createMedia,
+		arg.Hash,
+		arg.Name,
+		arg.ContentType,
+		arg.Size,
+		arg.Width,
+		arg.Height,
+	)
+	var i Medium
+	err := row.Scan(
+		&i.ID,
+		&i.Hash,
+		&i.Name,
+		&i.ContentType,
+		&i.Size,
+		&i.Width,
+		&i.Height,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createMediaVariant = `-- name: CreateMediaVariant :one
+INSERT INTO media_variants (media_id, hash, name, content_type, size, width, height)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, media_id, hash, name, content_type, size, width, height, created_at
+`
+
+type CreateMediaVariantParams struct {
+	MediaID     int64
+	Hash        string
+	Name        string
+	ContentType string
+	Size        int64
+	Width       int32
+	Height      int32
+}
+
+func (q *Queries) CreateMediaVariant(ctx context.Context, arg CreateMediaVariantParams) (MediaVariant, error) {
+	row := q.db.QueryRowContext(ctx, createMediaVariant,
+		arg.MediaID,
+		arg.Hash,
+		arg.Name,
+		arg.ContentType,
+		arg.Size,
+		arg.Width,
+		arg.Height,
+	)
+	var i MediaVariant
+	err := row.Scan(
+		&i.ID,
+		&i.MediaID,
+		&i.Hash,
+		&i.Name,
+		&i.ContentType,
+		&i.Size,
+		&i.Width,
+		&i.Height,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const deleteArticleByID = `-- name: DeleteArticleByID :one
 DELETE FROM articles
 WHERE id = $1
-RETURNING id, title, thumbnail, slug, content, tags, publication_date
+RETURNING id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date
 `
 
 type DeleteArticleByIDRow struct {
 	ID              int64
 	Title           string
 	Thumbnail       string
+	ThumbnailHtml   string
 	Slug            string
 	Content         string
+	ContentHtml     string
 	Tags            []string
+	Bibliography    json.RawMessage
 	PublicationDate time.Time
 }
 
@@ -64,16 +271,90 @@ func (q *Queries) DeleteArticleByID(ctx context.Context, id int64) (DeleteArticl
 		&i.ID,
 		&i.Title,
 		&i.Thumbnail,
+		&i.ThumbnailHtml,
 		&i.Slug,
 		&i.Content,
+		&i.ContentHtml,
 		pq.Array(&i.Tags),
+		&i.Bibliography,
 		&i.PublicationDate,
 	)
 	return i, err
 }
 
+const deleteArticleLinks = `-- name: DeleteArticleLinks :exec
+DELETE FROM article_links
+WHERE source_id = $1
+`
+
+func (q *Queries) DeleteArticleLinks(ctx context.Context, sourceID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticleLinks, sourceID)
+	return err
+}
+
+const deleteDeliveredArticleEvents = `-- name: DeleteDeliveredArticleEvents :execrows
+DELETE FROM article_events
+WHERE status = 'delivered'
+  AND delivered_at < CURRENT_TIMESTAMP - make_interval(secs => $1::float8)
+`
+
+func (q *Queries) DeleteDeliveredArticleEvents(ctx context.Context, ageSeconds float64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDeliveredArticleEvents, ageSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllArticleSummaries = `-- name: GetAllArticleSummaries :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+ORDER BY id ASC
+`
+
+type GetAllArticleSummariesRow struct {
+	ID              int64
+	Title           string
+	Thumbnail       string
+	ThumbnailHtml   string
+	Slug            string
+	Tags            []string
+	PublicationDate time.Time
+}
+
+func (q *Queries) GetAllArticleSummaries(ctx context.Context) ([]GetAllArticleSummariesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllArticleSummaries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllArticleSummariesRow
+	for rows.Next() {
+		var i GetAllArticleSummariesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			pq.Array(&i.Tags),
+			&i.PublicationDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllArticles = `-- name: GetAllArticles :many
-SELECT id, title, thumbnail, slug, content, tags, publication_date, created_at, updated_at FROM articles
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
+ORDER BY id ASC
 `
 
 func (q *Queries) GetAllArticles(ctx context.Context) ([]Article, error) {
@@ -89,9 +370,12 @@ func (q *Queries) GetAllArticles(ctx context.Context) ([]Article, error) {
 			&i.ID,
 			&i.Title,
 			&i.Thumbnail,
+			&i.ThumbnailHtml,
 			&i.Slug,
 			&i.Content,
+			&i.ContentHtml,
 			pq.Array(&i.Tags),
+			&i.Bibliography,
 			&i.PublicationDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
@@ -109,6 +393,43 @@ func (q *Queries) GetAllArticles(ctx context.Context) ([]Article, error) {
 	return items, nil
 }
 
+const getAllMedia = `-- name: GetAllMedia :many
+SELECT id, hash, name, content_type, size, width, height, created_at FROM media
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetAllMedia(ctx context.Context) ([]Medium, error) {
+	rows, err := q.db.QueryContext(ctx, getAllMedia)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Medium
+	for rows.Next() {
+		var i Medium
+		if err := rows.Scan(
+			&i.ID,
+			&i.Hash,
+			&i.Name,
+			&i.ContentType,
+			&i.Size,
+			&i.Width,
+			&i.Height,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllTags = `-- name: GetAllTags :many
 SELECT DISTINCT unnest(tags)::TEXT AS unique_tag
 FROM articles
@@ -139,8 +460,90 @@ func (q *Queries) GetAllTags(ctx context.Context) ([]string, error) {
 	return items, nil
 }
 
+const getArticleArchive = `-- name: GetArticleArchive :many
+SELECT CAST(EXTRACT(YEAR FROM publication_date AT TIME ZONE 'UTC') AS INTEGER) AS year,
+       CAST(EXTRACT(MONTH FROM publication_date AT TIME ZONE 'UTC') AS INTEGER) AS month,
+       COUNT(*) AS article_count
+FROM articles
+GROUP BY year, month
+ORDER BY year DESC, month DESC
+`
+
+type GetArticleArchiveRow struct {
+	Year         int32
+	Month        int32
+	ArticleCount int64
+}
+
+func (q *Queries) GetArticleArchive(ctx context.Context) ([]GetArticleArchiveRow, error) {
+	rows, err := q.db.QueryContext(ctx, getArticleArchive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetArticleArchiveRow
+	for rows.Next() {
+		var i GetArticleArchiveRow
+		if err := rows.Scan(&i.Year, &i.Month, &i.ArticleCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArticleBacklinks = `-- name: GetArticleBacklinks :many
+SELECT articles.id, articles.title, articles.thumbnail, articles.thumbnail_html, articles.slug, articles.content, articles.content_html, articles.tags, articles.bibliography, articles.publication_date, articles.created_at, articles.updated_at FROM articles
+JOIN article_links ON article_links.source_id = articles.id
+WHERE article_links.target_slug = $1
+  AND articles.slug != article_links.target_slug
+ORDER BY articles.publication_date DESC, articles.id
+`
+
+func (q *Queries) GetArticleBacklinks(ctx context.Context, targetSlug string) ([]Article, error) {
+	rows, err := q.db.QueryContext(ctx, getArticleBacklinks, targetSlug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Article
+	for rows.Next() {
+		var i Article
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			&i.Content,
+			&i.ContentHtml,
+			pq.Array(&i.Tags),
+			&i.Bibliography,
+			&i.PublicationDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getArticleByID = `-- name: GetArticleByID :one
-SELECT id, title, thumbnail, slug, content, tags, publication_date, created_at, updated_at FROM articles
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
 WHERE id = $1 LIMIT 1
 `
 
@@ -151,9 +554,12 @@ func (q *Queries) GetArticleByID(ctx context.Context, id int64) (Article, error)
 		&i.ID,
 		&i.Title,
 		&i.Thumbnail,
+		&i.ThumbnailHtml,
 		&i.Slug,
 		&i.Content,
+		&i.ContentHtml,
 		pq.Array(&i.Tags),
+		&i.Bibliography,
 		&i.PublicationDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
@@ -162,7 +568,7 @@ func (q *Queries) GetArticleByID(ctx context.Context, id int64) (Article, error)
 }
 
 const getArticleBySlug = `-- name: GetArticleBySlug :one
-SELECT id, title, thumbnail, slug, content, tags, publication_date, created_at, updated_at FROM articles
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
 WHERE slug = $1 LIMIT 1
 `
 
@@ -173,9 +579,12 @@ func (q *Queries) GetArticleBySlug(ctx context.Context, slug string) (Article, e
 		&i.ID,
 		&i.Title,
 		&i.Thumbnail,
+		&i.ThumbnailHtml,
 		&i.Slug,
 		&i.Content,
+		&i.ContentHtml,
 		pq.Array(&i.Tags),
+		&i.Bibliography,
 		&i.PublicationDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
@@ -183,9 +592,158 @@ func (q *Queries) GetArticleBySlug(ctx context.Context, slug string) (Article, e
 	return i, err
 }
 
+const getArticleSummariesByPublicationDate = `-- name: GetArticleSummariesByPublicationDate :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE ($1::timestamptz IS NULL OR publication_date >= $1)
+  AND ($2::timestamptz IS NULL OR publication_date < $2)
+ORDER BY publication_date ASC, id ASC
+`
+
+type GetArticleSummariesByPublicationDateParams struct {
+	FromDate sql.NullTime
+	ToDate   sql.NullTime
+}
+
+type GetArticleSummariesByPublicationDateRow struct {
+	ID              int64
+	Title           string
+	Thumbnail       string
+	ThumbnailHtml   string
+	Slug            string
+	Tags            []string
+	PublicationDate time.Time
+}
+
+func (q *Queries) GetArticleSummariesByPublicationDate(ctx context.Context, arg GetArticleSummariesByPublicationDateParams) ([]GetArticleSummariesByPublicationDateRow, error) {
+	rows, err := q.db.QueryContext(ctx, getArticleSummariesByPublicationDate, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetArticleSummariesByPublicationDateRow
+	for rows.Next() {
+		var i GetArticleSummariesByPublicationDateRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			pq.Array(&i.Tags),
+			&i.PublicationDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArticleSummariesByTags = `-- name: GetArticleSummariesByTags :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE tags && $1::text[]
+ORDER BY id ASC
+`
+
+type GetArticleSummariesByTagsRow struct {
+	ID              int64
+	Title           string
+	Thumbnail       string
+	ThumbnailHtml   string
+	Slug            string
+	Tags            []string
+	PublicationDate time.Time
+}
+
+func (q *Queries) GetArticleSummariesByTags(ctx context.Context, tags []string) ([]GetArticleSummariesByTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getArticleSummariesByTags, pq.Array(tags))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetArticleSummariesByTagsRow
+	for rows.Next() {
+		var i GetArticleSummariesByTagsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			pq.Array(&i.Tags),
+			&i.PublicationDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArticlesByPublicationDate = `-- name: GetArticlesByPublicationDate :many
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
+WHERE ($1::timestamptz IS NULL OR publication_date >= $1)
+  AND ($2::timestamptz IS NULL OR publication_date < $2)
+ORDER BY publication_date ASC, id ASC
+`
+
+type GetArticlesByPublicationDateParams struct {
+	FromDate sql.NullTime
+	ToDate   sql.NullTime
+}
+
+func (q *Queries) GetArticlesByPublicationDate(ctx context.Context, arg GetArticlesByPublicationDateParams) ([]Article, error) {
+	rows, err := q.db.QueryContext(ctx, getArticlesByPublicationDate, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Article
+	for rows.Next() {
+		var i Article
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Thumbnail,
+			&i.ThumbnailHtml,
+			&i.Slug,
+			&i.Content,
+			&i.ContentHtml,
+			pq.Array(&i.Tags),
+			&i.Bibliography,
+			&i.PublicationDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getArticlesByTags = `-- name: GetArticlesByTags :many
-SELECT id, title, thumbnail, slug, content, tags, publication_date, created_at, updated_at FROM articles
+SELECT id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date, created_at, updated_at FROM articles
 WHERE tags && $1::text[]
+ORDER BY id ASC
 `
 
 func (q *Queries) GetArticlesByTags(ctx context.Context, tags []string) ([]Article, error) {
@@ -201,9 +759,12 @@ func (q *Queries) GetArticlesByTags(ctx context.Context, tags []string) ([]Artic
 			&i.ID,
 			&i.Title,
 			&i.Thumbnail,
+			&i.ThumbnailHtml,
 			&i.Slug,
 			&i.Content,
+			&i.ContentHtml,
 			pq.Array(&i.Tags),
+			&i.Bibliography,
 			&i.PublicationDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
@@ -221,24 +782,159 @@ func (q *Queries) GetArticlesByTags(ctx context.Context, tags []string) ([]Artic
 	return items, nil
 }
 
+const getMediaByHash = `-- name: GetMediaByHash :one
+SELECT id, hash, name, content_type, size, width, height, created_at FROM media
+WHERE hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetMediaByHash(ctx context.Context, hash string) (Medium, error) {
+	row := q.db.QueryRowContext(ctx, getMediaByHash, hash)
+	var i Medium
+	err := row.Scan(
+		&i.ID,
+		&i.Hash,
+		&i.Name,
+		&i.ContentType,
+		&i.Size,
+		&i.Width,
+		&i.Height,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getMediaVariantByHash = `-- name: GetMediaVariantByHash :one
+SELECT id, media_id, hash, name, content_type, size, width, height, created_at FROM media_variants
+WHERE hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetMediaVariantByHash(ctx context.Context, hash string) (MediaVariant, error) {
+	row := q.db.QueryRowContext(ctx, getMediaVariantByHash, hash)
+	var i MediaVariant
+	err := row.Scan(
+		&i.ID,
+		&i.MediaID,
+		&i.Hash,
+		&i.Name,
+		&i.ContentType,
+		&i.Size,
+		&i.Width,
+		&i.Height,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getMediaVariantsByMediaID = `-- name: GetMediaVariantsByMediaID :many
+SELECT id, media_id, hash, name, content_type, size, width, height, created_at FROM media_variants
+WHERE media_id = $1
+ORDER BY width ASC
+`
+
+func (q *Queries) GetMediaVariantsByMediaID(ctx context.Context, mediaID int64) ([]MediaVariant, error) {
+	rows, err := q.db.QueryContext(ctx, getMediaVariantsByMediaID, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaVariant
+	for rows.Next() {
+		var i MediaVariant
+		if err := rows.Scan(
+			&i.ID,
+			&i.MediaID,
+			&i.Hash,
+			&i.Name,
+			&i.ContentType,
+			&i.Size,
+			&i.Width,
+			&i.Height,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markArticleEventDelivered = `-- name: MarkArticleEventDelivered :exec
+UPDATE article_events
+SET status = 'delivered',
+    delivered_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (q *Queries) MarkArticleEventDelivered(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markArticleEventDelivered, id)
+	return err
+}
+
+const markArticleEventFailed = `-- name: MarkArticleEventFailed :exec
+UPDATE article_events
+SET status = $1,
+    attempts = attempts + 1,
+    last_error = $2,
+    next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $3::float8)
+WHERE id = $4
+`
+
+type MarkArticleEventFailedParams struct {
+	Status       string
+	LastError    string
+	RetrySeconds float64
+	ID           int64
+}
+
+func (q *Queries) MarkArticleEventFailed(ctx context.Context, arg MarkArticleEventFailedParams) error {
+	_, err := q.db.ExecContext(ctx, markArticleEventFailed,
+		arg.Status,
+		arg.LastError,
+		arg.RetrySeconds,
+		arg.ID,
+	)
+	return err
+}
+
+const resetArticleIDSequence = `-- name: ResetArticleIDSequence :exec
+SELECT setval(pg_get_serial_sequence('articles', 'id'), (SELECT MAX(id) FROM articles))
+`
+
+func (q *Queries) ResetArticleIDSequence(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, resetArticleIDSequence)
+	return err
+}
+
 const updateArticleByID = `-- name: UpdateArticleByID :one
 UPDATE articles
 SET title = $1,
     thumbnail = $2,
-    slug = $3,
-    content = $4,
-    tags = $5,
-    publication_date = $6
-WHERE id = $7
-RETURNING id, title, thumbnail, slug, content, tags, publication_date
+    thumbnail_html = $3,
+    slug = $4,
+    content = $5,
+    content_html = $6,
+    tags = $7,
+    bibliography = $8,
+    publication_date = $9
+WHERE id = $10
+RETURNING id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date
 `
 
 type UpdateArticleByIDParams struct {
 	Title           string
 	Thumbnail       string
+	ThumbnailHtml   string
 	Slug            string
 	Content         string
+	ContentHtml     string
 	Tags            []string
+	Bibliography    json.RawMessage
 	PublicationDate time.Time
 	ID              int64
 }
@@ -247,9 +943,12 @@ type UpdateArticleByIDRow struct {
 	ID              int64
 	Title           string
 	Thumbnail       string
+	ThumbnailHtml   string
 	Slug            string
 	Content         string
+	ContentHtml     string
 	Tags            []string
+	Bibliography    json.RawMessage
 	PublicationDate time.Time
 }
 
@@ -257,9 +956,12 @@ func (q *Queries) UpdateArticleByID(ctx context.Context, arg UpdateArticleByIDPa
 	row := q.db.QueryRowContext(ctx, updateArticleByID,
 		arg.Title,
 		arg.Thumbnail,
+		arg.ThumbnailHtml,
 		arg.Slug,
 		arg.Content,
+		arg.ContentHtml,
 		pq.Array(arg.Tags),
+		arg.Bibliography,
 		arg.PublicationDate,
 		arg.ID,
 	)
@@ -268,10 +970,31 @@ func (q *Queries) UpdateArticleByID(ctx context.Context, arg UpdateArticleByIDPa
 		&i.ID,
 		&i.Title,
 		&i.Thumbnail,
+		&i.ThumbnailHtml,
 		&i.Slug,
 		&i.Content,
+		&i.ContentHtml,
 		pq.Array(&i.Tags),
+		&i.Bibliography,
 		&i.PublicationDate,
 	)
 	return i, err
 }
+
+const updateMediaDimensions = `-- name: UpdateMediaDimensions :exec
+UPDATE media
+SET width = $1,
+    height = $2
+WHERE id = $3
+`
+
+type UpdateMediaDimensionsParams struct {
+	Width  int32
+	Height int32
+	ID     int64
+}
+
+func (q *Queries) UpdateMediaDimensions(ctx context.Context, arg UpdateMediaDimensionsParams) error {
+	_, err := q.db.ExecContext(ctx, updateMediaDimensions, arg.Width, arg.Height, arg.ID)
+	return err
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jannawro/blog/middleware"
+)
+
+// replicaRetryInterval is how long a replica that failed a query is left out before reads are sent to it again.
+const replicaRetryInterval = 30 * time.Second
+
+type replica struct {
+	index int
+	q     *Queries
+	// downUntil is the time, in Unix nanoseconds, until which the replica is skipped
+	downUntil atomic.Int64
+}
+
+func newReplicas(dbs []*sql.DB) []*replica {
+	replicas := make([]*replica, len(dbs))
+	for i, db := range dbs {
+		replicas[i] = &replica{index: i, q: New(db)}
+	}
+	return replicas
+}
+
+func (r *replica) healthy(now time.Time) bool {
+	return r.downUntil.Load() <= now.UnixNano()
+}
+
+func (r *replica) markDown(now time.Time) {
+	r.downUntil.Store(now.Add(replicaRetryInterval).UnixNano())
+}
+
+// pickReplica returns the next healthy replica in round robin order, or nil when the read has to go to the primary.
+func (r *Repository) pickReplica(ctx context.Context) *replica {
+	if len(r.replicas) == 0 || middleware.PrimaryReadsFromCtx(ctx) {
+		return nil
+	}
+
+	now := time.Now()
+	start := r.nextReplica.Add(1)
+	for i := range uint64(len(r.replicas)) {
+		replica := r.replicas[(start+i)%uint64(len(r.replicas))]
+		if replica.healthy(now) {
+			return replica
+		}
+	}
+	return nil
+}
+
+// read runs query against a replica, falling back to the primary when no replica is healthy or the chosen one
+// fails. A missing row isn't a failure, the replica may just lag behind, and neither is the caller cancelling
+// ctx. A replica running past the deadline of ctx is marked down, but there's no time left to read from the
+// primary.
+func (r *Repository) read(ctx context.Context, query func(q *Queries) error) error {
+	replica := r.pickReplica(ctx)
+	if replica == nil {
+		return query(r.q)
+	}
+
+	err := query(replica.q)
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(ctx.Err(), context.Canceled) {
+		return err
+	}
+	replica.markDown(time.Now())
+	if ctx.Err() != nil {
+		return err
+	}
+	slog.Warn("Read replica failed, reading from the primary instead", "replica", replica.index, "error", err)
+	return query(r.q)
+}
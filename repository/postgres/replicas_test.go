@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jannawro/blog/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaRouting(t *testing.T) {
+	primary := New(nil)
+	newRepository := func(replicas int) *Repository {
+		return &Repository{q: primary, replicas: newReplicas(make([]*sql.DB, replicas))}
+	}
+	// readFrom returns which database served the read, -1 standing for the primary
+	readFrom := func(r *Repository, ctx context.Context, failing ...int) (int, error) {
+		served := -1
+		err := r.read(ctx, func(q *Queries) error {
+			served = -1
+			for _, replica := range r.replicas {
+				if replica.q == q {
+					served = replica.index
+				}
+			}
+			for _, index := range failing {
+				if served == index {
+					return errors.New("connection refused")
+				}
+			}
+			return nil
+		})
+		return served, err
+	}
+	ctx := context.Background()
+
+	t.Run("Without replicas reads go to the primary", func(t *testing.T) {
+		served, err := readFrom(newRepository(0), ctx)
+		require.NoError(t, err)
+		assert.Equal(t, -1, served)
+	})
+
+	t.Run("Reads are spread over the replicas", func(t *testing.T) {
+		r := newRepository(2)
+		seen := make(map[int]int)
+		for range 4 {
+			served, err := readFrom(r, ctx)
+			require.NoError(t, err)
+			seen[served]++
+		}
+		assert.Equal(t, map[int]int{0: 2, 1: 2}, seen)
+	})
+
+	t.Run("Primary reads skip the replicas", func(t *testing.T) {
+		served, err := readFrom(newRepository(2), middleware.WithPrimaryReads(ctx))
+		require.NoError(t, err)
+		assert.Equal(t, -1, served)
+	})
+
+	t.Run("A failing replica falls back to the primary and is left out", func(t *testing.T) {
+		r := newRepository(2)
+		r.nextReplica.Store(1) // the next read goes to replica 0
+
+		served, err := readFrom(r, ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, -1, served)
+		assert.False(t, r.replicas[0].healthy(time.Now()))
+
+		for range 3 {
+			served, err := readFrom(r, ctx)
+			require.NoError(t, err)
+			assert.Equal(t, 1, served)
+		}
+
+		assert.True(t, r.replicas[0].healthy(time.Now().Add(replicaRetryInterval)))
+	})
+
+	t.Run("Missing rows don't mark the replica down", func(t *testing.T) {
+		r := newRepository(1)
+		err := r.read(ctx, func(q *Queries) error {
+			return sql.ErrNoRows
+		})
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.True(t, r.replicas[0].healthy(time.Now()))
+	})
+
+	t.Run("A replica running past the deadline is left out", func(t *testing.T) {
+		r := newRepository(1)
+		ctx, cancel := context.WithDeadline(ctx, time.Now())
+		defer cancel()
+
+		err := r.read(ctx, func(q *Queries) error {
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.False(t, r.replicas[0].healthy(time.Now()))
+	})
+
+	t.Run("Cancelled reads don't mark the replica down", func(t *testing.T) {
+		r := newRepository(1)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := r.read(ctx, func(q *Queries) error {
+			return ctx.Err()
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.True(t, r.replicas[0].healthy(time.Now()))
+	})
+
+	t.Run("Reads go to the primary when every replica is down", func(t *testing.T) {
+		r := newRepository(2)
+		for _, replica := range r.replicas {
+			replica.markDown(time.Now())
+		}
+
+		served, err := readFrom(r, ctx)
+		require.NoError(t, err)
+		assert.Equal(t, -1, served)
+	})
+}
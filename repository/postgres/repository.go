@@ -3,9 +3,13 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io/fs"
 	"log/slog"
+	"sync/atomic"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -13,7 +17,7 @@ import (
 	"github.com/jannawro/blog/article"
 	"github.com/jannawro/blog/middleware"
 	"github.com/jannawro/blog/repository"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 const DBDriver = "postgres"
@@ -21,15 +25,20 @@ const DBDriver = "postgres"
 type Repository struct {
 	db *sql.DB
 	q  *Queries
+	// queryTimeout bounds every call, see repository.Options
+	queryTimeout time.Duration
+	// replicas serve reads unless the request needs to see its own writes, see middleware.PrimaryReads
+	replicas    []*replica
+	nextReplica atomic.Uint64
 }
 
-func NewDatabase(connString string) (*sql.DB, error) {
+func NewDatabase(ctx context.Context, connString string) (*sql.DB, error) {
 	db, err := sql.Open(DBDriver, connString)
 	if err != nil {
 		return nil, errors.Join(repository.ErrDatabaseConnectionFailed, err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := db.PingContext(ctx); err != nil {
 		closeErr := db.Close()
 		if closeErr != nil {
 			return nil, errors.Join(repository.ErrPingFailed, err, closeErr)
@@ -40,38 +49,52 @@ func NewDatabase(connString string) (*sql.DB, error) {
 	return db, nil
 }
 
-func NewRepository(db *sql.DB, migrationFiles fs.FS) (*Repository, error) {
-	// Run migration
-	if err := runMigration(db, migrationFiles); err != nil {
-		closeErr := db.Close()
-		if closeErr != nil {
-			return nil, errors.Join(repository.ErrMigrationFailed, err, closeErr)
+func NewRepository(db *sql.DB, migrationFiles fs.FS, options repository.Options) (*Repository, error) {
+	if !options.SkipMigrations {
+		if err := runMigration(db, migrationFiles); err != nil {
+			closeErr := db.Close()
+			if closeErr != nil {
+				return nil, errors.Join(repository.ErrMigrationFailed, err, closeErr)
+			}
+			return nil, errors.Join(repository.ErrMigrationFailed, err)
 		}
-		return nil, errors.Join(repository.ErrMigrationFailed, err)
 	}
 	return &Repository{
-		db: db,
-		q:  New(db),
+		db:           db,
+		q:            New(db),
+		queryTimeout: options.QueryTimeout,
+		replicas:     newReplicas(options.Replicas),
 	}, nil
 }
 
-func runMigration(db *sql.DB, migrationFiles fs.FS) error {
+// NewMigration prepares the migrations in migrationFiles for running against db. Closing the returned instance
+// closes db as well.
+func NewMigration(db *sql.DB, migrationFiles fs.FS) (*migrate.Migrate, error) {
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		return errors.Join(repository.ErrDriverCreationFailed, err)
+		return nil, errors.Join(repository.ErrDriverCreationFailed, err)
 	}
 
 	// Create an embed source for the migration
 	embedSource, err := iofs.New(migrationFiles, ".")
 	if err != nil {
-		return errors.Join(repository.ErrEmbedFailed, err)
+		return nil, errors.Join(repository.ErrEmbedFailed, err)
 	}
 
 	m, err := migrate.NewWithInstance(
 		"iofs", embedSource,
 		DBDriver, driver)
 	if err != nil {
-		return errors.Join(repository.ErrMigrationInstanceFailed, err)
+		return nil, errors.Join(repository.ErrMigrationInstanceFailed, err)
+	}
+
+	return m, nil
+}
+
+func runMigration(db *sql.DB, migrationFiles fs.FS) error {
+	m, err := NewMigration(db, migrationFiles)
+	if err != nil {
+		return err
 	}
 
 	// Run the migration
@@ -82,7 +105,10 @@ func runMigration(db *sql.DB, migrationFiles fs.FS) error {
 	return nil
 }
 
-func (r *Repository) Create(ctx context.Context, article article.Article) (*article.Article, error) {
+func (r *Repository) Create(ctx context.Context, a article.Article) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -97,18 +123,69 @@ func (r *Repository) Create(ctx context.Context, article article.Article) (*arti
 
 	qtx := r.q.WithTx(tx)
 	id, err := qtx.CreateArticle(ctx, CreateArticleParams{
+		Title:           a.Title,
+		Thumbnail:       a.Thumbnail,
+		ThumbnailHtml:   a.ThumbnailHTML,
+		Slug:            a.Slug,
+		Content:         a.Content,
+		ContentHtml:     a.ContentHTML,
+		Tags:            a.Tags,
+		Bibliography:    referencesToJSON(a.References),
+		PublicationDate: a.PublicationDate,
+	})
+	if err != nil {
+		return nil, duplicateSlug(err)
+	}
+
+	a.ID = id
+	if err := recordEvent(ctx, qtx, article.ChangeCreated, a); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// CreateWithID creates article under article.ID instead of the next free ID. It's meant for copying articles
+// between databases.
+func (r *Repository) CreateWithID(ctx context.Context, article article.Article) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err != sql.ErrTxDone {
+				slog.Error(errors.Join(repository.ErrTxRollbackFailed, err).Error(), "requestID", middleware.ReqIDFromCtx(ctx))
+			}
+		}
+	}()
+
+	qtx := r.q.WithTx(tx)
+	err = qtx.CreateArticleWithID(ctx, CreateArticleWithIDParams{
+		ID:              article.ID,
 		Title:           article.Title,
 		Thumbnail:       article.Thumbnail,
+		ThumbnailHtml:   article.ThumbnailHTML,
 		Slug:            article.Slug,
 		Content:         article.Content,
+		ContentHtml:     article.ContentHTML,
 		Tags:            article.Tags,
+		Bibliography:    referencesToJSON(article.References),
 		PublicationDate: article.PublicationDate,
 	})
 	if err != nil {
+		return nil, duplicateSlug(err)
+	}
+	// Later articles get IDs after the highest one, the sequence doesn't know about the inserted ID
+	if err := qtx.ResetArticleIDSequence(ctx); err != nil {
 		return nil, err
 	}
-
-	article.ID = id
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -117,81 +194,211 @@ func (r *Repository) Create(ctx context.Context, article article.Article) (*arti
 }
 
 func (r *Repository) GetAll(ctx context.Context) (article.Articles, error) {
-	dbArticles, err := r.q.GetAllArticles(ctx)
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var dbArticles []Article
+	err := r.read(ctx, func(q *Queries) (err error) {
+		dbArticles, err = q.GetAllArticles(ctx)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	articlesSlice := make(article.Articles, len(dbArticles))
-	for i, a := range dbArticles {
-		articlesSlice[i] = article.Article{
-			ID:              a.ID,
-			Title:           a.Title,
-			Thumbnail:       a.Thumbnail,
-			Slug:            a.Slug,
-			Content:         a.Content,
-			Tags:            a.Tags,
-			PublicationDate: a.PublicationDate,
-		}
+	return toArticles(dbArticles)
+}
+
+func (r *Repository) GetByID(ctx context.Context, id int64) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var dbArticle Article
+	err := r.read(ctx, func(q *Queries) (err error) {
+		dbArticle, err = q.GetArticleByID(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, notFound(err)
 	}
 
-	return articlesSlice, nil
+	a, err := toArticle(dbArticle)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
 }
 
-func (r *Repository) GetByID(ctx context.Context, id int64) (*article.Article, error) {
-	dbArticle, err := r.q.GetArticleByID(ctx, id)
+func (r *Repository) GetBySlug(ctx context.Context, slug string) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var dbArticle Article
+	err := r.read(ctx, func(q *Queries) (err error) {
+		dbArticle, err = q.GetArticleBySlug(ctx, slug)
+		return err
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	a, err := toArticle(dbArticle)
 	if err != nil {
 		return nil, err
 	}
+	return &a, nil
+}
 
-	return &article.Article{
-		ID:              dbArticle.ID,
-		Title:           dbArticle.Title,
-		Thumbnail:       dbArticle.Thumbnail,
-		Slug:            dbArticle.Slug,
-		Content:         dbArticle.Content,
-		Tags:            dbArticle.Tags,
-		PublicationDate: dbArticle.PublicationDate,
-	}, nil
+func (r *Repository) GetByTags(ctx context.Context, tags []string) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var dbArticles []Article
+	err := r.read(ctx, func(q *Queries) (err error) {
+		dbArticles, err = q.GetArticlesByTags(ctx, tags)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toArticles(dbArticles)
 }
 
-func (r *Repository) GetBySlug(ctx context.Context, slug string) (*article.Article, error) {
-	dbArticle, err := r.q.GetArticleBySlug(ctx, slug)
+func (r *Repository) GetByPublicationDate(ctx context.Context, from, to time.Time) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var dbArticles []Article
+	err := r.read(ctx, func(q *Queries) (err error) {
+		dbArticles, err = q.GetArticlesByPublicationDate(ctx, GetArticlesByPublicationDateParams{
+			FromDate: nullTime(from),
+			ToDate:   nullTime(to),
+		})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &article.Article{
-		ID:              dbArticle.ID,
-		Title:           dbArticle.Title,
-		Thumbnail:       dbArticle.Thumbnail,
-		Slug:            dbArticle.Slug,
-		Content:         dbArticle.Content,
-		Tags:            dbArticle.Tags,
-		PublicationDate: dbArticle.PublicationDate,
-	}, nil
+	return toArticles(dbArticles)
 }
 
-func (r *Repository) GetByTags(ctx context.Context, tags []string) (article.Articles, error) {
-	dbArticles, err := r.q.GetArticlesByTags(ctx, tags)
+func (r *Repository) GetAllSummaries(ctx context.Context) (article.ArticleSummaries, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var rows []GetAllArticleSummariesRow
+	err := r.read(ctx, func(q *Queries) (err error) {
+		rows, err = q.GetAllArticleSummaries(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(article.ArticleSummaries, len(rows))
+	for i, s := range rows {
+		summaries[i] = article.ArticleSummary{
+			ID:              s.ID,
+			Title:           s.Title,
+			Thumbnail:       s.Thumbnail,
+			ThumbnailHTML:   s.ThumbnailHtml,
+			Slug:            s.Slug,
+			Tags:            s.Tags,
+			PublicationDate: s.PublicationDate,
+		}
+	}
+
+	return summaries, nil
+}
+
+func (r *Repository) GetSummariesByTags(ctx context.Context, tags []string) (article.ArticleSummaries, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var rows []GetArticleSummariesByTagsRow
+	err := r.read(ctx, func(q *Queries) (err error) {
+		rows, err = q.GetArticleSummariesByTags(ctx, tags)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(article.ArticleSummaries, len(rows))
+	for i, s := range rows {
+		summaries[i] = article.ArticleSummary{
+			ID:              s.ID,
+			Title:           s.Title,
+			Thumbnail:       s.Thumbnail,
+			ThumbnailHTML:   s.ThumbnailHtml,
+			Slug:            s.Slug,
+			Tags:            s.Tags,
+			PublicationDate: s.PublicationDate,
+		}
+	}
+
+	return summaries, nil
+}
+
+func (r *Repository) GetSummariesByPublicationDate(
+	ctx context.Context,
+	from, to time.Time,
+) (article.ArticleSummaries, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var rows []GetArticleSummariesByPublicationDateRow
+	err := r.read(ctx, func(q *Queries) (err error) {
+		rows, err = q.GetArticleSummariesByPublicationDate(ctx, GetArticleSummariesByPublicationDateParams{
+			FromDate: nullTime(from),
+			ToDate:   nullTime(to),
+		})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	articlesSlice := make(article.Articles, len(dbArticles))
-	for i, a := range dbArticles {
-		articlesSlice[i] = article.Article{
-			ID:              a.ID,
-			Title:           a.Title,
-			Thumbnail:       a.Thumbnail,
-			Slug:            a.Slug,
-			Content:         a.Content,
-			Tags:            a.Tags,
-			PublicationDate: a.PublicationDate,
+	summaries := make(article.ArticleSummaries, len(rows))
+	for i, s := range rows {
+		summaries[i] = article.ArticleSummary{
+			ID:              s.ID,
+			Title:           s.Title,
+			Thumbnail:       s.Thumbnail,
+			ThumbnailHTML:   s.ThumbnailHtml,
+			Slug:            s.Slug,
+			Tags:            s.Tags,
+			PublicationDate: s.PublicationDate,
 		}
 	}
 
-	return articlesSlice, nil
+	return summaries, nil
+}
+
+func (r *Repository) GetArchive(ctx context.Context) ([]article.ArchiveMonth, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var rows []GetArticleArchiveRow
+	err := r.read(ctx, func(q *Queries) (err error) {
+		rows, err = q.GetArticleArchive(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	months := make([]article.ArchiveMonth, len(rows))
+	for i, row := range rows {
+		months[i] = article.ArchiveMonth{
+			Year:  int(row.Year),
+			Month: time.Month(row.Month),
+			Count: int(row.ArticleCount),
+		}
+	}
+	return months, nil
 }
 
 func (r *Repository) Update(
@@ -199,6 +406,9 @@ func (r *Repository) Update(
 	id int64,
 	updated article.Article,
 ) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -212,35 +422,63 @@ func (r *Repository) Update(
 	}()
 
 	qtx := r.q.WithTx(tx)
+	previous, err := qtx.GetArticleByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
 	dbArticle, err := qtx.UpdateArticleByID(ctx, UpdateArticleByIDParams{
 		ID:              id,
 		Title:           updated.Title,
 		Thumbnail:       updated.Thumbnail,
+		ThumbnailHtml:   updated.ThumbnailHTML,
 		Slug:            updated.Slug,
 		Content:         updated.Content,
+		ContentHtml:     updated.ContentHTML,
 		Tags:            updated.Tags,
+		Bibliography:    referencesToJSON(updated.References),
 		PublicationDate: updated.PublicationDate,
 	})
 	if err != nil {
-		return nil, err
+		return nil, notFound(duplicateSlug(err))
 	}
 
-	if err := tx.Commit(); err != nil {
+	previousArticle, err := toArticle(previous)
+	if err != nil {
 		return nil, err
 	}
-
-	return &article.Article{
+	references, err := jsonToReferences(dbArticle.Bibliography)
+	if err != nil {
+		return nil, err
+	}
+	a := article.Article{
 		ID:              dbArticle.ID,
 		Title:           dbArticle.Title,
 		Thumbnail:       dbArticle.Thumbnail,
+		ThumbnailHTML:   dbArticle.ThumbnailHtml,
 		Slug:            dbArticle.Slug,
 		Content:         dbArticle.Content,
+		ContentHTML:     dbArticle.ContentHtml,
 		Tags:            dbArticle.Tags,
+		References:      references,
 		PublicationDate: dbArticle.PublicationDate,
-	}, nil
+	}
+	// Rendering an article again isn't recorded, the outbox only carries the markdown
+	if !a.SameMarkdown(previousArticle) {
+		if err := recordEvent(ctx, qtx, article.ChangeUpdated, a); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
 }
 
 func (r *Repository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -254,14 +492,170 @@ func (r *Repository) Delete(ctx context.Context, id int64) error {
 	}()
 
 	qtx := r.q.WithTx(tx)
-	_, err = qtx.DeleteArticleByID(ctx, id)
+	dbArticle, err := qtx.DeleteArticleByID(ctx, id)
 	if err != nil {
+		return notFound(err)
+	}
+
+	references, err := jsonToReferences(dbArticle.Bibliography)
+	if err != nil {
+		return err
+	}
+	deleted := article.Article{
+		ID:              dbArticle.ID,
+		Title:           dbArticle.Title,
+		Thumbnail:       dbArticle.Thumbnail,
+		ThumbnailHTML:   dbArticle.ThumbnailHtml,
+		Slug:            dbArticle.Slug,
+		Content:         dbArticle.Content,
+		ContentHTML:     dbArticle.ContentHtml,
+		Tags:            dbArticle.Tags,
+		References:      references,
+		PublicationDate: dbArticle.PublicationDate,
+	}
+	if err := recordEvent(ctx, qtx, article.ChangeDeleted, deleted); err != nil {
 		return err
 	}
+	return tx.Commit()
+}
+
+func (r *Repository) SetLinks(ctx context.Context, sourceID int64, targetSlugs []string) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err != sql.ErrTxDone {
+				slog.Error(errors.Join(repository.ErrTxRollbackFailed, err).Error(), "requestID", middleware.ReqIDFromCtx(ctx))
+			}
+		}
+	}()
+
+	qtx := r.q.WithTx(tx)
+	if err := qtx.DeleteArticleLinks(ctx, sourceID); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, slug := range targetSlugs {
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		if err := qtx.CreateArticleLink(ctx, CreateArticleLinkParams{
+			SourceID:   sourceID,
+			TargetSlug: slug,
+		}); err != nil {
+			return err
+		}
+	}
 
 	return tx.Commit()
 }
 
+func (r *Repository) GetBacklinks(ctx context.Context, slug string) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var dbArticles []Article
+	err := r.read(ctx, func(q *Queries) (err error) {
+		dbArticles, err = q.GetArticleBacklinks(ctx, slug)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toArticles(dbArticles)
+}
+
 func (r *Repository) GetAllTags(ctx context.Context) ([]string, error) {
-	return r.q.GetAllTags(ctx)
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var tags []string
+	err := r.read(ctx, func(q *Queries) (err error) {
+		tags, err = q.GetAllTags(ctx)
+		return err
+	})
+	return tags, err
+}
+
+// toArticle converts an article row, failing if a column holding JSON can't be decoded
+func toArticle(a Article) (article.Article, error) {
+	references, err := jsonToReferences(a.Bibliography)
+	if err != nil {
+		return article.Article{}, err
+	}
+	return article.Article{
+		ID:              a.ID,
+		Title:           a.Title,
+		Thumbnail:       a.Thumbnail,
+		ThumbnailHTML:   a.ThumbnailHtml,
+		Slug:            a.Slug,
+		Content:         a.Content,
+		ContentHTML:     a.ContentHtml,
+		Tags:            a.Tags,
+		References:      references,
+		PublicationDate: a.PublicationDate,
+	}, nil
+}
+
+func toArticles(rows []Article) (article.Articles, error) {
+	articles := make(article.Articles, len(rows))
+	for i, row := range rows {
+		a, err := toArticle(row)
+		if err != nil {
+			return nil, err
+		}
+		articles[i] = a
+	}
+	return articles, nil
+}
+
+// notFound marks a missing row as a missing article so callers can tell it apart from other failures.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.Join(article.ErrArticleNotFound, err)
+	}
+	return err
+}
+
+// duplicateSlug turns the unique violation of the slug index into repository.ErrDuplicateSlug
+func duplicateSlug(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_articles_slug" {
+		return errors.Join(repository.ErrDuplicateSlug, err)
+	}
+	return err
+}
+
+// nullTime turns the zero time into NULL, an open end of a range
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func referencesToJSON(references []article.Reference) json.RawMessage {
+	if references == nil {
+		references = []article.Reference{}
+	}
+	jsonReferences, err := json.Marshal(references)
+	if err != nil {
+		panic(err)
+	}
+	return jsonReferences
+}
+
+func jsonToReferences(j json.RawMessage) ([]article.Reference, error) {
+	var references []article.Reference
+	if err := json.Unmarshal(j, &references); err != nil {
+		return nil, errors.Join(repository.ErrInvalidArticle, fmt.Errorf("bibliography: %w", err))
+	}
+	if len(references) == 0 {
+		return nil, nil
+	}
+	return references, nil
 }
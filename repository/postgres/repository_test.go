@@ -3,12 +3,17 @@ package postgres_test
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/repository"
 	"github.com/jannawro/blog/repository/postgres"
 	"github.com/jannawro/blog/repository/postgres/migrations"
+	"github.com/jannawro/blog/repository/repotest"
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,7 +22,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
-func setupTestDatabase(t *testing.T) (*sql.DB, func()) {
+func setupTestDatabase(t *testing.T) (*sql.DB, string, func()) {
 	ctx := context.Background()
 
 	dbName := "postgres"
@@ -50,80 +55,239 @@ func setupTestDatabase(t *testing.T) (*sql.DB, func()) {
 		assert.NoError(t, err)
 	}
 
-	return db, cleanup
+	return db, connString, cleanup
 }
 
 func TestRepository(t *testing.T) {
-	db, cleanup := setupTestDatabase(t)
+	db, _, cleanup := setupTestDatabase(t)
 	defer cleanup()
 
-	repo, err := postgres.NewRepository(db, migrations.Files())
+	repo, err := postgres.NewRepository(db, migrations.Files(), repository.Options{})
 	require.NoError(t, err)
 
-	ctx := context.Background()
-
-	t.Run("Create and GetByID", func(t *testing.T) {
-		article := article.Article{
-			Title:           "Test Article",
-			Thumbnail:       "Test article thumbnail",
-			Slug:            "test-article",
-			Content:         "This is a test article",
-			Tags:            []string{"test", "golang"},
-			PublicationDate: time.Now().UTC().Truncate(time.Second),
-		}
+	repotest.Run(t, func(t *testing.T) article.ArticleRepository {
+		_, err := db.Exec("DELETE FROM articles")
+		require.NoError(t, err)
+		return repo
+	})
 
-		createdArticle, err := repo.Create(ctx, article)
+	t.Run("With replicas", func(t *testing.T) {
+		// The primary doubles as its own replica, reads take the replica code path without replication lag.
+		replicated, err := postgres.NewRepository(db, migrations.Files(), repository.Options{
+			SkipMigrations: true,
+			Replicas:       []*sql.DB{db},
+		})
 		require.NoError(t, err)
-		assert.NotZero(t, createdArticle.ID)
 
-		fetchedArticle, err := repo.GetByID(ctx, createdArticle.ID)
+		repotest.Run(t, func(t *testing.T) article.ArticleRepository {
+			_, err := db.Exec("DELETE FROM articles")
+			require.NoError(t, err)
+			return replicated
+		})
+	})
+}
+
+func TestEvents(t *testing.T) {
+	db, _, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo, err := postgres.NewRepository(db, migrations.Files(), repository.Options{})
+	require.NoError(t, err)
+
+	repotest.RunEvents(t, func(t *testing.T) repotest.EventRepository {
+		_, err := db.Exec("DELETE FROM articles")
 		require.NoError(t, err)
-		assert.Equal(t, createdArticle, fetchedArticle)
+		_, err = db.Exec("DELETE FROM article_events")
+		require.NoError(t, err)
+		return repo
 	})
 
-	t.Run("GetAll", func(t *testing.T) {
-		articles, err := repo.GetAll(ctx)
+	t.Run("Payloads that can't be decoded are dead", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := db.Exec("DELETE FROM articles")
+		require.NoError(t, err)
+		_, err = db.Exec("DELETE FROM article_events")
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO article_events (operation, article_id, payload) VALUES ('created', 0, '{"id": "zero"}')`)
 		require.NoError(t, err)
-		assert.NotEmpty(t, articles)
+		created, err := repo.Create(ctx, article.Article{Title: "Valid", Slug: "valid", PublicationDate: time.Now().UTC()})
+		require.NoError(t, err)
+
+		// The rest of the batch is still claimed
+		events, err := repo.ClaimEvents(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, created.ID, events[0].Article.ID)
+
+		var status, lastError string
+		var attempts int
+		err = db.QueryRow("SELECT status, attempts, last_error FROM article_events WHERE article_id = 0").
+			Scan(&status, &attempts, &lastError)
+		require.NoError(t, err)
+		assert.Equal(t, string(article.EventDead), status)
+		assert.Equal(t, 1, attempts)
+		assert.Contains(t, lastError, repository.ErrInvalidEvent.Error())
 	})
+}
+
+func TestMediaRepository(t *testing.T) {
+	db, _, cleanup := setupTestDatabase(t)
+	defer cleanup()
 
-	t.Run("GetBySlug", func(t *testing.T) {
-		article, err := repo.GetBySlug(ctx, "test-article")
+	_, err := postgres.NewRepository(db, migrations.Files(), repository.Options{})
+	require.NoError(t, err)
+	repo := postgres.NewMediaRepository(db, repository.Options{})
+
+	ctx := context.Background()
+
+	m := media.Media{
+		Hash:        strings.Repeat("ab", 32),
+		Name:        "photo.png",
+		ContentType: "image/png",
+		Size:        1024,
+	}
+
+	t.Run("Create and GetByHash", func(t *testing.T) {
+		created, err := repo.Create(ctx, m)
 		require.NoError(t, err)
-		assert.Equal(t, "Test Article", article.Title)
+		assert.NotZero(t, created.ID)
+		assert.NotZero(t, created.CreatedAt)
+
+		fetched, err := repo.GetByHash(ctx, m.Hash)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, fetched.ID)
+		assert.Equal(t, m.Name, fetched.Name)
+		assert.Equal(t, m.ContentType, fetched.ContentType)
+		assert.Equal(t, m.Size, fetched.Size)
+	})
+
+	t.Run("Create duplicate hash", func(t *testing.T) {
+		_, err := repo.Create(ctx, m)
+		assert.Error(t, err)
 	})
 
-	t.Run("GetByTags", func(t *testing.T) {
-		articles, err := repo.GetByTags(ctx, []string{"test"})
+	t.Run("GetAll", func(t *testing.T) {
+		all, err := repo.GetAll(ctx)
 		require.NoError(t, err)
-		assert.NotEmpty(t, articles)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("GetByHash missing", func(t *testing.T) {
+		_, err := repo.GetByHash(ctx, strings.Repeat("cd", 32))
+		assert.ErrorIs(t, err, media.ErrMediaNotFound)
 	})
 
-	t.Run("Update", func(t *testing.T) {
-		article, err := repo.GetBySlug(ctx, "test-article")
+	t.Run("UpdateDimensions", func(t *testing.T) {
+		created, err := repo.GetByHash(ctx, m.Hash)
 		require.NoError(t, err)
 
-		article.Title = "Updated Test Article"
-		updatedArticle, err := repo.Update(ctx, article.ID, *article)
+		require.NoError(t, repo.UpdateDimensions(ctx, created.ID, 1920, 1080))
+
+		fetched, err := repo.GetByHash(ctx, m.Hash)
 		require.NoError(t, err)
-		assert.Equal(t, "Updated Test Article", updatedArticle.Title)
+		assert.Equal(t, 1920, fetched.Width)
+		assert.Equal(t, 1080, fetched.Height)
 	})
 
-	t.Run("GetAllTags", func(t *testing.T) {
-		tags, err := repo.GetAllTags(ctx)
+	t.Run("Variants", func(t *testing.T) {
+		created, err := repo.GetByHash(ctx, m.Hash)
 		require.NoError(t, err)
-		assert.Contains(t, tags, "test")
-		assert.Contains(t, tags, "golang")
-	})
 
-	t.Run("Delete", func(t *testing.T) {
-		article, err := repo.GetBySlug(ctx, "test-article")
+		for _, width := range []int{640, 320} {
+			_, err := repo.CreateVariant(ctx, media.Variant{
+				MediaID:     created.ID,
+				Hash:        strings.Repeat(fmt.Sprintf("%02d", width/32), 32),
+				Name:        fmt.Sprintf("photo-%dw.png", width),
+				ContentType: "image/png",
+				Size:        int64(width),
+				Width:       width,
+				Height:      width / 2,
+			})
+			require.NoError(t, err)
+		}
+
+		variants, err := repo.GetVariants(ctx, created.ID)
 		require.NoError(t, err)
+		require.Len(t, variants, 2)
+		assert.Equal(t, 320, variants[0].Width)
+		assert.Equal(t, 160, variants[0].Height)
+		assert.Equal(t, 640, variants[1].Width)
 
-		err = repo.Delete(ctx, article.ID)
+		fetched, err := repo.GetVariantByHash(ctx, variants[1].Hash)
 		require.NoError(t, err)
+		assert.Equal(t, "photo-640w.png", fetched.Name)
+		assert.Equal(t, created.ID, fetched.MediaID)
+	})
+}
 
-		_, err = repo.GetByID(ctx, article.ID)
-		assert.Error(t, err)
+func TestListener(t *testing.T) {
+	db, connString, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo, err := postgres.NewRepository(db, migrations.Files(), repository.Options{})
+	require.NoError(t, err)
+	listener, err := postgres.NewListener(connString)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	changes := make(chan article.Change, 16)
+	unsubscribe := listener.Subscribe(func(change article.Change) { changes <- change })
+	defer unsubscribe()
+	next := func() article.Change {
+		t.Helper()
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change was published")
+			return article.Change{}
+		}
+	}
+
+	ctx := context.Background()
+	created, err := repo.Create(ctx, article.Article{
+		Title:           "Listened",
+		Slug:            "listened",
+		Tags:            []string{"go"},
+		PublicationDate: time.Now().UTC().Truncate(time.Second),
 	})
+	require.NoError(t, err)
+	assert.Equal(t, article.Change{
+		Operation: article.ChangeCreated,
+		ID:        created.ID,
+		Slug:      "listened",
+		Tags:      []string{"go"},
+	}, next())
+
+	renamed := *created
+	renamed.Slug = "renamed"
+	renamed.Tags = []string{"db"}
+	_, err = repo.Update(ctx, created.ID, renamed)
+	require.NoError(t, err)
+	assert.Equal(t, article.Change{
+		Operation:    article.ChangeUpdated,
+		ID:           created.ID,
+		Slug:         "renamed",
+		Tags:         []string{"db"},
+		PreviousSlug: "listened",
+		PreviousTags: []string{"go"},
+	}, next())
+
+	require.NoError(t, repo.SetLinks(ctx, created.ID, []string{"target"}))
+	assert.Equal(t, article.Change{Operation: article.ChangeLinks, ID: created.ID, Slug: "target"}, next())
+
+	require.NoError(t, repo.Delete(ctx, created.ID))
+	// Deleting the article deletes its links as well
+	for range 2 {
+		if change := next(); change.Operation == article.ChangeDeleted {
+			assert.Equal(t, article.Change{
+				Operation:    article.ChangeDeleted,
+				ID:           created.ID,
+				PreviousSlug: "renamed",
+				PreviousTags: []string{"db"},
+			}, change)
+		} else {
+			assert.Equal(t, article.ChangeLinks, change.Operation)
+		}
+	}
 }
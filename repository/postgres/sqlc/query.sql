@@ -1,10 +1,19 @@
 -- name: CreateArticle :one
-INSERT INTO articles (title, thumbnail, slug, content, tags, publication_date)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO articles (title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING id;
 
+-- name: CreateArticleWithID :exec
+INSERT INTO articles (id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
 -- name: GetAllArticles :many
-SELECT * FROM articles;
+SELECT * FROM articles
+ORDER BY id ASC;
+
+-- name: GetAllArticleSummaries :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+ORDER BY id ASC;
 
 -- name: GetArticleByID :one
 SELECT * FROM articles
@@ -16,7 +25,33 @@ WHERE slug = $1 LIMIT 1;
 
 -- name: GetArticlesByTags :many
 SELECT * FROM articles
-WHERE tags && sqlc.arg(tags)::text[];
+WHERE tags && sqlc.arg(tags)::text[]
+ORDER BY id ASC;
+
+-- name: GetArticleSummariesByTags :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE tags && sqlc.arg(tags)::text[]
+ORDER BY id ASC;
+
+-- name: GetArticlesByPublicationDate :many
+SELECT * FROM articles
+WHERE (sqlc.narg(from_date)::timestamptz IS NULL OR publication_date >= sqlc.narg(from_date))
+  AND (sqlc.narg(to_date)::timestamptz IS NULL OR publication_date < sqlc.narg(to_date))
+ORDER BY publication_date ASC, id ASC;
+
+-- name: GetArticleSummariesByPublicationDate :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE (sqlc.narg(from_date)::timestamptz IS NULL OR publication_date >= sqlc.narg(from_date))
+  AND (sqlc.narg(to_date)::timestamptz IS NULL OR publication_date < sqlc.narg(to_date))
+ORDER BY publication_date ASC, id ASC;
+
+-- name: GetArticleArchive :many
+SELECT CAST(EXTRACT(YEAR FROM publication_date AT TIME ZONE 'UTC') AS INTEGER) AS year,
+       CAST(EXTRACT(MONTH FROM publication_date AT TIME ZONE 'UTC') AS INTEGER) AS month,
+       COUNT(*) AS article_count
+FROM articles
+GROUP BY year, month
+ORDER BY year DESC, month DESC;
 
 -- name: GetAllTags :many
 SELECT DISTINCT unnest(tags)::TEXT AS unique_tag
@@ -28,14 +63,103 @@ ORDER BY unique_tag ASC;
 UPDATE articles
 SET title = $1,
     thumbnail = $2,
-    slug = $3,
-    content = $4,
-    tags = $5,
-    publication_date = $6
-WHERE id = $7
-RETURNING id, title, thumbnail, slug, content, tags, publication_date;
+    thumbnail_html = $3,
+    slug = $4,
+    content = $5,
+    content_html = $6,
+    tags = $7,
+    bibliography = $8,
+    publication_date = $9
+WHERE id = $10
+RETURNING id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date;
 
 -- name: DeleteArticleByID :one
 DELETE FROM articles
 WHERE id = $1
-RETURNING id, title, thumbnail, slug, content, tags, publication_date;
+RETURNING id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date;
+
+-- name: CreateMedia :one
+INSERT INTO media (hash, name, content_type, size, width, height)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetMediaByHash :one
+SELECT * FROM media
+WHERE hash = $1 LIMIT 1;
+
+-- name: GetAllMedia :many
+SELECT * FROM media
+ORDER BY created_at DESC;
+
+-- name: UpdateMediaDimensions :exec
+UPDATE media
+SET width = $1,
+    height = $2
+WHERE id = $3;
+
+-- name: CreateMediaVariant :one
+INSERT INTO media_variants (media_id, hash, name, content_type, size, width, height)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetMediaVariantByHash :one
+SELECT * FROM media_variants
+WHERE hash = $1 LIMIT 1;
+
+-- name: GetMediaVariantsByMediaID :many
+SELECT * FROM media_variants
+WHERE media_id = $1
+ORDER BY width ASC;
+
+-- name: CreateArticleLink :exec
+INSERT INTO article_links (source_id, target_slug)
+VALUES ($1, $2);
+
+-- name: DeleteArticleLinks :exec
+DELETE FROM article_links
+WHERE source_id = $1;
+
+-- name: GetArticleBacklinks :many
+SELECT articles.* FROM articles
+JOIN article_links ON article_links.source_id = articles.id
+WHERE article_links.target_slug = $1
+  AND articles.slug != article_links.target_slug
+ORDER BY articles.publication_date DESC, articles.id;
+
+-- name: ResetArticleIDSequence :exec
+SELECT setval(pg_get_serial_sequence('articles', 'id'), (SELECT MAX(id) FROM articles));
+
+-- name: CreateArticleEvent :exec
+INSERT INTO article_events (operation, article_id, payload)
+VALUES ($1, $2, $3);
+
+-- name: ClaimArticleEvents :many
+UPDATE article_events
+SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => sqlc.arg(lease_seconds)::float8)
+WHERE id IN (
+    SELECT id FROM article_events
+    WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY id ASC
+    LIMIT sqlc.arg(max_events)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkArticleEventDelivered :exec
+UPDATE article_events
+SET status = 'delivered',
+    delivered_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: MarkArticleEventFailed :exec
+UPDATE article_events
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => sqlc.arg(retry_seconds)::float8)
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteDeliveredArticleEvents :execrows
+DELETE FROM article_events
+WHERE status = 'delivered'
+  AND delivered_at < CURRENT_TIMESTAMP - make_interval(secs => sqlc.arg(age_seconds)::float8);
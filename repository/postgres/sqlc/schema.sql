@@ -2,12 +2,64 @@ CREATE TABLE articles (
     id BIGSERIAL PRIMARY KEY,
     title VARCHAR(255) NOT NULL,
     thumbnail TEXT NOT NULL,
+    thumbnail_html TEXT NOT NULL,
     slug VARCHAR(255) NOT NULL,
     content TEXT NOT NULL,
+    content_html TEXT NOT NULL,
     tags TEXT[],
+    bibliography JSONB NOT NULL DEFAULT '[]',
     publication_date TIMESTAMP WITH TIME ZONE NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_articles_tags ON articles USING GIN (tags);
+CREATE UNIQUE INDEX idx_articles_slug ON articles (slug);
+
+CREATE TABLE media (
+    id BIGSERIAL PRIMARY KEY,
+    hash CHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE media_variants (
+    id BIGSERIAL PRIMARY KEY,
+    media_id BIGINT NOT NULL REFERENCES media (id) ON DELETE CASCADE,
+    hash CHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_media_variants_media_id ON media_variants (media_id);
+
+CREATE TABLE article_links (
+    source_id BIGINT NOT NULL REFERENCES articles (id) ON DELETE CASCADE,
+    target_slug VARCHAR(255) NOT NULL,
+    PRIMARY KEY (source_id, target_slug)
+);
+
+CREATE INDEX idx_article_links_target_slug ON article_links (target_slug);
+
+CREATE TABLE article_events (
+    id BIGSERIAL PRIMARY KEY,
+    operation VARCHAR(32) NOT NULL,
+    article_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_article_events_status ON article_events (status, next_attempt_at);
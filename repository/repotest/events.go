@@ -0,0 +1,140 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jannawro/blog/article"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// EventRepository is a repository recording its changes in an outbox
+type EventRepository interface {
+	article.ArticleRepository
+	article.EventRepository
+}
+
+// RunEvents runs the contract tests of the outbox against the repositories returned by newRepository. Every
+// subtest asks for its own repository, which has to be empty and have an empty outbox.
+func RunEvents(t *testing.T, newRepository func(t *testing.T) EventRepository) {
+	ctx := context.Background()
+
+	t.Run("Writes are recorded", func(t *testing.T) {
+		repo := newRepository(t)
+		a := newArticle("recorded", "events")
+		a.ContentHTML = "<p>This is recorded</p>"
+		created := create(t, repo, a)
+		changed := *created
+		changed.Title = "Changed"
+		_, err := repo.Update(ctx, created.ID, changed)
+		require.NoError(t, err)
+		require.NoError(t, repo.Delete(ctx, created.ID))
+
+		events, err := repo.ClaimEvents(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+		operations := make([]article.ChangeOperation, len(events))
+		for i, event := range events {
+			operations[i] = event.Operation
+			assert.Equal(t, created.ID, event.Article.ID)
+			assert.Equal(t, "recorded", event.Article.Slug)
+			assert.Equal(t, []string{"events"}, event.Article.Tags)
+			assert.Empty(t, event.Article.ContentHTML)
+			assert.Zero(t, event.Attempts)
+			assert.NotZero(t, event.OccurredAt)
+		}
+		assert.Equal(t, []article.ChangeOperation{
+			article.ChangeCreated,
+			article.ChangeUpdated,
+			article.ChangeDeleted,
+		}, operations)
+		assert.Equal(t, "Article recorded", events[0].Article.Title)
+		assert.Equal(t, "Changed", events[1].Article.Title)
+		assert.Equal(t, "Changed", events[2].Article.Title)
+	})
+
+	t.Run("Rendering again isn't recorded", func(t *testing.T) {
+		repo := newRepository(t)
+		created := create(t, repo, newArticle("rendered"))
+		rendered := *created
+		rendered.ContentHTML = "<p>Rendered again</p>"
+		rendered.ThumbnailHTML = "<p>Rendered again</p>"
+		_, err := repo.Update(ctx, created.ID, rendered)
+		require.NoError(t, err)
+
+		events, err := repo.ClaimEvents(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, article.ChangeCreated, events[0].Operation)
+	})
+
+	t.Run("Claims are leased", func(t *testing.T) {
+		repo := newRepository(t)
+		create(t, repo, newArticle("first"))
+		create(t, repo, newArticle("second"))
+
+		events, err := repo.ClaimEvents(ctx, 1, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "first", events[0].Article.Slug)
+
+		events, err = repo.ClaimEvents(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "second", events[0].Article.Slug)
+
+		events, err = repo.ClaimEvents(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("Failed deliveries are retried until dead", func(t *testing.T) {
+		repo := newRepository(t)
+		create(t, repo, newArticle("failing"))
+		events, err := repo.ClaimEvents(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		id := events[0].ID
+
+		require.NoError(t, repo.MarkEventFailed(ctx, id, "unreachable", 0, false))
+		events, err = repo.ClaimEvents(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, id, events[0].ID)
+		assert.Equal(t, 1, events[0].Attempts)
+
+		require.NoError(t, repo.MarkEventFailed(ctx, id, "unreachable", time.Hour, false))
+		events, err = repo.ClaimEvents(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		assert.Empty(t, events, "claimed before the retry is due")
+
+		require.NoError(t, repo.MarkEventFailed(ctx, id, "unreachable", 0, true))
+		events, err = repo.ClaimEvents(ctx, 10, time.Minute)
+		require.NoError(t, err)
+		assert.Empty(t, events, "dead events aren't claimed")
+	})
+
+	t.Run("Delivered events are pruned", func(t *testing.T) {
+		repo := newRepository(t)
+		create(t, repo, newArticle("delivered"))
+		events, err := repo.ClaimEvents(ctx, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.NoError(t, repo.MarkEventDelivered(ctx, events[0].ID))
+
+		events, err = repo.ClaimEvents(ctx, 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, events, "delivered events aren't claimed")
+
+		deleted, err := repo.DeleteDeliveredEvents(ctx, time.Hour)
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+		// Some databases store the delivery time in whole seconds
+		time.Sleep(1100 * time.Millisecond)
+		deleted, err = repo.DeleteDeliveredEvents(ctx, 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+}
@@ -0,0 +1,389 @@
+// Package repotest holds the contract every article.ArticleRepository implementation has to fulfil, written as
+// tests so each backend can run it from its own test file.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the contract tests against the repositories returned by newRepository. Every subtest asks for its
+// own repository, which has to be empty.
+func Run(t *testing.T, newRepository func(t *testing.T) article.ArticleRepository) {
+	ctx := context.Background()
+
+	t.Run("Create and GetByID", func(t *testing.T) {
+		repo := newRepository(t)
+
+		a := newArticle("test-article", "test", "golang")
+		a.Thumbnail = "Test article thumbnail"
+		a.ThumbnailHTML = "<p>Test article thumbnail</p>"
+		a.ContentHTML = "<p>This is a test article</p>"
+		a.References = []article.Reference{{Key: "gopl", Text: "*The Go Programming Language*"}}
+
+		created, err := repo.Create(ctx, a)
+		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
+
+		fetched, err := repo.GetByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, created, fetched)
+	})
+
+	t.Run("CreateWithID", func(t *testing.T) {
+		repo := newRepository(t)
+		creator, ok := repo.(interface {
+			CreateWithID(ctx context.Context, article article.Article) (*article.Article, error)
+		})
+		if !ok {
+			t.Skip("the repository can't create articles under a given ID")
+		}
+
+		a := newArticle("restored", "test")
+		a.ID = 42
+		created, err := creator.CreateWithID(ctx, a)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), created.ID)
+
+		fetched, err := repo.GetByID(ctx, 42)
+		require.NoError(t, err)
+		assert.Equal(t, created, fetched)
+
+		// Later articles don't collide with the given ID
+		next := create(t, repo, newArticle("next"))
+		assert.Greater(t, next.ID, int64(42))
+	})
+
+	t.Run("GetBySlug", func(t *testing.T) {
+		repo := newRepository(t)
+		create(t, repo, newArticle("first"))
+		second := create(t, repo, newArticle("second"))
+
+		fetched, err := repo.GetBySlug(ctx, "second")
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, fetched.ID)
+		assert.Equal(t, second.Title, fetched.Title)
+	})
+
+	t.Run("GetAll returns articles in creation order", func(t *testing.T) {
+		repo := newRepository(t)
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+
+		var ids []int64
+		for _, slug := range []string{"c", "a", "b"} {
+			ids = append(ids, create(t, repo, newArticle(slug)).ID)
+		}
+
+		all, err = repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, ids, articleIDs(all))
+	})
+
+	t.Run("GetByTags matches any of the tags", func(t *testing.T) {
+		repo := newRepository(t)
+		goAndDB := create(t, repo, newArticle("go-and-db", "go", "db"))
+		create(t, repo, newArticle("go", "go"))
+		web := create(t, repo, newArticle("web", "web"))
+		create(t, repo, newArticle("untagged"))
+
+		found, err := repo.GetByTags(ctx, []string{"db", "web"})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{goAndDB.ID, web.ID}, articleIDs(found))
+
+		found, err = repo.GetByTags(ctx, []string{"missing"})
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("GetByPublicationDate", func(t *testing.T) {
+		repo := newRepository(t)
+		march := create(t, repo, publishedOn("march", 2024, time.March, 31))
+		january := create(t, repo, publishedOn("january", 2024, time.January, 1))
+		april := create(t, repo, publishedOn("april", 2024, time.April, 1))
+		create(t, repo, publishedOn("last-year", 2023, time.December, 31))
+
+		found, err := repo.GetByPublicationDate(ctx,
+			time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{january.ID, march.ID}, articleIDs(found))
+
+		found, err = repo.GetByPublicationDate(ctx, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{march.ID, april.ID}, articleIDs(found))
+
+		found, err = repo.GetByPublicationDate(ctx, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Len(t, found, 4)
+
+		found, err = repo.GetByPublicationDate(ctx, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("GetArchive", func(t *testing.T) {
+		repo := newRepository(t)
+
+		months, err := repo.GetArchive(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, months)
+
+		create(t, repo, publishedOn("first", 2023, time.December, 31))
+		create(t, repo, publishedOn("second", 2024, time.March, 1))
+		create(t, repo, publishedOn("third", 2024, time.March, 31))
+		create(t, repo, publishedOn("fourth", 2024, time.January, 15))
+
+		months, err = repo.GetArchive(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []article.ArchiveMonth{
+			{Year: 2024, Month: time.March, Count: 2},
+			{Year: 2024, Month: time.January, Count: 1},
+			{Year: 2023, Month: time.December, Count: 1},
+		}, months)
+	})
+
+	t.Run("Summaries match the articles", func(t *testing.T) {
+		repo := newRepository(t)
+
+		summaries, err := repo.GetAllSummaries(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, summaries)
+
+		first := publishedOn("first", 2024, time.January, 1)
+		first.Tags = []string{"go", "db"}
+		first.Thumbnail = "First thumbnail"
+		first.ThumbnailHTML = "<p>First thumbnail</p>"
+		first.ContentHTML = "<p>This is first</p>"
+		create(t, repo, first)
+		second := publishedOn("second", 2024, time.February, 1)
+		second.Tags = []string{"web"}
+		create(t, repo, second)
+		create(t, repo, publishedOn("untagged", 2023, time.December, 1))
+
+		articles, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		summaries, err = repo.GetAllSummaries(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, articles.Summaries(), summaries)
+
+		articles, err = repo.GetByTags(ctx, []string{"db", "web"})
+		require.NoError(t, err)
+		summaries, err = repo.GetSummariesByTags(ctx, []string{"db", "web"})
+		require.NoError(t, err)
+		assert.Len(t, summaries, 2)
+		assert.Equal(t, articles.Summaries(), summaries)
+
+		from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		articles, err = repo.GetByPublicationDate(ctx, from, time.Time{})
+		require.NoError(t, err)
+		summaries, err = repo.GetSummariesByPublicationDate(ctx, from, time.Time{})
+		require.NoError(t, err)
+		assert.Len(t, summaries, 2)
+		assert.Equal(t, articles.Summaries(), summaries)
+	})
+
+	t.Run("GetAllTags", func(t *testing.T) {
+		repo := newRepository(t)
+
+		tags, err := repo.GetAllTags(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, tags)
+
+		create(t, repo, newArticle("first", "web", "go"))
+		create(t, repo, newArticle("second", "go", "db", "apis", "testing", "sql"))
+		create(t, repo, newArticle("untagged"))
+
+		tags, err = repo.GetAllTags(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"apis", "db", "go", "sql", "testing", "web"}, tags)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepository(t)
+		created := create(t, repo, newArticle("test-article", "test"))
+		other := create(t, repo, newArticle("other", "other"))
+
+		changed := *created
+		changed.Title = "Updated Test Article"
+		changed.Slug = "updated-test-article"
+		changed.Tags = []string{"updated"}
+		updated, err := repo.Update(ctx, created.ID, changed)
+		require.NoError(t, err)
+		assert.Equal(t, &changed, updated)
+
+		fetched, err := repo.GetByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, &changed, fetched)
+
+		untouched, err := repo.GetByID(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Equal(t, other, untouched)
+	})
+
+	t.Run("Update without changes", func(t *testing.T) {
+		repo := newRepository(t)
+		created := create(t, repo, newArticle("test-article", "test"))
+
+		updated, err := repo.Update(ctx, created.ID, *created)
+		require.NoError(t, err)
+		assert.Equal(t, created, updated)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		repo := newRepository(t)
+		created := create(t, repo, newArticle("test-article"))
+		kept := create(t, repo, newArticle("kept"))
+
+		require.NoError(t, repo.Delete(ctx, created.ID))
+
+		_, err := repo.GetByID(ctx, created.ID)
+		assert.ErrorIs(t, err, article.ErrArticleNotFound)
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{kept.ID}, articleIDs(all))
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		repo := newRepository(t)
+		created := create(t, repo, newArticle("test-article"))
+		missingID := created.ID + 1000
+
+		_, err := repo.GetByID(ctx, missingID)
+		assert.ErrorIs(t, err, article.ErrArticleNotFound)
+
+		_, err = repo.GetBySlug(ctx, "missing")
+		assert.ErrorIs(t, err, article.ErrArticleNotFound)
+
+		_, err = repo.Update(ctx, missingID, newArticle("missing"))
+		assert.ErrorIs(t, err, article.ErrArticleNotFound)
+
+		err = repo.Delete(ctx, missingID)
+		assert.ErrorIs(t, err, article.ErrArticleNotFound)
+	})
+
+	t.Run("Duplicate slug", func(t *testing.T) {
+		repo := newRepository(t)
+		first := create(t, repo, newArticle("first"))
+		second := create(t, repo, newArticle("second"))
+
+		_, err := repo.Create(ctx, newArticle("first"))
+		assert.ErrorIs(t, err, repository.ErrDuplicateSlug)
+
+		changed := *second
+		changed.Slug = first.Slug
+		_, err = repo.Update(ctx, second.ID, changed)
+		assert.ErrorIs(t, err, repository.ErrDuplicateSlug)
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{first.ID, second.ID}, articleIDs(all))
+		fetched, err := repo.GetByID(ctx, second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "second", fetched.Slug)
+	})
+
+	t.Run("SetLinks and GetBacklinks", func(t *testing.T) {
+		repo := newRepository(t)
+		target := create(t, repo, newArticle("target"))
+		older := newArticle("older")
+		older.PublicationDate = older.PublicationDate.Add(-time.Hour)
+		olderSource := create(t, repo, older)
+		source := create(t, repo, newArticle("source"))
+		// Backlinks published at the same time are in creation order
+		published := newArticle("sibling")
+		published.PublicationDate = source.PublicationDate
+		sibling := create(t, repo, published)
+
+		require.NoError(t, repo.SetLinks(ctx, sibling.ID, []string{"target"}))
+		require.NoError(t, repo.SetLinks(ctx, olderSource.ID, []string{"target"}))
+		require.NoError(t, repo.SetLinks(ctx, source.ID, []string{"target", "missing", "target"}))
+		require.NoError(t, repo.SetLinks(ctx, target.ID, []string{"target"}))
+
+		backlinks, err := repo.GetBacklinks(ctx, "target")
+		require.NoError(t, err)
+		assert.Equal(t, []int64{source.ID, sibling.ID, olderSource.ID}, articleIDs(backlinks))
+
+		require.NoError(t, repo.SetLinks(ctx, source.ID, nil))
+		require.NoError(t, repo.Delete(ctx, sibling.ID))
+		require.NoError(t, repo.Delete(ctx, olderSource.ID))
+		backlinks, err = repo.GetBacklinks(ctx, "target")
+		require.NoError(t, err)
+		assert.Empty(t, backlinks)
+	})
+
+	t.Run("Concurrent writes", func(t *testing.T) {
+		repo := newRepository(t)
+		const writers = 16
+
+		var wg sync.WaitGroup
+		ids := make(chan int64, writers)
+		for i := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				created, err := repo.Create(ctx, newArticle(fmt.Sprintf("article-%d", i), "concurrent"))
+				if assert.NoError(t, err) {
+					ids <- created.ID
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		unique := make(map[int64]bool)
+		for id := range ids {
+			unique[id] = true
+		}
+		assert.Len(t, unique, writers)
+
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, writers)
+		tagged, err := repo.GetByTags(ctx, []string{"concurrent"})
+		require.NoError(t, err)
+		assert.Len(t, tagged, writers)
+	})
+}
+
+func newArticle(slug string, tags ...string) article.Article {
+	return article.Article{
+		Title:           "Article " + slug,
+		Slug:            slug,
+		Content:         "This is " + slug,
+		Tags:            tags,
+		PublicationDate: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func publishedOn(slug string, year int, month time.Month, day int) article.Article {
+	a := newArticle(slug)
+	a.PublicationDate = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return a
+}
+
+func create(t *testing.T, repo article.ArticleRepository, a article.Article) *article.Article {
+	t.Helper()
+	created, err := repo.Create(context.Background(), a)
+	require.NoError(t, err)
+	return created
+}
+
+func articleIDs(articles article.Articles) []int64 {
+	ids := make([]int64, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+	}
+	return ids
+}
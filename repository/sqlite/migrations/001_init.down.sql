@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS article_links;
+DROP TABLE IF EXISTS media_variants;
+DROP TABLE IF EXISTS media;
+DROP TABLE IF EXISTS articles;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_articles_slug;
+CREATE UNIQUE INDEX idx_articles_slug ON articles (slug);
@@ -0,0 +1,645 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/middleware"
+	"github.com/jannawro/blog/repository"
+	sqlite3 "modernc.org/sqlite"
+	sqlite3lib "modernc.org/sqlite/lib"
+)
+
+const DBDriver = "sqlite"
+
+// defaultParams are added to the connection string unless it already sets them. Foreign keys are off in SQLite
+// by default and the cascades in the schema depend on them.
+var defaultParams = []struct {
+	name  string
+	param string
+}{
+	{"_pragma=foreign_keys", "_pragma=foreign_keys(1)"},
+	{"_pragma=busy_timeout", "_pragma=busy_timeout(5000)"},
+	{"_pragma=journal_mode", "_pragma=journal_mode(WAL)"},
+	{"_time_format=", "_time_format=sqlite"},
+}
+
+type Repository struct {
+	db *sql.DB
+	q  *Queries
+	// queryTimeout bounds every call, see repository.Options
+	queryTimeout time.Duration
+}
+
+// NewDatabase opens the SQLite database file at connString, creating it if it doesn't exist.
+func NewDatabase(ctx context.Context, connString string) (*sql.DB, error) {
+	db, err := sql.Open(DBDriver, withDefaultParams(connString))
+	if err != nil {
+		return nil, errors.Join(repository.ErrDatabaseConnectionFailed, err)
+	}
+	// SQLite allows a single writer at a time, serializing access in the pool avoids SQLITE_BUSY errors
+	// and keeps in-memory databases from being opened once per connection.
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		closeErr := db.Close()
+		if closeErr != nil {
+			return nil, errors.Join(repository.ErrPingFailed, err, closeErr)
+		}
+		return nil, errors.Join(repository.ErrPingFailed, err)
+	}
+
+	return db, nil
+}
+
+func withDefaultParams(connString string) string {
+	for _, p := range defaultParams {
+		if strings.Contains(connString, p.name) {
+			continue
+		}
+		if strings.Contains(connString, "?") {
+			connString += "&" + p.param
+		} else {
+			connString += "?" + p.param
+		}
+	}
+	return connString
+}
+
+func NewRepository(db *sql.DB, migrationFiles fs.FS, options repository.Options) (*Repository, error) {
+	if !options.SkipMigrations {
+		if err := runMigration(db, migrationFiles); err != nil {
+			closeErr := db.Close()
+			if closeErr != nil {
+				return nil, errors.Join(repository.ErrMigrationFailed, err, closeErr)
+			}
+			return nil, errors.Join(repository.ErrMigrationFailed, err)
+		}
+	}
+	return &Repository{
+		db:           db,
+		q:            New(db),
+		queryTimeout: options.QueryTimeout,
+	}, nil
+}
+
+// NewMigration prepares the migrations in migrationFiles for running against db. Closing the returned instance
+// closes db as well.
+func NewMigration(db *sql.DB, migrationFiles fs.FS) (*migrate.Migrate, error) {
+	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		return nil, errors.Join(repository.ErrDriverCreationFailed, err)
+	}
+
+	// Create an embed source for the migration
+	embedSource, err := iofs.New(migrationFiles, ".")
+	if err != nil {
+		return nil, errors.Join(repository.ErrEmbedFailed, err)
+	}
+
+	m, err := migrate.NewWithInstance(
+		"iofs", embedSource,
+		DBDriver, driver)
+	if err != nil {
+		return nil, errors.Join(repository.ErrMigrationInstanceFailed, err)
+	}
+
+	return m, nil
+}
+
+func runMigration(db *sql.DB, migrationFiles fs.FS) error {
+	m, err := NewMigration(db, migrationFiles)
+	if err != nil {
+		return err
+	}
+
+	// Run the migration
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return errors.Join(repository.ErrMigrationRunFailed, err)
+	}
+
+	return nil
+}
+
+func (r *Repository) Create(ctx context.Context, article article.Article) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err != sql.ErrTxDone {
+				slog.Error(errors.Join(repository.ErrTxRollbackFailed, err).Error(), "requestID", middleware.ReqIDFromCtx(ctx))
+			}
+		}
+	}()
+
+	qtx := r.q.WithTx(tx)
+	id, err := qtx.CreateArticle(ctx, CreateArticleParams{
+		Title:           article.Title,
+		Thumbnail:       article.Thumbnail,
+		ThumbnailHtml:   article.ThumbnailHTML,
+		Slug:            article.Slug,
+		Content:         article.Content,
+		ContentHtml:     article.ContentHTML,
+		Tags:            tagsToJSON(article.Tags),
+		Bibliography:    referencesToJSON(article.References),
+		PublicationDate: article.PublicationDate,
+	})
+	if err != nil {
+		return nil, duplicateSlug(err)
+	}
+
+	article.ID = id
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+// CreateWithID creates article under article.ID instead of the next free ID. It's meant for copying articles
+// between databases.
+func (r *Repository) CreateWithID(ctx context.Context, article article.Article) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err != sql.ErrTxDone {
+				slog.Error(errors.Join(repository.ErrTxRollbackFailed, err).Error(), "requestID", middleware.ReqIDFromCtx(ctx))
+			}
+		}
+	}()
+
+	qtx := r.q.WithTx(tx)
+	err = qtx.CreateArticleWithID(ctx, CreateArticleWithIDParams{
+		ID:              article.ID,
+		Title:           article.Title,
+		Thumbnail:       article.Thumbnail,
+		ThumbnailHtml:   article.ThumbnailHTML,
+		Slug:            article.Slug,
+		Content:         article.Content,
+		ContentHtml:     article.ContentHTML,
+		Tags:            tagsToJSON(article.Tags),
+		Bibliography:    referencesToJSON(article.References),
+		PublicationDate: article.PublicationDate,
+	})
+	if err != nil {
+		return nil, duplicateSlug(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &article, nil
+}
+
+func (r *Repository) GetAll(ctx context.Context) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbArticles, err := r.q.GetAllArticles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toArticles(dbArticles)
+}
+
+func (r *Repository) GetByID(ctx context.Context, id int64) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbArticle, err := r.q.GetArticleByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	a, err := toArticle(dbArticle)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *Repository) GetBySlug(ctx context.Context, slug string) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbArticle, err := r.q.GetArticleBySlug(ctx, slug)
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	a, err := toArticle(dbArticle)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *Repository) GetByTags(ctx context.Context, tags []string) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbArticles, err := r.q.GetArticlesByTags(ctx, tagsToJSON(tags))
+	if err != nil {
+		return nil, err
+	}
+
+	return toArticles(dbArticles)
+}
+
+func (r *Repository) GetByPublicationDate(ctx context.Context, from, to time.Time) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbArticles, err := r.q.GetArticlesByPublicationDate(ctx, GetArticlesByPublicationDateParams{
+		FromDate: nullTime(from),
+		ToDate:   nullTime(to),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toArticles(dbArticles)
+}
+
+func (r *Repository) GetAllSummaries(ctx context.Context) (article.ArticleSummaries, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.q.GetAllArticleSummaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(article.ArticleSummaries, len(rows))
+	for i, s := range rows {
+		summaryTags, err := jsonToTags(s.Tags)
+		if err != nil {
+			return nil, err
+		}
+		summaries[i] = article.ArticleSummary{
+			ID:              s.ID,
+			Title:           s.Title,
+			Thumbnail:       s.Thumbnail,
+			ThumbnailHTML:   s.ThumbnailHtml,
+			Slug:            s.Slug,
+			Tags:            summaryTags,
+			PublicationDate: s.PublicationDate.UTC(),
+		}
+	}
+
+	return summaries, nil
+}
+
+func (r *Repository) GetSummariesByTags(ctx context.Context, tags []string) (article.ArticleSummaries, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.q.GetArticleSummariesByTags(ctx, tagsToJSON(tags))
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(article.ArticleSummaries, len(rows))
+	for i, s := range rows {
+		summaryTags, err := jsonToTags(s.Tags)
+		if err != nil {
+			return nil, err
+		}
+		summaries[i] = article.ArticleSummary{
+			ID:              s.ID,
+			Title:           s.Title,
+			Thumbnail:       s.Thumbnail,
+			ThumbnailHTML:   s.ThumbnailHtml,
+			Slug:            s.Slug,
+			Tags:            summaryTags,
+			PublicationDate: s.PublicationDate.UTC(),
+		}
+	}
+
+	return summaries, nil
+}
+
+func (r *Repository) GetSummariesByPublicationDate(
+	ctx context.Context,
+	from, to time.Time,
+) (article.ArticleSummaries, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.q.GetArticleSummariesByPublicationDate(ctx, GetArticleSummariesByPublicationDateParams{
+		FromDate: nullTime(from),
+		ToDate:   nullTime(to),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(article.ArticleSummaries, len(rows))
+	for i, s := range rows {
+		summaryTags, err := jsonToTags(s.Tags)
+		if err != nil {
+			return nil, err
+		}
+		summaries[i] = article.ArticleSummary{
+			ID:              s.ID,
+			Title:           s.Title,
+			Thumbnail:       s.Thumbnail,
+			ThumbnailHTML:   s.ThumbnailHtml,
+			Slug:            s.Slug,
+			Tags:            summaryTags,
+			PublicationDate: s.PublicationDate.UTC(),
+		}
+	}
+
+	return summaries, nil
+}
+
+func (r *Repository) GetArchive(ctx context.Context) ([]article.ArchiveMonth, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.q.GetArticleArchive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	months := make([]article.ArchiveMonth, len(rows))
+	for i, row := range rows {
+		months[i] = article.ArchiveMonth{
+			Year:  int(row.Year),
+			Month: time.Month(row.Month),
+			Count: int(row.ArticleCount),
+		}
+	}
+	return months, nil
+}
+
+func (r *Repository) Update(
+	ctx context.Context,
+	id int64,
+	updated article.Article,
+) (*article.Article, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err != sql.ErrTxDone {
+				slog.Error(errors.Join(repository.ErrTxRollbackFailed, err).Error(), "requestID", middleware.ReqIDFromCtx(ctx))
+			}
+		}
+	}()
+
+	qtx := r.q.WithTx(tx)
+	dbArticle, err := qtx.UpdateArticleByID(ctx, UpdateArticleByIDParams{
+		ID:              id,
+		Title:           updated.Title,
+		Thumbnail:       updated.Thumbnail,
+		ThumbnailHtml:   updated.ThumbnailHTML,
+		Slug:            updated.Slug,
+		Content:         updated.Content,
+		ContentHtml:     updated.ContentHTML,
+		Tags:            tagsToJSON(updated.Tags),
+		Bibliography:    referencesToJSON(updated.References),
+		PublicationDate: updated.PublicationDate,
+	})
+	if err != nil {
+		return nil, notFound(duplicateSlug(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	tags, err := jsonToTags(dbArticle.Tags)
+	if err != nil {
+		return nil, err
+	}
+	references, err := jsonToReferences(dbArticle.Bibliography)
+	if err != nil {
+		return nil, err
+	}
+	return &article.Article{
+		ID:              dbArticle.ID,
+		Title:           dbArticle.Title,
+		Thumbnail:       dbArticle.Thumbnail,
+		ThumbnailHTML:   dbArticle.ThumbnailHtml,
+		Slug:            dbArticle.Slug,
+		Content:         dbArticle.Content,
+		ContentHTML:     dbArticle.ContentHtml,
+		Tags:            tags,
+		References:      references,
+		PublicationDate: dbArticle.PublicationDate.UTC(),
+	}, nil
+}
+
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err != sql.ErrTxDone {
+				slog.Error(errors.Join(repository.ErrTxRollbackFailed, err).Error(), "requestID", middleware.ReqIDFromCtx(ctx))
+			}
+		}
+	}()
+
+	qtx := r.q.WithTx(tx)
+	_, err = qtx.DeleteArticleByID(ctx, id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) SetLinks(ctx context.Context, sourceID int64, targetSlugs []string) error {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			if err != sql.ErrTxDone {
+				slog.Error(errors.Join(repository.ErrTxRollbackFailed, err).Error(), "requestID", middleware.ReqIDFromCtx(ctx))
+			}
+		}
+	}()
+
+	qtx := r.q.WithTx(tx)
+	if err := qtx.DeleteArticleLinks(ctx, sourceID); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, slug := range targetSlugs {
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		if err := qtx.CreateArticleLink(ctx, CreateArticleLinkParams{
+			SourceID:   sourceID,
+			TargetSlug: slug,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) GetBacklinks(ctx context.Context, slug string) (article.Articles, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	dbArticles, err := r.q.GetArticleBacklinks(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	return toArticles(dbArticles)
+}
+
+func (r *Repository) GetAllTags(ctx context.Context) ([]string, error) {
+	ctx, cancel := repository.WithQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.q.GetAllTags(ctx)
+}
+
+// toArticle converts an article row, failing if a column holding JSON can't be decoded
+func toArticle(a Article) (article.Article, error) {
+	tags, err := jsonToTags(a.Tags)
+	if err != nil {
+		return article.Article{}, err
+	}
+	references, err := jsonToReferences(a.Bibliography)
+	if err != nil {
+		return article.Article{}, err
+	}
+	return article.Article{
+		ID:              a.ID,
+		Title:           a.Title,
+		Thumbnail:       a.Thumbnail,
+		ThumbnailHTML:   a.ThumbnailHtml,
+		Slug:            a.Slug,
+		Content:         a.Content,
+		ContentHTML:     a.ContentHtml,
+		Tags:            tags,
+		References:      references,
+		PublicationDate: a.PublicationDate.UTC(),
+	}, nil
+}
+
+func toArticles(rows []Article) (article.Articles, error) {
+	articles := make(article.Articles, len(rows))
+	for i, row := range rows {
+		a, err := toArticle(row)
+		if err != nil {
+			return nil, err
+		}
+		articles[i] = a
+	}
+	return articles, nil
+}
+
+// notFound marks a missing row as a missing article so callers can tell it apart from other failures.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.Join(article.ErrArticleNotFound, err)
+	}
+	return err
+}
+
+// duplicateSlug turns the unique constraint failure of the slug index into repository.ErrDuplicateSlug
+func duplicateSlug(err error) error {
+	var sqliteErr *sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3lib.SQLITE_CONSTRAINT_UNIQUE &&
+		strings.Contains(sqliteErr.Error(), "articles.slug") {
+		return errors.Join(repository.ErrDuplicateSlug, err)
+	}
+	return err
+}
+
+// nullTime turns the zero time into NULL, an open end of a range
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
+
+func tagsToJSON(tags []string) string {
+	if tags == nil {
+		tags = []string{}
+	}
+	jsonTags, err := json.Marshal(tags)
+	if err != nil {
+		panic(err)
+	}
+	return string(jsonTags)
+}
+
+func jsonToTags(j string) ([]string, error) {
+	var tags []string
+	if err := json.Unmarshal([]byte(j), &tags); err != nil {
+		return nil, errors.Join(repository.ErrInvalidArticle, fmt.Errorf("tags: %w", err))
+	}
+	return tags, nil
+}
+
+func referencesToJSON(references []article.Reference) string {
+	if references == nil {
+		references = []article.Reference{}
+	}
+	jsonReferences, err := json.Marshal(references)
+	if err != nil {
+		panic(err)
+	}
+	return string(jsonReferences)
+}
+
+func jsonToReferences(j string) ([]article.Reference, error) {
+	var references []article.Reference
+	if err := json.Unmarshal([]byte(j), &references); err != nil {
+		return nil, errors.Join(repository.ErrInvalidArticle, fmt.Errorf("bibliography: %w", err))
+	}
+	if len(references) == 0 {
+		return nil, nil
+	}
+	return references, nil
+}
@@ -0,0 +1,208 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jannawro/blog/article"
+	"github.com/jannawro/blog/media"
+	"github.com/jannawro/blog/repository"
+	"github.com/jannawro/blog/repository/repotest"
+	"github.com/jannawro/blog/repository/sqlite"
+	"github.com/jannawro/blog/repository/sqlite/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestDatabase(t *testing.T) (*sql.DB, func()) {
+	db, err := sqlite.NewDatabase(context.Background(), filepath.Join(t.TempDir(), "blog.db"))
+	require.NoError(t, err)
+
+	cleanup := func() {
+		err := db.Close()
+		assert.NoError(t, err)
+	}
+
+	return db, cleanup
+}
+
+func TestRepository(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo, err := sqlite.NewRepository(db, migrations.Files(), repository.Options{})
+	require.NoError(t, err)
+
+	repotest.Run(t, func(t *testing.T) article.ArticleRepository {
+		_, err := db.Exec("DELETE FROM articles")
+		require.NoError(t, err)
+		return repo
+	})
+}
+
+func TestRepositoryCascades(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo, err := sqlite.NewRepository(db, migrations.Files(), repository.Options{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	source, err := repo.Create(ctx, article.Article{
+		Title:           "Source",
+		Slug:            "source",
+		PublicationDate: time.Now().UTC().Truncate(time.Second),
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.SetLinks(ctx, source.ID, []string{"target"}))
+
+	require.NoError(t, repo.Delete(ctx, source.ID))
+
+	var links int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM article_links").Scan(&links))
+	assert.Zero(t, links)
+}
+
+func TestInvalidStoredArticle(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	repo, err := sqlite.NewRepository(db, migrations.Files(), repository.Options{})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, article.Article{
+		Title:           "Corrupt",
+		Slug:            "corrupt",
+		Tags:            []string{"go"},
+		PublicationDate: time.Now().UTC().Truncate(time.Second),
+	})
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE articles SET bibliography = '[{' WHERE id = ?", created.ID)
+	require.NoError(t, err)
+
+	_, err = repo.GetByID(ctx, created.ID)
+	assert.ErrorIs(t, err, repository.ErrInvalidArticle)
+	_, err = repo.GetAll(ctx)
+	assert.ErrorIs(t, err, repository.ErrInvalidArticle)
+
+	_, err = db.Exec("UPDATE articles SET bibliography = '[]', tags = 'go' WHERE id = ?", created.ID)
+	require.NoError(t, err)
+	_, err = repo.GetBySlug(ctx, "corrupt")
+	assert.ErrorIs(t, err, repository.ErrInvalidArticle)
+	_, err = repo.GetAllSummaries(ctx)
+	assert.ErrorIs(t, err, repository.ErrInvalidArticle)
+}
+
+func TestMediaRepository(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	_, err := sqlite.NewRepository(db, migrations.Files(), repository.Options{})
+	require.NoError(t, err)
+	repo := sqlite.NewMediaRepository(db, repository.Options{})
+
+	ctx := context.Background()
+
+	m := media.Media{
+		Hash:        strings.Repeat("ab", 32),
+		Name:        "photo.png",
+		ContentType: "image/png",
+		Size:        1024,
+	}
+
+	t.Run("Create and GetByHash", func(t *testing.T) {
+		created, err := repo.Create(ctx, m)
+		require.NoError(t, err)
+		assert.NotZero(t, created.ID)
+		assert.NotZero(t, created.CreatedAt)
+
+		fetched, err := repo.GetByHash(ctx, m.Hash)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, fetched.ID)
+		assert.Equal(t, m.Name, fetched.Name)
+		assert.Equal(t, m.ContentType, fetched.ContentType)
+		assert.Equal(t, m.Size, fetched.Size)
+	})
+
+	t.Run("Create duplicate hash", func(t *testing.T) {
+		_, err := repo.Create(ctx, m)
+		assert.Error(t, err)
+	})
+
+	t.Run("GetAll", func(t *testing.T) {
+		all, err := repo.GetAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("GetByHash missing", func(t *testing.T) {
+		_, err := repo.GetByHash(ctx, strings.Repeat("cd", 32))
+		assert.ErrorIs(t, err, media.ErrMediaNotFound)
+	})
+
+	t.Run("UpdateDimensions", func(t *testing.T) {
+		created, err := repo.GetByHash(ctx, m.Hash)
+		require.NoError(t, err)
+
+		require.NoError(t, repo.UpdateDimensions(ctx, created.ID, 1920, 1080))
+
+		fetched, err := repo.GetByHash(ctx, m.Hash)
+		require.NoError(t, err)
+		assert.Equal(t, 1920, fetched.Width)
+		assert.Equal(t, 1080, fetched.Height)
+	})
+
+	t.Run("Variants", func(t *testing.T) {
+		created, err := repo.GetByHash(ctx, m.Hash)
+		require.NoError(t, err)
+
+		for _, width := range []int{640, 320} {
+			_, err := repo.CreateVariant(ctx, media.Variant{
+				MediaID:     created.ID,
+				Hash:        strings.Repeat(fmt.Sprintf("%02d", width/32), 32),
+				Name:        fmt.Sprintf("photo-%dw.png", width),
+				ContentType: "image/png",
+				Size:        int64(width),
+				Width:       width,
+				Height:      width / 2,
+			})
+			require.NoError(t, err)
+		}
+
+		variants, err := repo.GetVariants(ctx, created.ID)
+		require.NoError(t, err)
+		require.Len(t, variants, 2)
+		assert.Equal(t, 320, variants[0].Width)
+		assert.Equal(t, 160, variants[0].Height)
+		assert.Equal(t, 640, variants[1].Width)
+
+		fetched, err := repo.GetVariantByHash(ctx, variants[1].Hash)
+		require.NoError(t, err)
+		assert.Equal(t, "photo-640w.png", fetched.Name)
+		assert.Equal(t, created.ID, fetched.MediaID)
+	})
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db, cleanup := setupTestDatabase(t)
+	defer cleanup()
+
+	_, err := sqlite.NewRepository(db, migrations.Files(), repository.Options{})
+	require.NoError(t, err)
+	repo, err := sqlite.NewRepository(db, migrations.Files(), repository.Options{
+		SkipMigrations: true,
+		QueryTimeout:   time.Nanosecond,
+	})
+	require.NoError(t, err)
+
+	_, err = repo.GetAll(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
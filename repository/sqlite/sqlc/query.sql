@@ -0,0 +1,133 @@
+-- name: CreateArticle :one
+INSERT INTO articles (title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id;
+
+-- name: CreateArticleWithID :exec
+INSERT INTO articles (id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetAllArticles :many
+SELECT * FROM articles
+ORDER BY id ASC;
+
+-- name: GetAllArticleSummaries :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+ORDER BY id ASC;
+
+-- name: GetArticleByID :one
+SELECT * FROM articles
+WHERE id = ? LIMIT 1;
+
+-- name: GetArticleBySlug :one
+SELECT * FROM articles
+WHERE slug = ? LIMIT 1;
+
+-- name: GetArticlesByTags :many
+SELECT * FROM articles
+WHERE EXISTS (
+    SELECT 1 FROM json_each(articles.tags)
+    WHERE json_each.value IN (SELECT value FROM json_each(sqlc.arg(tags)))
+)
+ORDER BY id ASC;
+
+-- name: GetArticleSummariesByTags :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE EXISTS (
+    SELECT 1 FROM json_each(articles.tags)
+    WHERE json_each.value IN (SELECT value FROM json_each(sqlc.arg(tags)))
+)
+ORDER BY id ASC;
+
+-- name: GetArticlesByPublicationDate :many
+SELECT * FROM articles
+WHERE (sqlc.narg(from_date) IS NULL OR julianday(publication_date) >= julianday(sqlc.narg(from_date)))
+  AND (sqlc.narg(to_date) IS NULL OR julianday(publication_date) < julianday(sqlc.narg(to_date)))
+ORDER BY publication_date ASC, id ASC;
+
+-- name: GetArticleSummariesByPublicationDate :many
+SELECT id, title, thumbnail, thumbnail_html, slug, tags, publication_date FROM articles
+WHERE (sqlc.narg(from_date) IS NULL OR julianday(publication_date) >= julianday(sqlc.narg(from_date)))
+  AND (sqlc.narg(to_date) IS NULL OR julianday(publication_date) < julianday(sqlc.narg(to_date)))
+ORDER BY publication_date ASC, id ASC;
+
+-- name: GetArticleArchive :many
+SELECT CAST(strftime('%Y', publication_date) AS INTEGER) AS year,
+       CAST(strftime('%m', publication_date) AS INTEGER) AS month,
+       COUNT(*) AS article_count
+FROM articles
+GROUP BY year, month
+ORDER BY year DESC, month DESC;
+
+-- name: GetAllTags :many
+SELECT DISTINCT CAST(json_each.value AS TEXT) AS unique_tag
+FROM articles, json_each(articles.tags)
+ORDER BY unique_tag ASC;
+
+-- name: UpdateArticleByID :one
+UPDATE articles
+SET title = ?,
+    thumbnail = ?,
+    thumbnail_html = ?,
+    slug = ?,
+    content = ?,
+    content_html = ?,
+    tags = ?,
+    bibliography = ?,
+    publication_date = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date;
+
+-- name: DeleteArticleByID :one
+DELETE FROM articles
+WHERE id = ?
+RETURNING id, title, thumbnail, thumbnail_html, slug, content, content_html, tags, bibliography, publication_date;
+
+-- name: CreateMedia :one
+INSERT INTO media (hash, name, content_type, size, width, height)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetMediaByHash :one
+SELECT * FROM media
+WHERE hash = ? LIMIT 1;
+
+-- name: GetAllMedia :many
+SELECT * FROM media
+ORDER BY created_at DESC;
+
+-- name: UpdateMediaDimensions :exec
+UPDATE media
+SET width = ?,
+    height = ?
+WHERE id = ?;
+
+-- name: CreateMediaVariant :one
+INSERT INTO media_variants (media_id, hash, name, content_type, size, width, height)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetMediaVariantByHash :one
+SELECT * FROM media_variants
+WHERE hash = ? LIMIT 1;
+
+-- name: GetMediaVariantsByMediaID :many
+SELECT * FROM media_variants
+WHERE media_id = ?
+ORDER BY width ASC;
+
+-- name: CreateArticleLink :exec
+INSERT INTO article_links (source_id, target_slug)
+VALUES (?, ?);
+
+-- name: DeleteArticleLinks :exec
+DELETE FROM article_links
+WHERE source_id = ?;
+
+-- name: GetArticleBacklinks :many
+SELECT articles.* FROM articles
+JOIN article_links ON article_links.source_id = articles.id
+WHERE article_links.target_slug = ?
+  AND articles.slug != article_links.target_slug
+ORDER BY articles.publication_date DESC, articles.id;
@@ -0,0 +1,49 @@
+CREATE TABLE articles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    thumbnail TEXT NOT NULL,
+    thumbnail_html TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    content TEXT NOT NULL,
+    content_html TEXT NOT NULL,
+    tags TEXT NOT NULL DEFAULT '[]',
+    bibliography TEXT NOT NULL DEFAULT '[]',
+    publication_date DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_articles_slug ON articles (slug);
+
+CREATE TABLE media (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    hash TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    width INTEGER NOT NULL DEFAULT 0,
+    height INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE media_variants (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    media_id INTEGER NOT NULL REFERENCES media (id) ON DELETE CASCADE,
+    hash TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_media_variants_media_id ON media_variants (media_id);
+
+CREATE TABLE article_links (
+    source_id INTEGER NOT NULL REFERENCES articles (id) ON DELETE CASCADE,
+    target_slug TEXT NOT NULL,
+    PRIMARY KEY (source_id, target_slug)
+);
+
+CREATE INDEX idx_article_links_target_slug ON article_links (target_slug);